@@ -0,0 +1,24 @@
+"""add clinic ids of clinic administrators
+
+Revision ID: 3f9a1c2d7b10
+Revises: ebb6a9cbc3ff
+Create Date: 2021-10-02 11:20:41.118362
+
+"""
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision = '3f9a1c2d7b10'
+down_revision = 'ebb6a9cbc3ff'
+branch_labels = None
+depends_on = None
+
+
+def upgrade():
+    op.add_column('user', sa.Column('clinic_ids', sa.String(length=1024), nullable=True))
+
+
+def downgrade():
+    op.drop_column('user', 'clinic_ids')
@@ -17,10 +17,17 @@ class User(db.Model):
     last_name = db.Column(db.String(40))
     password = db.Column(db.String(256))
     role = db.Column(db.String(40))
+    # comma separated IDs of the clinics managed by a clinic administrator
+    clinic_ids = db.Column(db.String(1024))
 
     def __str__(self):
         return self.username
 
+    def get_clinic_ids(self):
+        if not self.clinic_ids:
+            return []
+        return [v.strip() for v in self.clinic_ids.split(',') if v.strip()]
+
     def get_user_id(self):
         return self.id
 
@@ -9,7 +9,7 @@ from authlib.integrations.flask_oauth2 import current_token
 from authlib.oauth2 import OAuth2Error
 from .models import db, User, OAuth2Client
 from .oauth2 import authorization, require_oauth, MyIntrospectionEndpoint
-from .utils import split_by_crlf, hash_password
+from .utils import split_by_crlf, hash_password, jwks
 
 bp = Blueprint('home', __name__)
 
@@ -124,6 +124,10 @@ def revoke_token():
 def introspect_token():
     return authorization.create_endpoint_response(MyIntrospectionEndpoint.ENDPOINT_NAME)
 
+@bp.route('/.well-known/jwks.json')
+def get_jwks():
+    return jsonify(jwks())
+
 @bp.route('/register', methods=['POST'])
 def register():
     req = request.json
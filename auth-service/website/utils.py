@@ -1,5 +1,13 @@
 import hashlib
-from authlib.jose import jwt
+import time
+from authlib.jose import jwt, JsonWebKey
+
+# lifetime of the issued access tokens in seconds
+TOKEN_EXPIRES_IN = 864000
+
+# public keys published in the JWKS document. The first one belongs to the
+# current signing key; keep retired keys here until the tokens they signed expire
+PUBLIC_KEY_FILES = ['jwt-private.key.pub']
 
 
 def split_by_crlf(s):
@@ -11,16 +19,32 @@ def hash_password(password):
     pass_with_salt = password + salt
     return hashlib.md5(pass_with_salt.encode()).hexdigest()
 
+def load_public_jwk(path):
+    key = JsonWebKey.import_key(open(path, 'r').read(), {'kty': 'RSA'})
+    jwk = key.as_dict()
+    jwk.update({'kid': key.thumbprint(), 'use': 'sig', 'alg': 'RS256'})
+    return jwk
+
+
+def jwks():
+    return {'keys': [load_public_jwk(path) for path in PUBLIC_KEY_FILES]}
+
+
 def gen_token(client, grant_type, user, scope):
-    header = {'alg': 'RS256'}
+    header = {'alg': 'RS256', 'kid': load_public_jwk(PUBLIC_KEY_FILES[0])['kid']}
+    now = int(time.time())
     payload = {
         'iss': 'http://127.0.0.1:5000',
+        'iat': now,
+        'exp': now + TOKEN_EXPIRES_IN,
         'sub': client.client_id,
         'aud': 'idk',
         'username': user.username,
         'id': user.id,
         'role': user.role
     }
+    if user.role == 'clinic_admin':
+        payload['clinics'] = user.get_clinic_ids()
     try:
         key = open('jwt-private.key', 'r').read()
         
@@ -9,6 +9,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/go-ozzo/ozzo-dbx"
 	"github.com/go-ozzo/ozzo-routing/v2"
 	"github.com/go-ozzo/ozzo-routing/v2/content"
@@ -16,13 +17,18 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	appointment_type "github.com/matijapetrovic/clinichub/clinic-service/internal/appointment-type"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/availability"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/client/rating"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/client/scheduling"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/clinic"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/config"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/doctor"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/healthcheck"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/room"
 	"github.com/matijapetrovic/clinichub/clinic-service/pkg/accesslog"
 	"github.com/matijapetrovic/clinichub/clinic-service/pkg/dbcontext"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/httpclient"
 	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
 )
 
@@ -43,6 +49,21 @@ func main() {
 		os.Exit(-1)
 	}
 
+	// load the keys used to verify JWTs
+	var keySet *auth.KeySet
+	if cfg.JWKSLocation != "" {
+		if keySet, err = auth.NewKeySet(cfg.JWKSLocation, logger); err != nil {
+			logger.Errorf("failed to load JWKS: %s", err)
+			os.Exit(-1)
+		}
+		go keySet.RefreshEvery(time.Duration(cfg.JWKSRefreshInterval) * time.Minute)
+	}
+	keyFunc, err := auth.NewKeyFunc(keySet, cfg.JWTSigningKey, cfg.JWTVerificationKeyFile)
+	if err != nil {
+		logger.Errorf("failed to load JWT verification keys: %s", err)
+		os.Exit(-1)
+	}
+
 	// connect to the database
 	db, err := dbx.MustOpen("mysql", cfg.DSN)
 	if err != nil {
@@ -61,7 +82,7 @@ func main() {
 	address := fmt.Sprintf(":%v", cfg.ServerPort)
 	hs := &http.Server{
 		Addr:    address,
-		Handler: buildHandler(logger, dbcontext.New(db), cfg),
+		Handler: buildHandler(logger, dbcontext.New(db), cfg, keyFunc),
 	}
 
 	// start the HTTP server with graceful shutdown
@@ -74,7 +95,7 @@ func main() {
 }
 
 // buildHandler sets up the HTTP routing and builds an HTTP handler.
-func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.Handler {
+func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config, keyFunc jwt.Keyfunc) http.Handler {
 	router := routing.New()
 
 	router.Use(
@@ -88,10 +109,15 @@ func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.
 
 	rg := router.Group("/v1")
 
-	authHandler := auth.Handler(cfg.JWTSigningKey)
+	authHandler := auth.Handler(keyFunc, cfg.JWTIssuer)
 
 	appointmentTypeRepo := appointment_type.NewRepository(db, logger)
 	clinicRepo := clinic.NewRepository(db, logger)
+	doctorRepo := doctor.NewRepository(db, logger)
+
+	ratingClient := rating.NewClient(newPeerClient(cfg, cfg.RatingServiceURL, cfg.RatingServiceTimeout))
+	schedulingClient := scheduling.NewClient(newPeerClient(cfg, cfg.SchedulingServiceURL, cfg.SchedulingServiceTimeout))
+	availabilityService := availability.NewService(doctorRepo, clinicRepo, schedulingClient, logger)
 
 	appointment_type.RegisterHandlers(rg.Group(""),
 		appointment_type.NewService(appointmentTypeRepo, logger),
@@ -99,12 +125,17 @@ func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.
 	)
 
 	clinic.RegisterHandlers(rg.Group(""),
-		clinic.NewService(clinicRepo, appointmentTypeRepo, logger),
+		clinic.NewService(clinicRepo, appointmentTypeRepo, ratingClient, availabilityService, db.Transactional, logger),
 		authHandler, logger,
 	)
 
 	doctor.RegisterHandlers(rg.Group(""),
-		doctor.NewService(doctor.NewRepository(db, logger), clinicRepo, appointmentTypeRepo, logger),
+		doctor.NewService(doctorRepo, clinicRepo, appointmentTypeRepo, ratingClient, availabilityService, db.Transactional, logger),
+		authHandler, logger,
+	)
+
+	room.RegisterHandlers(rg.Group(""),
+		room.NewService(room.NewRepository(db, logger), clinicRepo, appointmentTypeRepo, db.Transactional, logger),
 		authHandler, logger,
 	)
 
@@ -132,3 +163,14 @@ func logDBExec(logger log.Logger) dbx.ExecLogFunc {
 		}
 	}
 }
+
+// newPeerClient creates a client for the API of another service with the given base URL and timeout in seconds.
+// Every peer gets its own circuit breaker.
+func newPeerClient(cfg *config.Config, baseURL string, timeout int) *httpclient.Client {
+	return httpclient.New(baseURL,
+		httpclient.WithRequestFunc(auth.ForwardToken),
+		httpclient.WithTimeout(time.Duration(timeout)*time.Second),
+		httpclient.WithRetries(cfg.PeerRetries, time.Duration(cfg.PeerRetryBackoff)*time.Millisecond),
+		httpclient.WithCircuitBreaker(cfg.CircuitBreakerThreshold, time.Duration(cfg.CircuitBreakerCooldown)*time.Second),
+	)
+}
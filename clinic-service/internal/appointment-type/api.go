@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
 	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
 )
@@ -15,8 +17,8 @@ func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routin
 
 	r.Get("/appointment-types", res.getAll)
 
-	r.Post("/appointment-types", res.create)
-	r.Put("/appointment-types/<id>", res.update)
+	r.Post("/appointment-types", auth.RequireRole(entity.RoleAdmin), res.create)
+	r.Put("/appointment-types/<id>", auth.RequireRole(entity.RoleAdmin), res.update)
 }
 
 type resource struct {
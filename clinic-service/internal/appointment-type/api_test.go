@@ -0,0 +1,47 @@
+package appointment_type
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/test"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+// mockService answers every call with empty results, so that the tests can check which requests reach it.
+type mockService struct{}
+
+func (s mockService) GetById(ctx context.Context, id string) (entity.AppointmentType, error) {
+	return entity.AppointmentType{}, nil
+}
+
+func (s mockService) GetAll(ctx context.Context) ([]entity.AppointmentType, error) {
+	return []entity.AppointmentType{}, nil
+}
+
+func (s mockService) Create(ctx context.Context, req CreateAppointmentTypeRequest) (entity.AppointmentType, error) {
+	return entity.AppointmentType{}, nil
+}
+
+func (s mockService) Update(ctx context.Context, id string, req UpdateAppointmentTypeRequest) (entity.AppointmentType, error) {
+	return entity.AppointmentType{}, nil
+}
+
+func TestAPI_roles(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), mockService{}, test.MockAuthHandler(), logger)
+
+	const (
+		ok        = http.StatusOK
+		created   = http.StatusCreated
+		forbidden = http.StatusForbidden
+		anonymous = http.StatusUnauthorized
+	)
+	test.Routes(t, router, []test.RouteTestCase{
+		{Method: "POST", URL: "/appointment-types", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: created, entity.RoleClinicAdmin: forbidden, entity.RolePatient: forbidden}},
+		{Method: "PUT", URL: "/appointment-types/1", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: forbidden, entity.RolePatient: forbidden}},
+	})
+}
@@ -15,23 +15,38 @@ type Service interface {
 	Update(ctx context.Context, id string, req UpdateAppointmentTypeRequest) (entity.AppointmentType, error)
 }
 
+// Limits of the appointment duration and the buffer after an appointment, in minutes.
+const (
+	minDuration = 5
+	maxDuration = 8 * 60
+	maxBuffer   = 2 * 60
+)
+
 type CreateAppointmentTypeRequest struct {
-	Name string `json:"name"`
+	Name     string `json:"name"`
+	Duration uint   `json:"duration"`
+	Buffer   uint   `json:"buffer"`
 }
 
 func (m CreateAppointmentTypeRequest) Validate() error {
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.Name, validation.Required, validation.Length(1, 50)),
+		validation.Field(&m.Duration, validation.Required, validation.Min(uint(minDuration)), validation.Max(uint(maxDuration))),
+		validation.Field(&m.Buffer, validation.Max(uint(maxBuffer))),
 	)
 }
 
 type UpdateAppointmentTypeRequest struct {
-	Name string `json:"name"`
+	Name     string `json:"name"`
+	Duration uint   `json:"duration"`
+	Buffer   uint   `json:"buffer"`
 }
 
 func (m UpdateAppointmentTypeRequest) Validate() error {
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.Name, validation.Required, validation.Length(1, 50)),
+		validation.Field(&m.Duration, validation.Required, validation.Min(uint(minDuration)), validation.Max(uint(maxDuration))),
+		validation.Field(&m.Buffer, validation.Max(uint(maxBuffer))),
 	)
 }
 
@@ -60,8 +75,10 @@ func (s service) Create(ctx context.Context, req CreateAppointmentTypeRequest) (
 
 	id := entity.GenerateID()
 	err := s.repo.Create(ctx, entity.AppointmentType{
-		Id:   id,
-		Name: req.Name,
+		Id:       id,
+		Name:     req.Name,
+		Duration: req.Duration,
+		Buffer:   req.Buffer,
 	})
 
 	if err != nil {
@@ -82,6 +99,8 @@ func (s service) Update(ctx context.Context, appointmentTypeId string, req Updat
 	}
 
 	appointmentType.Name = req.Name
+	appointmentType.Duration = req.Duration
+	appointmentType.Buffer = req.Buffer
 
 	err = s.repo.Update(ctx, appointmentType)
 	if err != nil {
@@ -3,60 +3,40 @@ package auth
 import (
 	"context"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
 )
 
 // Handler returns a JWT-based authentication middleware.
-func Handler(verificationKey string) routing.Handler {
+// The token signature is verified with the key returned by keyFunc. Tokens that are expired, not valid yet
+// or, when issuer is not empty, issued by someone else are rejected.
+func Handler(keyFunc jwt.Keyfunc, issuer string) routing.Handler {
+	parser := &jwt.Parser{
+		ValidMethods: []string{"RS256", "HS256"},
+	}
 	return func(c *routing.Context) error {
 		header := c.Request.Header.Get("Authorization")
-		// message := ""
-		// parser := &jwt.Parser{
-		// 	ValidMethods: []string{"RS256"},
-		// }
-		// block, _ := pem.Decode([]byte(PemString()))
-		// key, err := jwt.ParseRSAPublicKeyFromPEM(block.Bytes)
-		// if err != nil {
-		// 	message = err.Error()
-		// } else {
-		// 	if strings.HasPrefix(header, "Bearer ") {
-		// 		token, err := parser.Parse(header[7:], func(t *jwt.Token) (interface{}, error) { return key, nil })
-		// 		if err == nil && token.Valid {
-		// 			err = handleToken(c, token)
-		// 		}
-		// 		if err == nil {
-		// 			return nil
-		// 		}
-		// 		message = err.Error()
-		// 	}
-		// }
-		var message string
-		if len(header) < 7 {
-			message = "Unauthorized"
-		} else {
-			tokenStr := header[7:]
-			// c.Set("JWT", token)
-			// ctx := WithUser(
-			// 	c.Request.Context(),
-			// 	"aaec208d077e4f18a6cba93ed7875cc5",
-			// 	"patient@gmail.com",
-			// )
-			// c.Request = c.Request.WithContext(ctx)
-			// return nil
-			parser := &jwt.Parser{}
-			token, _, _ := parser.ParseUnverified(tokenStr, jwt.MapClaims{})
-			err := handleToken(c, token)
-			if err != nil {
-				message = err.Error()
-			} else {
+		message := ""
+		if strings.HasPrefix(header, "Bearer ") {
+			token, err := parser.Parse(header[7:], keyFunc)
+			if err == nil && token.Valid {
+				err = verifyClaims(token, issuer)
+			}
+			if err == nil {
+				err = handleToken(c, token)
+			}
+			if err == nil {
 				return nil
 			}
+			message = err.Error()
 		}
 
-		c.Response.Header().Set("WWW-Authenticate", `Bearer realm="`+"API"+`"`)
+		c.Response.Header().Set("WWW-Authenticate", `Bearer realm="API"`)
 		if message != "" {
 			return routing.NewHTTPError(http.StatusUnauthorized, message)
 		}
@@ -64,22 +44,90 @@ func Handler(verificationKey string) routing.Handler {
 	}
 }
 
+// RequireRole returns a middleware that only lets through users having one of the given roles.
+// It must be used after Handler so that the user identity is available in the request context.
+func RequireRole(roles ...string) routing.Handler {
+	return func(c *routing.Context) error {
+		if user := CurrentUser(c.Request.Context()); user != nil {
+			for _, role := range roles {
+				if user.GetRole() == role {
+					return nil
+				}
+			}
+		}
+		return errors.Forbidden("")
+	}
+}
+
+// verifyClaims checks the registered claims that jwt.Parser treats as optional.
+// The expiration time is required; the not-before time is already checked by the parser when present.
+func verifyClaims(token *jwt.Token, issuer string) error {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return errors.Unauthorized("token has invalid claims")
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return errors.Unauthorized("token has no expiration time")
+	}
+	if issuer != "" && !claims.VerifyIssuer(issuer, true) {
+		return errors.Unauthorized("token has an invalid issuer")
+	}
+	return nil
+}
+
 // handleToken stores the user identity in the request context so that it can be accessed elsewhere.
 func handleToken(c *routing.Context, token *jwt.Token) error {
-	ctx := WithUser(
-		c.Request.Context(),
-		token.Claims.(jwt.MapClaims)["id"].(string),
-		token.Claims.(jwt.MapClaims)["username"].(string),
-		token.Claims.(jwt.MapClaims)["role"].(string),
-	)
+	claims := token.Claims.(jwt.MapClaims)
+	id, ok := claims["id"].(string)
+	if !ok || id == "" {
+		return errors.Unauthorized("token has no valid id claim")
+	}
+	username, ok := claims["username"].(string)
+	if !ok {
+		return errors.Unauthorized("token has no valid username claim")
+	}
+	role, ok := claims["role"].(string)
+	if !ok || role == "" {
+		return errors.Unauthorized("token has no valid role claim")
+	}
+	clinicIds, ok := stringSliceClaim(claims, "clinics")
+	if !ok {
+		return errors.Unauthorized("token has no valid clinics claim")
+	}
+
+	ctx := WithUser(c.Request.Context(), id, username, role, clinicIds)
+	ctx = WithToken(ctx, token.Raw)
 	c.Request = c.Request.WithContext(ctx)
 	return nil
 }
 
+// stringSliceClaim returns the list of strings stored in the given claim.
+// A missing claim is treated as an empty list.
+func stringSliceClaim(claims jwt.MapClaims, name string) ([]string, bool) {
+	value, ok := claims[name]
+	if !ok {
+		return nil, true
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		result = append(result, s)
+	}
+	return result, true
+}
+
 type contextKey int
 
 const (
 	userKey contextKey = iota
+	tokenKey
 )
 
 type Identity interface {
@@ -87,12 +135,23 @@ type Identity interface {
 	GetID() string
 	// GetName returns the user name.
 	GetName() string
+	// GetRole returns the user role.
 	GetRole() string
+	// GetClinicIds returns the IDs of the clinics managed by a clinic administrator.
+	GetClinicIds() []string
+	// CanManageClinic returns whether the user is allowed to administer the clinic with the given ID.
+	CanManageClinic(clinicId string) bool
 }
 
 // WithUser returns a context that contains the user identity from the given JWT.
-func WithUser(ctx context.Context, id, name, role string) context.Context {
-	return context.WithValue(ctx, userKey, entity.User{ID: id, Name: name, Role: role})
+func WithUser(ctx context.Context, id, name, role string, clinicIds []string) context.Context {
+	return context.WithValue(ctx, userKey, entity.User{ID: id, Name: name, Role: role, ClinicIds: clinicIds})
+}
+
+// CanManageClinic returns whether the user found in the given context is allowed to administer the clinic.
+func CanManageClinic(ctx context.Context, clinicId string) bool {
+	user := CurrentUser(ctx)
+	return user != nil && user.CanManageClinic(clinicId)
 }
 
 // CurrentUser returns the user identity from the given context.
@@ -103,3 +162,23 @@ func CurrentUser(ctx context.Context) Identity {
 	}
 	return nil
 }
+
+// WithToken returns a context that contains the given raw JWT.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+// CurrentToken returns the raw JWT from the given context.
+// An empty string is returned if no token is found in the context.
+func CurrentToken(ctx context.Context) string {
+	token, _ := ctx.Value(tokenKey).(string)
+	return token
+}
+
+// ForwardToken sets the Authorization header of an outgoing request to the JWT found in the given context,
+// so that calls to other services are made on behalf of the current user.
+func ForwardToken(ctx context.Context, r *http.Request) {
+	if token := CurrentToken(ctx); token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+}
@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+)
+
+const (
+	testSigningKey = "test-signing-key"
+	testIssuer     = "https://auth.clinichub.test"
+)
+
+// testRSAKey signs the RS256 tokens of the tests.
+var testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+// validClaims returns the claims of a valid token of a clinic administrator.
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"id":       "00000000-0000-0000-0000-000000000100",
+		"username": "admin",
+		"role":     "clinic_admin",
+		"clinics":  []string{"00000000-0000-0000-0000-000000000001"},
+		"iss":      testIssuer,
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// withClaims returns validClaims changed by the given function.
+func withClaims(change func(claims jwt.MapClaims)) jwt.MapClaims {
+	claims := validClaims()
+	change(claims)
+	return claims
+}
+
+// signToken returns a token with the given claims signed with the given method and key.
+// A non-empty kid is added to the token header.
+func signToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// publicKeyPEM returns the PEM encoded public key of testRSAKey.
+func publicKeyPEM(t *testing.T) []byte {
+	t.Helper()
+	bytes, err := x509.MarshalPKIXPublicKey(&testRSAKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: bytes})
+}
+
+// publicKeyFile writes the public key of testRSAKey to a PEM file and returns its path.
+func publicKeyFile(t *testing.T) string {
+	t.Helper()
+	file, err := ioutil.TempFile("", "public-key-*.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.Write(publicKeyPEM(t)); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
+
+// serveAuthenticated sends a request with the given Authorization header through the authentication middleware
+// and returns the response. Authenticated requests are answered with the ID and clinics of the user.
+func serveAuthenticated(keyFunc jwt.Keyfunc, issuer, header string) *httptest.ResponseRecorder {
+	router := routing.New()
+	router.Get("/", Handler(keyFunc, issuer), func(c *routing.Context) error {
+		user := CurrentUser(c.Request.Context())
+		return c.Write(user.GetID() + " " + strings.Join(user.GetClinicIds(), ","))
+	})
+	req, _ := http.NewRequest("GET", "/", nil)
+	if header != "" {
+		req.Header.Set("Authorization", header)
+	}
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func TestHandler(t *testing.T) {
+	file := publicKeyFile(t)
+	defer os.Remove(file)
+	keyFunc, err := NewKeyFunc(nil, testSigningKey, file)
+	if err != nil {
+		t.Fatalf("NewKeyFunc() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{"HS256 token", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", validClaims()), http.StatusOK},
+		{"RS256 token", "Bearer " + signToken(t, jwt.SigningMethodRS256, testRSAKey, "", validClaims()), http.StatusOK},
+		{"no header", "", http.StatusUnauthorized},
+		{"not a bearer token", "Basic YWRtaW46cGFzcw==", http.StatusUnauthorized},
+		{"malformed token", "Bearer not.a.token", http.StatusUnauthorized},
+		{"HS256 token with another key", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte("another-key"), "", validClaims()), http.StatusUnauthorized},
+		{"HS256 token signed with the RSA public key", "Bearer " + signToken(t, jwt.SigningMethodHS256, publicKeyPEM(t), "", validClaims()), http.StatusUnauthorized},
+		{"RS384 token", "Bearer " + signToken(t, jwt.SigningMethodRS384, testRSAKey, "", validClaims()), http.StatusUnauthorized},
+		{"unsigned token", "Bearer " + signToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims()), http.StatusUnauthorized},
+		{"expired token", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		})), http.StatusUnauthorized},
+		{"token without expiration time", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			delete(claims, "exp")
+		})), http.StatusUnauthorized},
+		{"token not valid yet", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			claims["nbf"] = time.Now().Add(time.Hour).Unix()
+		})), http.StatusUnauthorized},
+		{"token of another issuer", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			claims["iss"] = "https://auth.example.com"
+		})), http.StatusUnauthorized},
+		{"token without issuer", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			delete(claims, "iss")
+		})), http.StatusUnauthorized},
+		{"token without id", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			delete(claims, "id")
+		})), http.StatusUnauthorized},
+		{"numeric role", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			claims["role"] = 1
+		})), http.StatusUnauthorized},
+		{"clinics as a string", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			claims["clinics"] = "00000000-0000-0000-0000-000000000001"
+		})), http.StatusUnauthorized},
+		{"clinics as numbers", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			claims["clinics"] = []int{1, 2}
+		})), http.StatusUnauthorized},
+		{"role as an object", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			claims["role"] = map[string]string{"name": "admin"}
+		})), http.StatusUnauthorized},
+		{"token without clinics", "Bearer " + signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", withClaims(func(claims jwt.MapClaims) {
+			delete(claims, "clinics")
+		})), http.StatusOK},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := serveAuthenticated(keyFunc, testIssuer, tc.header)
+			if res.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d (%s)", res.Code, tc.wantStatus, res.Body.String())
+			}
+			if res.Code == http.StatusUnauthorized && res.Header().Get("WWW-Authenticate") == "" {
+				t.Error("WWW-Authenticate header is missing")
+			}
+		})
+	}
+}
+
+func TestHandler_identity(t *testing.T) {
+	keyFunc, err := NewKeyFunc(nil, testSigningKey, "")
+	if err != nil {
+		t.Fatalf("NewKeyFunc() error = %v", err)
+	}
+
+	res := serveAuthenticated(keyFunc, "", "Bearer "+signToken(t, jwt.SigningMethodHS256, []byte(testSigningKey), "", validClaims()))
+	if want := "00000000-0000-0000-0000-000000000100 00000000-0000-0000-0000-000000000001"; res.Code != http.StatusOK || res.Body.String() != want {
+		t.Errorf("response = %d %q, want 200 %q", res.Code, res.Body.String(), want)
+	}
+}
+
+func TestHandler_methodWithoutKey(t *testing.T) {
+	keyFunc, err := NewKeyFunc(nil, testSigningKey, "")
+	if err != nil {
+		t.Fatalf("NewKeyFunc() error = %v", err)
+	}
+
+	res := serveAuthenticated(keyFunc, testIssuer, "Bearer "+signToken(t, jwt.SigningMethodRS256, testRSAKey, "", validClaims()))
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401 for an RS256 token without a configured public key", res.Code)
+	}
+}
@@ -0,0 +1,229 @@
+// Package availability works out when doctors have free appointment slots.
+package availability
+
+import (
+	"context"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/client/scheduling"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+// DoctorRepository provides the doctors and their schedules.
+type DoctorRepository interface {
+	GetByClinicIdsAndSpecializationId(ctx context.Context, clinicIds []string, specializationId string) ([]entity.Doctor, error)
+	GetShifts(ctx context.Context, doctorId string) ([]entity.DoctorShift, error)
+	GetShiftsByDoctorIds(ctx context.Context, doctorIds []string) ([]entity.DoctorShift, error)
+	GetTimeOff(ctx context.Context, doctorId string, startDate string, endDate string) ([]entity.DoctorTimeOff, error)
+	GetTimeOffByDoctorIds(ctx context.Context, doctorIds []string, startDate string, endDate string) ([]entity.DoctorTimeOff, error)
+}
+
+// ClinicRepository provides the clinics with their opening hours and holidays.
+type ClinicRepository interface {
+	GetById(ctx context.Context, id string) (entity.Clinic, error)
+	GetOpeningHours(ctx context.Context, clinicId string) ([]entity.ClinicOpeningHours, error)
+	GetHolidays(ctx context.Context, clinicId string, startDate string, endDate string) ([]entity.ClinicHoliday, error)
+}
+
+type Service interface {
+	// GetSchedule returns the schedule of the doctor with the time off including any of the days from startDate
+	// to endDate, together with the time zone and opening hours of the doctor's clinic and its holidays in that period.
+	GetSchedule(ctx context.Context, doctor entity.Doctor, startDate string, endDate string) (entity.Schedule, error)
+	// GetFreeSlots returns the start times of the free slots of the doctors on the given date for an appointment
+	// of the given type, keyed by doctor ID. The date is taken in the time zone of each doctor's clinic and
+	// the slots are in the local time of the clinic.
+	GetFreeSlots(ctx context.Context, doctors []entity.Doctor, appointmentType entity.AppointmentType, date time.Time) (map[string][]time.Time, error)
+	// GetClinicsWithFreeSlots returns the IDs of those of the clinics having a doctor specialized for the appointment type
+	// with a free slot on the given date.
+	GetClinicsWithFreeSlots(ctx context.Context, clinicIds []string, appointmentType entity.AppointmentType, date time.Time) ([]string, error)
+}
+
+type service struct {
+	doctorRepo       DoctorRepository
+	clinicRepo       ClinicRepository
+	schedulingClient scheduling.Client
+	logger           log.Logger
+}
+
+func NewService(doctorRepo DoctorRepository, clinicRepo ClinicRepository, schedulingClient scheduling.Client, logger log.Logger) Service {
+	return service{doctorRepo, clinicRepo, schedulingClient, logger}
+}
+
+// GetSchedule returns the schedule of the doctor. A doctor whose shifts have never been set works the working hours
+// of the doctor every day.
+func (s service) GetSchedule(ctx context.Context, doctor entity.Doctor, startDate string, endDate string) (entity.Schedule, error) {
+	clinicSchedule, err := s.getClinicSchedule(ctx, doctor.ClinicId, startDate, endDate)
+	if err != nil {
+		return entity.Schedule{}, err
+	}
+	shifts, err := s.doctorRepo.GetShifts(ctx, doctor.Id)
+	if err != nil {
+		return entity.Schedule{}, err
+	}
+	timeOff, err := s.doctorRepo.GetTimeOff(ctx, doctor.Id, startDate, endDate)
+	if err != nil {
+		return entity.Schedule{}, err
+	}
+	return getSchedule(doctor, shifts, timeOff, clinicSchedule), nil
+}
+
+func (s service) GetFreeSlots(ctx context.Context, doctors []entity.Doctor, appointmentType entity.AppointmentType, date time.Time) (map[string][]time.Time, error) {
+	// shifts starting the day before may continue into the requested date
+	startDate := date.AddDate(0, 0, -1).Format(entity.DateLayout)
+	endDate := date.Format(entity.DateLayout)
+	length := time.Duration(appointmentType.Duration) * time.Minute
+
+	slots := make(map[string][]time.Time, len(doctors))
+	if len(doctors) == 0 {
+		return slots, nil
+	}
+
+	// the shifts and time off of all doctors are loaded at once instead of with a query per doctor
+	ids := make([]string, len(doctors))
+	for i, doctor := range doctors {
+		ids[i] = doctor.Id
+	}
+	shifts, err := s.doctorRepo.GetShiftsByDoctorIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	shiftsByDoctor := make(map[string][]entity.DoctorShift)
+	for _, shift := range shifts {
+		shiftsByDoctor[shift.DoctorId] = append(shiftsByDoctor[shift.DoctorId], shift)
+	}
+	timeOff, err := s.doctorRepo.GetTimeOffByDoctorIds(ctx, ids, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	timeOffByDoctor := make(map[string][]entity.DoctorTimeOff)
+	for _, off := range timeOff {
+		timeOffByDoctor[off.DoctorId] = append(timeOffByDoctor[off.DoctorId], off)
+	}
+
+	clinicSchedules := make(map[string]entity.Schedule)
+	var doctorIds []string
+	// the day may start and end at different times in the clinics, so busy times are asked for
+	// the period covering all slots
+	var start, end time.Time
+	for _, doctor := range doctors {
+		clinicSchedule, ok := clinicSchedules[doctor.ClinicId]
+		if !ok {
+			if clinicSchedule, err = s.getClinicSchedule(ctx, doctor.ClinicId, startDate, endDate); err != nil {
+				return nil, err
+			}
+			clinicSchedules[doctor.ClinicId] = clinicSchedule
+		}
+
+		schedule := getSchedule(doctor, shiftsByDoctor[doctor.Id], timeOffByDoctor[doctor.Id], clinicSchedule)
+		doctorSlots := schedule.GetSlots(date, length, appointmentType.SlotLength())
+		slots[doctor.Id] = doctorSlots
+		if len(doctorSlots) == 0 {
+			continue
+		}
+		doctorIds = append(doctorIds, doctor.Id)
+		if first := doctorSlots[0]; start.IsZero() || first.Before(start) {
+			start = first
+		}
+		if last := doctorSlots[len(doctorSlots)-1].Add(appointmentType.SlotLength()); last.After(end) {
+			end = last
+		}
+	}
+	if len(doctorIds) == 0 {
+		return slots, nil
+	}
+
+	busyTimes, err := s.schedulingClient.GetBusyTimes(ctx, doctorIds, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range doctorIds {
+		free := make([]time.Time, 0, len(slots[id]))
+		for _, slot := range slots[id] {
+			if !isBusy(busyTimes[id], slot, slot.Add(appointmentType.SlotLength())) {
+				free = append(free, slot)
+			}
+		}
+		slots[id] = free
+	}
+	return slots, nil
+}
+
+func (s service) GetClinicsWithFreeSlots(ctx context.Context, clinicIds []string, appointmentType entity.AppointmentType, date time.Time) ([]string, error) {
+	if len(clinicIds) == 0 {
+		return []string{}, nil
+	}
+	doctors, err := s.doctorRepo.GetByClinicIdsAndSpecializationId(ctx, clinicIds, appointmentType.Id)
+	if err != nil {
+		return nil, err
+	}
+	slots, err := s.GetFreeSlots(ctx, doctors, appointmentType, date)
+	if err != nil {
+		return nil, err
+	}
+
+	available := make(map[string]bool)
+	for _, doctor := range doctors {
+		if len(slots[doctor.Id]) > 0 {
+			available[doctor.ClinicId] = true
+		}
+	}
+	result := []string{}
+	for _, id := range clinicIds {
+		if available[id] {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+// getSchedule returns the schedule of the doctor, adding the doctor's shifts and time off to the schedule of the clinic.
+// A doctor whose shifts have never been set works the working hours of the doctor every day.
+func getSchedule(doctor entity.Doctor, shifts []entity.DoctorShift, timeOff []entity.DoctorTimeOff, schedule entity.Schedule) entity.Schedule {
+	if len(shifts) == 0 && !doctor.ShiftsConfigured {
+		shifts = entity.DefaultShifts(doctor.Id, doctor.WorkStart, doctor.WorkEnd)
+	}
+	if timeOff == nil {
+		timeOff = []entity.DoctorTimeOff{}
+	}
+
+	schedule.Shifts = shifts
+	schedule.TimeOff = timeOff
+	return schedule
+}
+
+// getClinicSchedule returns a schedule without shifts holding the time zone of the clinic, its opening hours
+// and its holidays from startDate to endDate.
+func (s service) getClinicSchedule(ctx context.Context, clinicId string, startDate string, endDate string) (entity.Schedule, error) {
+	clinic, err := s.clinicRepo.GetById(ctx, clinicId)
+	if err != nil {
+		return entity.Schedule{}, err
+	}
+	openingHours, err := s.clinicRepo.GetOpeningHours(ctx, clinicId)
+	if err != nil {
+		return entity.Schedule{}, err
+	}
+	holidays, err := s.clinicRepo.GetHolidays(ctx, clinicId, startDate, endDate)
+	if err != nil {
+		return entity.Schedule{}, err
+	}
+
+	calendar := entity.OpeningCalendar{OpeningHours: openingHours, Holidays: holidays}
+	if calendar.OpeningHours == nil {
+		calendar.OpeningHours = []entity.ClinicOpeningHours{}
+	}
+	if calendar.Holidays == nil {
+		calendar.Holidays = []entity.ClinicHoliday{}
+	}
+	return entity.Schedule{TimeZone: clinic.TimeZone, OpeningCalendar: calendar}, nil
+}
+
+// isBusy returns whether the doctor is busy at some point between start and end.
+func isBusy(busyTimes []scheduling.BusyTime, start time.Time, end time.Time) bool {
+	for _, busy := range busyTimes {
+		if busy.Overlaps(start, end) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package availability
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/client/scheduling"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+// doctorRepository keeps the shifts and time off of the doctors in memory and counts the queries by method.
+type doctorRepository struct {
+	shifts  []entity.DoctorShift
+	timeOff []entity.DoctorTimeOff
+	queries map[string]int
+}
+
+func (r doctorRepository) GetByClinicIdsAndSpecializationId(ctx context.Context, clinicIds []string, specializationId string) ([]entity.Doctor, error) {
+	r.queries["GetByClinicIdsAndSpecializationId"]++
+	return nil, nil
+}
+
+func (r doctorRepository) GetShifts(ctx context.Context, doctorId string) ([]entity.DoctorShift, error) {
+	r.queries["GetShifts"]++
+	return r.GetShiftsByDoctorIds(ctx, []string{doctorId})
+}
+
+func (r doctorRepository) GetShiftsByDoctorIds(ctx context.Context, doctorIds []string) ([]entity.DoctorShift, error) {
+	r.queries["GetShiftsByDoctorIds"]++
+	var shifts []entity.DoctorShift
+	for _, shift := range r.shifts {
+		if contains(doctorIds, shift.DoctorId) {
+			shifts = append(shifts, shift)
+		}
+	}
+	return shifts, nil
+}
+
+func (r doctorRepository) GetTimeOff(ctx context.Context, doctorId string, startDate string, endDate string) ([]entity.DoctorTimeOff, error) {
+	r.queries["GetTimeOff"]++
+	return r.GetTimeOffByDoctorIds(ctx, []string{doctorId}, startDate, endDate)
+}
+
+func (r doctorRepository) GetTimeOffByDoctorIds(ctx context.Context, doctorIds []string, startDate string, endDate string) ([]entity.DoctorTimeOff, error) {
+	r.queries["GetTimeOffByDoctorIds"]++
+	var timeOff []entity.DoctorTimeOff
+	for _, off := range r.timeOff {
+		if contains(doctorIds, off.DoctorId) && off.StartDate <= endDate && off.EndDate >= startDate {
+			timeOff = append(timeOff, off)
+		}
+	}
+	return timeOff, nil
+}
+
+// clinicRepository returns clinics in UTC that are open all day every day.
+type clinicRepository struct{}
+
+func (r clinicRepository) GetById(ctx context.Context, id string) (entity.Clinic, error) {
+	return entity.Clinic{Id: id, TimeZone: entity.DefaultTimeZone}, nil
+}
+
+func (r clinicRepository) GetOpeningHours(ctx context.Context, clinicId string) ([]entity.ClinicOpeningHours, error) {
+	return nil, nil
+}
+
+func (r clinicRepository) GetHolidays(ctx context.Context, clinicId string, startDate string, endDate string) ([]entity.ClinicHoliday, error) {
+	return nil, nil
+}
+
+// schedulingClient returns the given busy times and counts the requests.
+type schedulingClient struct {
+	busyTimes map[string][]scheduling.BusyTime
+	requests  *int
+}
+
+func (c schedulingClient) GetBusyTimes(ctx context.Context, doctorIds []string, start time.Time, end time.Time) (map[string][]scheduling.BusyTime, error) {
+	*c.requests++
+	return c.busyTimes, nil
+}
+
+func contains(ids []string, id string) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestService_GetFreeSlots_batched(t *testing.T) {
+	logger, _ := log.NewForTest()
+	at := func(hour, minute int) time.Time {
+		return time.Date(2021, 9, 6, hour, minute, 0, 0, time.UTC)
+	}
+	doctors := []entity.Doctor{
+		{Id: "shifts", ClinicId: "c1", ShiftsConfigured: true},
+		{Id: "time-off", ClinicId: "c1", ShiftsConfigured: true},
+		{Id: "working-hours", ClinicId: "c2", WorkStart: "08:00", WorkEnd: "09:00"},
+		{Id: "no-shifts", ClinicId: "c2", ShiftsConfigured: true},
+	}
+	repo := doctorRepository{
+		shifts: []entity.DoctorShift{
+			{Id: "s1", DoctorId: "shifts", Weekday: 1, Start: "08:00", End: "10:00"},
+			{Id: "s2", DoctorId: "time-off", Weekday: 1, Start: "08:00", End: "10:00"},
+		},
+		timeOff: []entity.DoctorTimeOff{
+			{Id: "t1", DoctorId: "time-off", StartDate: "2021-09-06", EndDate: "2021-09-10"},
+		},
+		queries: map[string]int{},
+	}
+	var requests int
+	client := schedulingClient{
+		busyTimes: map[string][]scheduling.BusyTime{"shifts": {{Start: at(8, 0), End: at(8, 30)}}},
+		requests:  &requests,
+	}
+	s := NewService(repo, clinicRepository{}, client, logger)
+
+	slots, err := s.GetFreeSlots(context.Background(), doctors, entity.AppointmentType{Id: "a1", Duration: 30}, at(0, 0))
+	if err != nil {
+		t.Fatalf("GetFreeSlots() error = %v", err)
+	}
+	want := map[string][]time.Time{
+		"shifts":        {at(8, 30), at(9, 0), at(9, 30)},
+		"time-off":      nil,
+		"working-hours": {at(8, 0), at(8, 30)},
+		"no-shifts":     nil,
+	}
+	for id, wantSlots := range want {
+		if len(slots[id]) != len(wantSlots) || len(wantSlots) > 0 && !reflect.DeepEqual(slots[id], wantSlots) {
+			t.Errorf("slots[%s] = %v, want %v", id, slots[id], wantSlots)
+		}
+	}
+	wantQueries := map[string]int{"GetShiftsByDoctorIds": 1, "GetTimeOffByDoctorIds": 1}
+	if !reflect.DeepEqual(repo.queries, wantQueries) {
+		t.Errorf("queries = %v, want %v", repo.queries, wantQueries)
+	}
+	if requests != 1 {
+		t.Errorf("busy times requests = %d, want 1", requests)
+	}
+}
+
+func TestService_GetFreeSlots_noDoctors(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := doctorRepository{queries: map[string]int{}}
+	var requests int
+	s := NewService(repo, clinicRepository{}, schedulingClient{requests: &requests}, logger)
+
+	slots, err := s.GetFreeSlots(context.Background(), nil, entity.AppointmentType{Duration: 30}, time.Date(2021, 9, 6, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("GetFreeSlots() error = %v", err)
+	}
+	if len(slots) != 0 || len(repo.queries) != 0 || requests != 0 {
+		t.Errorf("slots = %v, queries = %v, busy times requests = %d, want nothing queried", slots, repo.queries, requests)
+	}
+}
@@ -0,0 +1,76 @@
+// Package rating provides a client for the rating-service API.
+package rating
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/httpclient"
+)
+
+// Client sends requests to the rating-service API.
+type Client interface {
+	// GetClinicRating returns the average rating of the clinic with the given ID.
+	GetClinicRating(ctx context.Context, clinicId string) (entity.Rating, error)
+	// GetDoctorRating returns the average rating of the doctor with the given ID.
+	GetDoctorRating(ctx context.Context, doctorId string) (entity.Rating, error)
+	// GetClinicRatings returns the average ratings of the clinics with the given IDs, keyed by clinic ID.
+	GetClinicRatings(ctx context.Context, clinicIds []string) (map[string]entity.Rating, error)
+	// GetDoctorRatings returns the average ratings of the doctors with the given IDs, keyed by doctor ID.
+	GetDoctorRatings(ctx context.Context, doctorIds []string) (map[string]entity.Rating, error)
+}
+
+// maxBatchSize is the maximum number of IDs the rating-service accepts in a single batch request.
+const maxBatchSize = 100
+
+type client struct {
+	http *httpclient.Client
+}
+
+// NewClient creates a new rating-service client sending requests with the given HTTP client.
+func NewClient(http *httpclient.Client) Client {
+	return client{http}
+}
+
+func (c client) GetClinicRating(ctx context.Context, clinicId string) (entity.Rating, error) {
+	var rating entity.Rating
+	err := c.http.Get(ctx, "/v1/clinics/"+clinicId+"/average-rating", nil, &rating)
+	return rating, err
+}
+
+func (c client) GetDoctorRating(ctx context.Context, doctorId string) (entity.Rating, error) {
+	var rating entity.Rating
+	err := c.http.Get(ctx, "/v1/doctors/"+doctorId+"/average-rating", nil, &rating)
+	return rating, err
+}
+
+func (c client) GetClinicRatings(ctx context.Context, clinicIds []string) (map[string]entity.Rating, error) {
+	return c.getRatings(ctx, "/v1/clinics/average-ratings", clinicIds)
+}
+
+func (c client) GetDoctorRatings(ctx context.Context, doctorIds []string) (map[string]entity.Rating, error) {
+	return c.getRatings(ctx, "/v1/doctors/average-ratings", doctorIds)
+}
+
+// getRatings fetches the ratings from the given batch endpoint, splitting the IDs into batches
+// no larger than the rating-service accepts.
+func (c client) getRatings(ctx context.Context, path string, ids []string) (map[string]entity.Rating, error) {
+	ratings := make(map[string]entity.Rating, len(ids))
+	for start := 0; start < len(ids); start += maxBatchSize {
+		end := start + maxBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var batch map[string]entity.Rating
+		query := url.Values{"ids": {strings.Join(ids[start:end], ",")}}
+		if err := c.http.Get(ctx, path, query, &batch); err != nil {
+			return nil, err
+		}
+		for id, rating := range batch {
+			ratings[id] = rating
+		}
+	}
+	return ratings, nil
+}
@@ -0,0 +1,65 @@
+// Package scheduling provides a client for the scheduling-service API.
+package scheduling
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/httpclient"
+)
+
+// BusyTime is a period in which a doctor is busy with an appointment, including the buffer after it.
+type BusyTime struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Overlaps returns whether the doctor is busy at some point between start and end.
+func (b BusyTime) Overlaps(start time.Time, end time.Time) bool {
+	return b.Start.Before(end) && start.Before(b.End)
+}
+
+// Client sends requests to the scheduling-service API.
+type Client interface {
+	// GetBusyTimes returns the times at which the doctors with the given IDs are busy at some point between
+	// start and end, keyed by doctor ID.
+	GetBusyTimes(ctx context.Context, doctorIds []string, start time.Time, end time.Time) (map[string][]BusyTime, error)
+}
+
+// maxBatchSize is the maximum number of IDs the scheduling-service accepts in a single batch request.
+const maxBatchSize = 100
+
+type client struct {
+	http *httpclient.Client
+}
+
+// NewClient creates a new scheduling-service client sending requests with the given HTTP client.
+func NewClient(http *httpclient.Client) Client {
+	return client{http}
+}
+
+// GetBusyTimes splits the IDs into batches no larger than the scheduling-service accepts.
+func (c client) GetBusyTimes(ctx context.Context, doctorIds []string, start time.Time, end time.Time) (map[string][]BusyTime, error) {
+	busyTimes := make(map[string][]BusyTime, len(doctorIds))
+	for first := 0; first < len(doctorIds); first += maxBatchSize {
+		last := first + maxBatchSize
+		if last > len(doctorIds) {
+			last = len(doctorIds)
+		}
+		var batch map[string][]BusyTime
+		query := url.Values{
+			"doctorIds": {strings.Join(doctorIds[first:last], ",")},
+			"start":     {start.UTC().Format(time.RFC3339)},
+			"end":       {end.UTC().Format(time.RFC3339)},
+		}
+		if err := c.http.Get(ctx, "/v1/doctors/busy-times", query, &batch); err != nil {
+			return nil, err
+		}
+		for id, times := range batch {
+			busyTimes[id] = times
+		}
+	}
+	return busyTimes, nil
+}
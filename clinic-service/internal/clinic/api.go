@@ -5,6 +5,7 @@ import (
 
 	routing "github.com/go-ozzo/ozzo-routing/v2"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
 	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
 	"github.com/matijapetrovic/clinichub/clinic-service/pkg/pagination"
@@ -18,19 +19,28 @@ func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routin
 	r.Get("/clinics", res.query)
 	r.Get("/clinics/<id>", res.getById)
 	r.Get("/clinics/<id>/prices", res.getPrices)
-	r.Post("/clinics", res.create)
-	r.Put("/clinics/<id>", res.update)
-	r.Post("/clinics/<id>/prices", res.addPrice)
-	r.Put("/clinics/<id>/prices", res.updatePrice)
+	r.Post("/clinics", auth.RequireRole(entity.RoleAdmin), res.create)
+	r.Put("/clinics/<id>", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.update)
+	r.Post("/clinics/<id>/prices", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.addPrice)
+	r.Put("/clinics/<id>/prices", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.updatePrice)
+
+	r.Put("/clinics/<id>/opening-hours", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.setOpeningHours)
+	r.Get("/clinics/<id>/holidays", res.getHolidays)
+	r.Post("/clinics/<id>/holidays", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.addHoliday)
+	r.Post("/clinics/<id>/holidays/import", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.importHolidays)
+	r.Delete("/clinics/<id>/holidays/<holidayId>", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.deleteHoliday)
 }
 
+// maxCalendarSize is the maximum size in bytes of an imported iCalendar file.
+const maxCalendarSize = 1 << 20
+
 type resource struct {
 	service Service
 	logger  log.Logger
 }
 
 func (r resource) getById(c *routing.Context) error {
-	clinic, err := r.service.GetById(c.Request, c.Param("id"))
+	clinic, err := r.service.GetById(c.Request.Context(), c.Param("id"))
 	if err != nil {
 		return err
 	}
@@ -45,9 +55,10 @@ func (r resource) query(c *routing.Context) error {
 		return err
 	}
 	pages := pagination.NewFromRequest(c.Request, count)
-	clinics, err := r.service.Query(c.Request, QueryClinicsRequest{
+	clinics, err := r.service.Query(c.Request.Context(), QueryClinicsRequest{
 		AppointmentTypeId: c.Request.URL.Query().Get("appointmentTypeId"),
 		Date:              c.Request.URL.Query().Get("date"),
+		Sort:              c.Request.URL.Query().Get("sort"),
 		Limit:             pages.Limit(),
 		Offset:            pages.Offset(),
 	})
@@ -72,10 +83,6 @@ func (r resource) getPrices(c *routing.Context) error {
 }
 
 func (r resource) create(c *routing.Context) error {
-	user := auth.CurrentUser(c.Request.Context())
-	if user.GetRole() != "admin" {
-		return c.WriteWithStatus("Forbidden", http.StatusForbidden)
-	}
 	var request CreateClinicRequest
 	if err := c.Read(&request); err != nil {
 		r.logger.With(c.Request.Context()).Info(err)
@@ -132,3 +139,64 @@ func (r resource) updatePrice(c *routing.Context) error {
 
 	return c.Write(clinic)
 }
+
+func (r resource) setOpeningHours(c *routing.Context) error {
+	var request SetOpeningHoursRequest
+	if err := c.Read(&request); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest("")
+	}
+
+	openingHours, err := r.service.SetOpeningHours(c.Request.Context(), c.Param("id"), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(openingHours)
+}
+
+func (r resource) getHolidays(c *routing.Context) error {
+	holidays, err := r.service.GetHolidays(c.Request.Context(), c.Param("id"), GetHolidaysRequest{
+		StartDate: c.Query("startDate"),
+		EndDate:   c.Query("endDate"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Write(holidays)
+}
+
+func (r resource) addHoliday(c *routing.Context) error {
+	var request AddHolidayRequest
+	if err := c.Read(&request); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest("")
+	}
+
+	holiday, err := r.service.AddHoliday(c.Request.Context(), c.Param("id"), request)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteWithStatus(holiday, http.StatusCreated)
+}
+
+// importHolidays imports the holidays from the iCalendar file sent as the request body.
+func (r resource) importHolidays(c *routing.Context) error {
+	holidays, err := r.service.ImportHolidays(c.Request.Context(), c.Param("id"), http.MaxBytesReader(c.Response, c.Request.Body, maxCalendarSize))
+	if err != nil {
+		return err
+	}
+
+	return c.WriteWithStatus(holidays, http.StatusCreated)
+}
+
+func (r resource) deleteHoliday(c *routing.Context) error {
+	holiday, err := r.service.DeleteHoliday(c.Request.Context(), c.Param("id"), c.Param("holidayId"))
+	if err != nil {
+		return err
+	}
+
+	return c.Write(holiday)
+}
@@ -0,0 +1,104 @@
+package clinic
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/test"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+// mockService answers every call with empty results, so that the tests can check which requests reach it.
+type mockService struct{}
+
+func (s mockService) GetById(ctx context.Context, id string) (entity.Clinic, error) {
+	return entity.Clinic{}, nil
+}
+
+func (s mockService) Count(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (s mockService) Query(ctx context.Context, req QueryClinicsRequest) ([]entity.Clinic, error) {
+	return []entity.Clinic{}, nil
+}
+
+func (s mockService) Create(ctx context.Context, req CreateClinicRequest) (entity.Clinic, error) {
+	return entity.Clinic{}, nil
+}
+
+func (s mockService) Update(ctx context.Context, clinicId string, req UpdateClinicRequest) (entity.Clinic, error) {
+	return entity.Clinic{}, nil
+}
+
+func (s mockService) AddAppointmentTypePrice(ctx context.Context, clinicId string, req AddAppointmentTypePriceRequest) (entity.AppointmentTypePrice, error) {
+	return entity.AppointmentTypePrice{}, nil
+}
+
+func (s mockService) GetAppointmentTypePrices(ctx context.Context, clinicId string) ([]entity.AppointmentTypePrice, error) {
+	return []entity.AppointmentTypePrice{}, nil
+}
+
+func (s mockService) UpdateAppointmentTypePrice(ctx context.Context, clinicId string, req UpdateAppointmentTypePriceRequest) (entity.AppointmentTypePrice, error) {
+	return entity.AppointmentTypePrice{}, nil
+}
+
+func (s mockService) SetOpeningHours(ctx context.Context, clinicId string, req SetOpeningHoursRequest) ([]entity.ClinicOpeningHours, error) {
+	return []entity.ClinicOpeningHours{}, nil
+}
+
+func (s mockService) GetHolidays(ctx context.Context, clinicId string, req GetHolidaysRequest) ([]entity.ClinicHoliday, error) {
+	return []entity.ClinicHoliday{}, nil
+}
+
+func (s mockService) AddHoliday(ctx context.Context, clinicId string, req AddHolidayRequest) (entity.ClinicHoliday, error) {
+	return entity.ClinicHoliday{}, nil
+}
+
+func (s mockService) ImportHolidays(ctx context.Context, clinicId string, calendar io.Reader) ([]entity.ClinicHoliday, error) {
+	return []entity.ClinicHoliday{}, nil
+}
+
+func (s mockService) DeleteHoliday(ctx context.Context, clinicId string, id string) (entity.ClinicHoliday, error) {
+	return entity.ClinicHoliday{}, nil
+}
+
+func TestAPI_roles(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), mockService{}, test.MockAuthHandler(), logger)
+
+	const (
+		ok        = http.StatusOK
+		created   = http.StatusCreated
+		forbidden = http.StatusForbidden
+		anonymous = http.StatusUnauthorized
+	)
+	test.Routes(t, router, []test.RouteTestCase{
+		{Method: "POST", URL: "/clinics", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: created, entity.RoleClinicAdmin: forbidden, entity.RolePatient: forbidden}},
+		{Method: "PUT", URL: "/clinics/" + test.ClinicId, Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+		{Method: "POST", URL: "/clinics/" + test.ClinicId + "/prices", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: created, entity.RoleClinicAdmin: created, entity.RolePatient: forbidden}},
+		{Method: "PUT", URL: "/clinics/" + test.ClinicId + "/prices", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+		{Method: "PUT", URL: "/clinics/" + test.ClinicId + "/opening-hours", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+		{Method: "POST", URL: "/clinics/" + test.ClinicId + "/holidays", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: created, entity.RoleClinicAdmin: created, entity.RolePatient: forbidden}},
+		{Method: "POST", URL: "/clinics/" + test.ClinicId + "/holidays/import", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: created, entity.RoleClinicAdmin: created, entity.RolePatient: forbidden}},
+		{Method: "DELETE", URL: "/clinics/" + test.ClinicId + "/holidays/1", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+	})
+}
+
+func TestAPI_query_sortWithoutSearch(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), NewService(mockRepository{}, nil, nil, nil, nil, logger), test.MockAuthHandler(), logger)
+
+	test.Endpoint(t, router, test.APITestCase{
+		Name:       "sort without search",
+		Method:     "GET",
+		URL:        "/clinics?sort=" + entity.SortRating,
+		Header:     test.MockAuthHeader(entity.RolePatient),
+		WantStatus: http.StatusBadRequest,
+	})
+}
@@ -0,0 +1,290 @@
+package clinic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/dbcontext"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/ical"
+)
+
+// lastDate is used as the end date when asking for all upcoming holidays.
+const lastDate = "9999-12-31"
+
+// maxImportedDays is the maximum number of holidays that can be imported at once.
+const maxImportedDays = 1000
+
+// OpeningHoursRequest represents the opening hours of a clinic on a day of the week.
+type OpeningHoursRequest struct {
+	Weekday int         `json:"weekday"`
+	Open    entity.Time `json:"open"`
+	Close   entity.Time `json:"close"`
+}
+
+func (m OpeningHoursRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Weekday, validation.Min(0), validation.Max(6)),
+		validation.Field(&m.Open, validation.By(validateTimeOfDay)),
+		validation.Field(&m.Close, validation.By(validateTimeOfDay), validation.By(func(value interface{}) error {
+			if !m.Open.Before(value.(entity.Time)) {
+				return validation.NewError("validation_close", "must be after the opening time")
+			}
+			return nil
+		})),
+	)
+}
+
+// SetOpeningHoursRequest represents a request to replace the opening hours of a clinic.
+// The clinic is closed on the days without opening hours. Without any opening hours, it is always open.
+type SetOpeningHoursRequest struct {
+	OpeningHours []OpeningHoursRequest `json:"openingHours"`
+}
+
+func (m SetOpeningHoursRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.OpeningHours, validation.By(func(value interface{}) error {
+			weekdays := make(map[int]bool)
+			for _, hours := range value.([]OpeningHoursRequest) {
+				if weekdays[hours.Weekday] {
+					return validation.NewError("validation_weekday_unique", "must have at most one entry per weekday")
+				}
+				weekdays[hours.Weekday] = true
+			}
+			return nil
+		})),
+	)
+}
+
+// GetHolidaysRequest represents a request for the holidays of a clinic from StartDate to EndDate.
+// Without dates, all upcoming holidays are returned.
+type GetHolidaysRequest struct {
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
+
+func (m GetHolidaysRequest) Validate() error {
+	startDate, _ := time.Parse(entity.DateLayout, m.StartDate)
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.StartDate, validation.Date(entity.DateLayout)),
+		validation.Field(&m.EndDate, validation.Date(entity.DateLayout).Min(startDate).RangeError("must not be before the start date")),
+	)
+}
+
+// AddHolidayRequest represents a request to add a holiday to the calendar of a clinic.
+type AddHolidayRequest struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+func (m AddHolidayRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Date, validation.Required, validation.Date(entity.DateLayout)),
+		validation.Field(&m.Name, validation.Required, validation.Length(1, 255)),
+	)
+}
+
+// validateTimeOfDay checks that the value is a valid time of day.
+func validateTimeOfDay(value interface{}) error {
+	if t := value.(entity.Time); t.Hour > 23 || t.Minute > 59 {
+		return validation.NewError("validation_time_of_day", "must be a valid time of day")
+	}
+	return nil
+}
+
+// SetOpeningHours replaces the opening hours of the clinic.
+func (s service) SetOpeningHours(ctx context.Context, clinicId string, req SetOpeningHoursRequest) ([]entity.ClinicOpeningHours, error) {
+	if !auth.CanManageClinic(ctx, clinicId) {
+		return nil, errors.Forbidden("")
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	clinic, err := s.repo.GetById(ctx, clinicId)
+	if err != nil {
+		return nil, err
+	}
+
+	openingHours := make([]entity.ClinicOpeningHours, len(req.OpeningHours))
+	for i, hours := range req.OpeningHours {
+		openingHours[i] = entity.ClinicOpeningHours{
+			ClinicId: clinic.Id,
+			Weekday:  hours.Weekday,
+			Open:     hours.Open.ToString(),
+			Close:    hours.Close.ToString(),
+		}
+	}
+	err = s.transactional(ctx, func(ctx context.Context) error {
+		return s.repo.ReplaceOpeningHours(ctx, clinic.Id, openingHours)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetOpeningHours(ctx, clinic.Id)
+}
+
+// GetHolidays returns the requested holidays of the clinic.
+func (s service) GetHolidays(ctx context.Context, clinicId string, req GetHolidaysRequest) ([]entity.ClinicHoliday, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	clinic, err := s.repo.GetById(ctx, clinicId)
+	if err != nil {
+		return nil, err
+	}
+	if req.StartDate == "" {
+		req.StartDate = time.Now().In(entity.LoadLocation(clinic.TimeZone)).Format(entity.DateLayout)
+	}
+	if req.EndDate == "" {
+		req.EndDate = lastDate
+	}
+
+	holidays, err := s.repo.GetHolidays(ctx, clinic.Id, req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	if holidays == nil {
+		holidays = []entity.ClinicHoliday{}
+	}
+	return holidays, nil
+}
+
+// AddHoliday adds a day on which the clinic is closed. Only one holiday can fall on a date.
+func (s service) AddHoliday(ctx context.Context, clinicId string, req AddHolidayRequest) (entity.ClinicHoliday, error) {
+	if !auth.CanManageClinic(ctx, clinicId) {
+		return entity.ClinicHoliday{}, errors.Forbidden("")
+	}
+	if err := req.Validate(); err != nil {
+		return entity.ClinicHoliday{}, err
+	}
+
+	clinic, err := s.repo.GetById(ctx, clinicId)
+	if err != nil {
+		return entity.ClinicHoliday{}, err
+	}
+
+	id := entity.GenerateID()
+	err = s.repo.CreateHoliday(ctx, entity.ClinicHoliday{
+		Id:       id,
+		ClinicId: clinic.Id,
+		Date:     req.Date,
+		Name:     req.Name,
+	})
+	if dbcontext.IsUniqueViolation(err) {
+		return entity.ClinicHoliday{}, errors.Conflict(fmt.Sprintf("The clinic already has a holiday on %s.", req.Date))
+	} else if err != nil {
+		return entity.ClinicHoliday{}, err
+	}
+	return s.repo.GetHolidayById(ctx, id)
+}
+
+// ImportHolidays adds every day of the events of the iCalendar file as a holiday of the clinic.
+// Dates on which the clinic already has a holiday are skipped. The added holidays are returned.
+func (s service) ImportHolidays(ctx context.Context, clinicId string, calendar io.Reader) ([]entity.ClinicHoliday, error) {
+	if !auth.CanManageClinic(ctx, clinicId) {
+		return nil, errors.Forbidden("")
+	}
+
+	clinic, err := s.repo.GetById(ctx, clinicId)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := ical.Parse(calendar)
+	if err != nil {
+		return nil, errors.BadRequest(fmt.Sprintf("The calendar cannot be read: %v.", err))
+	}
+	var holidays []entity.ClinicHoliday
+	dates := make(map[string]bool)
+	for _, event := range events {
+		eventDates, err := event.Dates(entity.DateLayout, maxImportedDays-len(holidays))
+		if err != nil {
+			return nil, errors.BadRequest(fmt.Sprintf("The calendar has more than %d days of events.", maxImportedDays))
+		}
+		for _, date := range eventDates {
+			if dates[date] {
+				continue
+			}
+			dates[date] = true
+			holidays = append(holidays, entity.ClinicHoliday{ClinicId: clinic.Id, Date: date, Name: event.Summary})
+		}
+	}
+
+	added := []entity.ClinicHoliday{}
+	err = s.transactional(ctx, func(ctx context.Context) error {
+		existing, err := s.repo.GetHolidays(ctx, clinic.Id, "", lastDate)
+		if err != nil {
+			return err
+		}
+		taken := make(map[string]bool, len(existing))
+		for _, holiday := range existing {
+			taken[holiday.Date] = true
+		}
+
+		for _, holiday := range holidays {
+			if taken[holiday.Date] {
+				continue
+			}
+			holiday.Id = entity.GenerateID()
+			if holiday.Name == "" {
+				holiday.Name = "Holiday"
+			}
+			if err := s.repo.CreateHoliday(ctx, holiday); err != nil {
+				return err
+			}
+			added = append(added, holiday)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// DeleteHoliday removes the holiday with the given ID from the calendar of the clinic.
+func (s service) DeleteHoliday(ctx context.Context, clinicId string, id string) (entity.ClinicHoliday, error) {
+	if !auth.CanManageClinic(ctx, clinicId) {
+		return entity.ClinicHoliday{}, errors.Forbidden("")
+	}
+
+	holiday, err := s.repo.GetHolidayById(ctx, id)
+	if err != nil {
+		return entity.ClinicHoliday{}, err
+	}
+	if holiday.ClinicId != clinicId {
+		return entity.ClinicHoliday{}, errors.NotFound("")
+	}
+
+	if err = s.repo.DeleteHoliday(ctx, holiday); err != nil {
+		return entity.ClinicHoliday{}, err
+	}
+	return holiday, nil
+}
+
+// getOpeningCalendar returns the opening hours of the clinic and its holidays from startDate to endDate.
+func (s service) getOpeningCalendar(ctx context.Context, clinicId string, startDate string, endDate string) (entity.OpeningCalendar, error) {
+	openingHours, err := s.repo.GetOpeningHours(ctx, clinicId)
+	if err != nil {
+		return entity.OpeningCalendar{}, err
+	}
+	holidays, err := s.repo.GetHolidays(ctx, clinicId, startDate, endDate)
+	if err != nil {
+		return entity.OpeningCalendar{}, err
+	}
+
+	calendar := entity.OpeningCalendar{OpeningHours: openingHours, Holidays: holidays}
+	if calendar.OpeningHours == nil {
+		calendar.OpeningHours = []entity.ClinicOpeningHours{}
+	}
+	if calendar.Holidays == nil {
+		calendar.Holidays = []entity.ClinicHoliday{}
+	}
+	return calendar, nil
+}
@@ -22,6 +22,16 @@ type Repository interface {
 	GetAppointmentTypePrice(ctx context.Context, clinicId string, appointmentTypeId string) (entity.AppointmentTypePrice, error)
 	AddAppointmentTypePrice(ctx context.Context, appointmentTypePrice entity.AppointmentTypePrice) error
 	UpdateAppointmentTypePrice(ctx context.Context, appointmentTypePrice entity.AppointmentTypePrice) error
+
+	// GetOpeningHours returns the opening hours of the clinic ordered by weekday.
+	GetOpeningHours(ctx context.Context, clinicId string) ([]entity.ClinicOpeningHours, error)
+	// ReplaceOpeningHours replaces all opening hours of the clinic with the given ones.
+	ReplaceOpeningHours(ctx context.Context, clinicId string, openingHours []entity.ClinicOpeningHours) error
+	// GetHolidays returns the holidays of the clinic from startDate to endDate ordered by date.
+	GetHolidays(ctx context.Context, clinicId string, startDate string, endDate string) ([]entity.ClinicHoliday, error)
+	GetHolidayById(ctx context.Context, id string) (entity.ClinicHoliday, error)
+	CreateHoliday(ctx context.Context, holiday entity.ClinicHoliday) error
+	DeleteHoliday(ctx context.Context, holiday entity.ClinicHoliday) error
 }
 
 type repository struct {
@@ -114,3 +124,49 @@ func (r repository) AddAppointmentTypePrice(ctx context.Context, appointmentType
 func (r repository) UpdateAppointmentTypePrice(ctx context.Context, appointmentTypePrice entity.AppointmentTypePrice) error {
 	return r.db.With(ctx).Model(&appointmentTypePrice).Update()
 }
+
+func (r repository) GetOpeningHours(ctx context.Context, clinicId string) ([]entity.ClinicOpeningHours, error) {
+	var openingHours []entity.ClinicOpeningHours
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"clinic_id": clinicId}).
+		OrderBy("weekday").
+		All(&openingHours)
+	return openingHours, err
+}
+
+func (r repository) ReplaceOpeningHours(ctx context.Context, clinicId string, openingHours []entity.ClinicOpeningHours) error {
+	if _, err := r.db.With(ctx).Delete("clinic_opening_hours", dbx.HashExp{"clinic_id": clinicId}).Execute(); err != nil {
+		return err
+	}
+	for _, hours := range openingHours {
+		if err := r.db.With(ctx).Model(&hours).Insert(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r repository) GetHolidays(ctx context.Context, clinicId string, startDate string, endDate string) ([]entity.ClinicHoliday, error) {
+	var holidays []entity.ClinicHoliday
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.And(dbx.HashExp{"clinic_id": clinicId}, dbx.Between("date", startDate, endDate))).
+		OrderBy("date").
+		All(&holidays)
+	return holidays, err
+}
+
+func (r repository) GetHolidayById(ctx context.Context, id string) (entity.ClinicHoliday, error) {
+	var holiday entity.ClinicHoliday
+	err := r.db.With(ctx).Select().Model(id, &holiday)
+	return holiday, err
+}
+
+func (r repository) CreateHoliday(ctx context.Context, holiday entity.ClinicHoliday) error {
+	return r.db.With(ctx).Model(&holiday).Insert()
+}
+
+func (r repository) DeleteHoliday(ctx context.Context, holiday entity.ClinicHoliday) error {
+	return r.db.With(ctx).Model(&holiday).Delete()
+}
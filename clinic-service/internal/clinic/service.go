@@ -2,28 +2,35 @@ package clinic
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"net/http"
-	"net/url"
+	"io"
+	"sort"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	appointment_type "github.com/matijapetrovic/clinichub/clinic-service/internal/appointment-type"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/availability"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/client/rating"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
-	"github.com/matijapetrovic/clinichub/clinic-service/pkg/httpclient"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/dbcontext"
 	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
 )
 
 type Service interface {
-	GetById(request *http.Request, id string) (entity.Clinic, error)
+	GetById(ctx context.Context, id string) (entity.Clinic, error)
 	Count(ctx context.Context) (int, error)
-	Query(request *http.Request, req QueryClinicsRequest) ([]entity.Clinic, error)
+	Query(ctx context.Context, req QueryClinicsRequest) ([]entity.Clinic, error)
 	Create(ctx context.Context, req CreateClinicRequest) (entity.Clinic, error)
 	Update(ctx context.Context, clinicId string, req UpdateClinicRequest) (entity.Clinic, error)
 	AddAppointmentTypePrice(ctx context.Context, clinicId string, req AddAppointmentTypePriceRequest) (entity.AppointmentTypePrice, error)
 	GetAppointmentTypePrices(ctx context.Context, clinicId string) ([]entity.AppointmentTypePrice, error)
 	UpdateAppointmentTypePrice(ctx context.Context, clinicId string, req UpdateAppointmentTypePriceRequest) (entity.AppointmentTypePrice, error)
+	SetOpeningHours(ctx context.Context, clinicId string, req SetOpeningHoursRequest) ([]entity.ClinicOpeningHours, error)
+	GetHolidays(ctx context.Context, clinicId string, req GetHolidaysRequest) ([]entity.ClinicHoliday, error)
+	AddHoliday(ctx context.Context, clinicId string, req AddHolidayRequest) (entity.ClinicHoliday, error)
+	ImportHolidays(ctx context.Context, clinicId string, calendar io.Reader) ([]entity.ClinicHoliday, error)
+	DeleteHoliday(ctx context.Context, clinicId string, id string) (entity.ClinicHoliday, error)
 }
 
 type QueryClinicsRequest struct {
@@ -31,6 +38,9 @@ type QueryClinicsRequest struct {
 	Date              string `json:"date"`
 	Limit             int    `json:"limit"`
 	Offset            int    `json:"offset"`
+	// Sort orders the clinics found by appointment type and date by their rating or weighted rating, highest first.
+	// It is rejected without AppointmentTypeId and Date, as the plain listing is paged and cannot be ordered by rating.
+	Sort string `json:"sort"`
 }
 
 func (m QueryClinicsRequest) Validate() error {
@@ -42,16 +52,22 @@ func (m QueryClinicsRequest) Validate() error {
 	)
 }
 
+// errSortWithoutSearch is returned when the results of a plain listing are to be sorted by rating.
+var errSortWithoutSearch = validation.NewError("validation_sort_without_search", "can only be used when searching by appointment type and date")
+
 type CreateClinicRequest struct {
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
 	Address     entity.Address `json:"address"`
+	// TimeZone is the IANA time zone of the clinic. It defaults to UTC.
+	TimeZone string `json:"timeZone"`
 }
 
 func (m CreateClinicRequest) Validate() error {
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.Name, validation.Required, validation.Length(1, 50)),
 		validation.Field(&m.Description, validation.Required, validation.Length(1, 256)),
+		validation.Field(&m.TimeZone, validation.By(validateTimeZone)),
 	)
 }
 
@@ -59,15 +75,30 @@ type UpdateClinicRequest struct {
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
 	Address     entity.Address `json:"address"`
+	// TimeZone is the IANA time zone of the clinic. It defaults to the current time zone of the clinic.
+	TimeZone string `json:"timeZone"`
 }
 
 func (m UpdateClinicRequest) Validate() error {
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.Name, validation.Required, validation.Length(1, 50)),
 		validation.Field(&m.Description, validation.Required, validation.Length(1, 256)),
+		validation.Field(&m.TimeZone, validation.By(validateTimeZone)),
 	)
 }
 
+// validateTimeZone checks that the value is the name of a known IANA time zone.
+func validateTimeZone(value interface{}) error {
+	name, _ := value.(string)
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil || name == "Local" {
+		return validation.NewError("validation_time_zone", "must be a valid IANA time zone")
+	}
+	return nil
+}
+
 type AddAppointmentTypePriceRequest struct {
 	AppointmentTypeId string `json:"appointmentTypeId"`
 	Price             uint   `json:"price"`
@@ -95,40 +126,76 @@ func (m UpdateAppointmentTypePriceRequest) Validate() error {
 type service struct {
 	repo                Repository
 	appointmentTypeRepo appointment_type.Repository
+	ratingClient        rating.Client
+	availability        availability.Service
+	transactional       dbcontext.TransactionFunc
 	logger              log.Logger
 }
 
-func NewService(repo Repository, appointmentTypeRepo appointment_type.Repository, logger log.Logger) Service {
-	return service{repo, appointmentTypeRepo, logger}
+func NewService(repo Repository, appointmentTypeRepo appointment_type.Repository, ratingClient rating.Client, availability availability.Service, transactional dbcontext.TransactionFunc, logger log.Logger) Service {
+	return service{repo, appointmentTypeRepo, ratingClient, availability, transactional, logger}
 }
 
-func (s service) GetById(request *http.Request, id string) (entity.Clinic, error) {
-	ctx := request.Context()
+func (s service) GetById(ctx context.Context, id string) (entity.Clinic, error) {
 	clinic, err := s.repo.GetById(ctx, id)
 	if err != nil {
 		return entity.Clinic{}, err
 	}
 
-	rating, err := getClinicRating(clinic.Id, request.Header.Get("Authorization"))
-	if err != nil {
+	clinic.Rating = s.getClinicRating(ctx, clinic.Id)
+
+	today := time.Now().In(entity.LoadLocation(clinic.TimeZone)).Format(entity.DateLayout)
+	if clinic.OpeningCalendar, err = s.getOpeningCalendar(ctx, clinic.Id, today, lastDate); err != nil {
 		return entity.Clinic{}, err
 	}
-	clinic.Rating = rating
 
 	return clinic, nil
 }
 
+// getClinicRating returns the rating of the clinic or, if the rating-service cannot provide it,
+// a rating marked as unavailable so that the clinic can still be returned.
+func (s service) getClinicRating(ctx context.Context, clinicId string) entity.Rating {
+	rating, err := s.ratingClient.GetClinicRating(ctx, clinicId)
+	if err != nil {
+		s.logger.With(ctx).Infof("rating of clinic %s is unavailable: %v", clinicId, err)
+		return entity.Rating{Unavailable: true}
+	}
+	return rating
+}
+
+// getClinicRatings returns the ratings of the clinics keyed by clinic ID. If the rating-service cannot
+// provide them, every clinic gets a rating marked as unavailable.
+func (s service) getClinicRatings(ctx context.Context, clinicIds []string) map[string]entity.Rating {
+	if len(clinicIds) == 0 {
+		return map[string]entity.Rating{}
+	}
+	ratings, err := s.ratingClient.GetClinicRatings(ctx, clinicIds)
+	if err != nil {
+		s.logger.With(ctx).Infof("ratings of clinics are unavailable: %v", err)
+		ratings = make(map[string]entity.Rating, len(clinicIds))
+		for _, id := range clinicIds {
+			ratings[id] = entity.Rating{Unavailable: true}
+		}
+	}
+	return ratings
+}
+
 func (s service) Create(ctx context.Context, req CreateClinicRequest) (entity.Clinic, error) {
 	if err := req.Validate(); err != nil {
 		return entity.Clinic{}, err
 	}
 
+	if req.TimeZone == "" {
+		req.TimeZone = entity.DefaultTimeZone
+	}
+
 	id := entity.GenerateID()
 	err := s.repo.Create(ctx, entity.Clinic{
 		Id:          id,
 		Name:        req.Name,
 		Description: req.Description,
 		Address:     req.Address,
+		TimeZone:    req.TimeZone,
 	})
 
 	if err != nil {
@@ -139,6 +206,10 @@ func (s service) Create(ctx context.Context, req CreateClinicRequest) (entity.Cl
 }
 
 func (s service) Update(ctx context.Context, clinicId string, req UpdateClinicRequest) (entity.Clinic, error) {
+	if !auth.CanManageClinic(ctx, clinicId) {
+		return entity.Clinic{}, errors.Forbidden("")
+	}
+
 	if err := req.Validate(); err != nil {
 		return entity.Clinic{}, err
 	}
@@ -151,6 +222,9 @@ func (s service) Update(ctx context.Context, clinicId string, req UpdateClinicRe
 	clinic.Name = req.Name
 	clinic.Description = req.Description
 	clinic.Address = req.Address
+	if req.TimeZone != "" {
+		clinic.TimeZone = req.TimeZone
+	}
 
 	err = s.repo.Update(ctx, clinic)
 	if err != nil {
@@ -164,10 +238,15 @@ func (s service) Count(ctx context.Context) (int, error) {
 	return s.repo.Count(ctx)
 }
 
-func (s service) Query(request *http.Request, req QueryClinicsRequest) ([]entity.Clinic, error) {
-	ctx := request.Context()
+func (s service) Query(ctx context.Context, req QueryClinicsRequest) ([]entity.Clinic, error) {
 	if req.AppointmentTypeId != "" && req.Date == "" || req.AppointmentTypeId == "" && req.Date != "" {
-		return nil, errors.New("bad request")
+		return nil, errors.BadRequest("bad request")
+	}
+	if err := validation.Validate(req.Sort, validation.In(entity.SortRating, entity.SortWeightedRating)); err != nil {
+		return nil, validation.Errors{"sort": err}
+	}
+	if req.Sort != "" && req.AppointmentTypeId == "" {
+		return nil, validation.Errors{"sort": errSortWithoutSearch}
 	}
 	if req.AppointmentTypeId == "" && req.Date == "" {
 		clinics, err := s.repo.GetPaged(ctx, req.Offset, req.Limit)
@@ -177,22 +256,28 @@ func (s service) Query(request *http.Request, req QueryClinicsRequest) ([]entity
 
 		return clinics, nil
 	} else {
+		date, err := time.Parse(entity.DateLayout, req.Date)
+		if err != nil {
+			return nil, validation.Errors{"date": validation.NewError("validation_date", "must be a date in the "+entity.DateLayout+" format")}
+		}
+
 		clinicIds, err := s.repo.GetIdsByHasPrice(ctx, req.AppointmentTypeId)
 		if err != nil {
 			return nil, err
 		}
 
-		clinics, err := s.repo.GetByIdList(ctx, clinicIds)
+		clinics, err := s.getAvailableClinics(ctx, clinicIds, req.AppointmentTypeId, date)
 		if err != nil {
 			return nil, err
 		}
+		clinicIds = make([]string, len(clinics))
+		for i, clinic := range clinics {
+			clinicIds[i] = clinic.Id
+		}
 
+		ratings := s.getClinicRatings(ctx, clinicIds)
 		for idx, clinic := range clinics {
-			rating, err := getClinicRating(clinic.Id, request.Header.Get("Authorization"))
-			if err != nil {
-				return nil, err
-			}
-			clinic.Rating = rating
+			clinic.Rating = ratings[clinic.Id]
 
 			price, err := s.repo.GetAppointmentTypePrice(ctx, clinic.Id, req.AppointmentTypeId)
 			if err != nil {
@@ -201,45 +286,42 @@ func (s service) Query(request *http.Request, req QueryClinicsRequest) ([]entity
 			clinic.Price = price.Price
 			clinics[idx] = clinic
 		}
+		if req.Sort != "" {
+			sort.SliceStable(clinics, func(i, j int) bool {
+				return clinics[i].Rating.SortKey(req.Sort) > clinics[j].Rating.SortKey(req.Sort)
+			})
+		}
 
 		return clinics, err
 	}
 }
 
-func getClinicRating(clinicId string, token string) (entity.Rating, error) {
-	url, err := url.Parse("http://localhost:8082/v1/clinics/" + clinicId + "/average-rating")
+// getAvailableClinics returns those of the clinics with the given IDs having a doctor specialized
+// for the appointment type with a free slot on the given date.
+func (s service) getAvailableClinics(ctx context.Context, clinicIds []string, appointmentTypeId string, date time.Time) ([]entity.Clinic, error) {
+	if len(clinicIds) == 0 {
+		return []entity.Clinic{}, nil
+	}
+	appointmentType, err := s.appointmentTypeRepo.GetById(ctx, appointmentTypeId)
 	if err != nil {
-		return entity.Rating{}, err
+		return nil, err
 	}
 
-	client := httpclient.NewJsonClient(
-		"GET",
-		url,
-		func(ctx context.Context, r *http.Response) (interface{}, error) {
-			var rating entity.Rating
-			err := json.NewDecoder(r.Body).Decode(&rating)
-			if err != nil {
-				return nil, err
-			}
-			return rating, nil
-		},
-		token,
-		nil,
-	)
-
-	res, err := client.Endpoint()(context.Background(), struct{}{})
+	clinicIds, err = s.availability.GetClinicsWithFreeSlots(ctx, clinicIds, appointmentType, date)
 	if err != nil {
-		return entity.Rating{}, err
+		return nil, err
 	}
-	rating, ok := res.(entity.Rating)
-	if !ok {
-		return entity.Rating{}, errors.New("unexpected error")
+	if len(clinicIds) == 0 {
+		return []entity.Clinic{}, nil
 	}
-
-	return rating, nil
+	return s.repo.GetByIdList(ctx, clinicIds)
 }
 
 func (s service) AddAppointmentTypePrice(ctx context.Context, clinicId string, req AddAppointmentTypePriceRequest) (entity.AppointmentTypePrice, error) {
+	if !auth.CanManageClinic(ctx, clinicId) {
+		return entity.AppointmentTypePrice{}, errors.Forbidden("")
+	}
+
 	clinic, err := s.repo.GetById(ctx, clinicId)
 	if err != nil {
 		return entity.AppointmentTypePrice{}, err
@@ -262,6 +344,10 @@ func (s service) AddAppointmentTypePrice(ctx context.Context, clinicId string, r
 }
 
 func (s service) UpdateAppointmentTypePrice(ctx context.Context, clinicId string, req UpdateAppointmentTypePriceRequest) (entity.AppointmentTypePrice, error) {
+	if !auth.CanManageClinic(ctx, clinicId) {
+		return entity.AppointmentTypePrice{}, errors.Forbidden("")
+	}
+
 	clinic, err := s.repo.GetById(ctx, clinicId)
 	if err != nil {
 		return entity.AppointmentTypePrice{}, err
@@ -0,0 +1,99 @@
+package clinic
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	appointment_type "github.com/matijapetrovic/clinichub/clinic-service/internal/appointment-type"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/availability"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/client/rating"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/httpclient"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+type mockRepository struct {
+	Repository
+	clinics []entity.Clinic
+}
+
+func (m mockRepository) Count(ctx context.Context) (int, error) {
+	return len(m.clinics), nil
+}
+
+func (m mockRepository) GetIdsByHasPrice(ctx context.Context, appointmentTypeId string) ([]string, error) {
+	ids := make([]string, len(m.clinics))
+	for i, clinic := range m.clinics {
+		ids[i] = clinic.Id
+	}
+	return ids, nil
+}
+
+func (m mockRepository) GetByIdList(ctx context.Context, clinicIds []string) ([]entity.Clinic, error) {
+	return m.clinics, nil
+}
+
+func (m mockRepository) GetAppointmentTypePrice(ctx context.Context, clinicId string, appointmentTypeId string) (entity.AppointmentTypePrice, error) {
+	return entity.AppointmentTypePrice{ClinicId: clinicId, AppointmentTypeId: appointmentTypeId, Price: 100}, nil
+}
+
+type mockAppointmentTypeRepository struct {
+	appointment_type.Repository
+}
+
+func (m mockAppointmentTypeRepository) GetById(ctx context.Context, id string) (entity.AppointmentType, error) {
+	return entity.AppointmentType{Id: id}, nil
+}
+
+// mockAvailability finds free slots in every clinic.
+type mockAvailability struct {
+	availability.Service
+}
+
+func (m mockAvailability) GetClinicsWithFreeSlots(ctx context.Context, clinicIds []string, appointmentType entity.AppointmentType, date time.Time) ([]string, error) {
+	return clinicIds, nil
+}
+
+func TestService_Query_ratingServiceDown(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	unreachable := httptest.NewServer(http.NotFoundHandler())
+	unreachable.Close()
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"5xx", failing.URL},
+		{"unreachable", unreachable.URL},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, _ := log.NewForTest()
+			ratingClient := rating.NewClient(httpclient.New(tc.url, httpclient.WithRetries(1, time.Millisecond)))
+			repo := mockRepository{clinics: []entity.Clinic{{Id: "c1"}, {Id: "c2"}}}
+			s := NewService(repo, mockAppointmentTypeRepository{}, ratingClient, mockAvailability{}, nil, logger)
+
+			clinics, err := s.Query(context.Background(), QueryClinicsRequest{
+				AppointmentTypeId: "t1",
+				Date:              time.Now().AddDate(0, 0, 1).Format(entity.DateLayout),
+			})
+			if err != nil {
+				t.Fatalf("Query() error = %v, want the clinics without ratings", err)
+			}
+			if len(clinics) != 2 {
+				t.Fatalf("Query() returned %d clinics, want 2", len(clinics))
+			}
+			for _, clinic := range clinics {
+				if !clinic.Rating.Unavailable || clinic.Price != 100 {
+					t.Errorf("Query() clinic = %+v, want a price and an unavailable rating", clinic)
+				}
+			}
+		})
+	}
+}
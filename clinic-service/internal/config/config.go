@@ -11,6 +11,12 @@ import (
 const (
 	defaultServerPort         = 8081
 	defaultJWTExpirationHours = 72
+	defaultJWKSRefreshMinutes = 15
+	defaultPeerTimeoutSeconds = 5
+	defaultPeerRetries        = 2
+	defaultPeerRetryBackoffMs = 100
+	defaultBreakerThreshold   = 5
+	defaultBreakerCooldownSec = 30
 )
 
 // Config represents an application configuration.
@@ -19,17 +25,51 @@ type Config struct {
 	ServerPort int `yaml:"server_port" env:"SERVER_PORT"`
 	// the data source name (DSN) for connecting to the database. required.
 	DSN string `yaml:"dsn" env:"DSN,secret"`
-	// JWT signing key. required.
+	// JWT signing key used to verify HS256-signed tokens without a key ID.
+	// required unless JWTVerificationKeyFile or JWKSLocation is set.
 	JWTSigningKey string `yaml:"jwt_signing_key" env:"JWT_SIGNING_KEY,secret"`
+	// path to the PEM file holding the RSA public key used to verify RS256-signed tokens.
+	JWTVerificationKeyFile string `yaml:"jwt_verification_key_file" env:"JWT_VERIFICATION_KEY_FILE"`
+	// the expected issuer of JWTs. The issuer is not checked if empty.
+	JWTIssuer string `yaml:"jwt_issuer" env:"JWT_ISSUER"`
+	// URL or file path of the JWKS document holding the keys used to verify tokens with a key ID.
+	JWKSLocation string `yaml:"jwks_location" env:"JWKS_LOCATION"`
+	// JWKS refresh interval in minutes. Defaults to 15 minutes
+	JWKSRefreshInterval int `yaml:"jwks_refresh_interval" env:"JWKS_REFRESH_INTERVAL"`
 	// JWT expiration in hours. Defaults to 72 hours (3 days)
 	JWTExpiration int `yaml:"jwt_expiration" env:"JWT_EXPIRATION"`
+	// the base URL of the rating-service API. required.
+	RatingServiceURL string `yaml:"rating_service_url" env:"RATING_SERVICE_URL"`
+	// timeout of requests to the rating-service API in seconds. Defaults to 5 seconds
+	RatingServiceTimeout int `yaml:"rating_service_timeout" env:"RATING_SERVICE_TIMEOUT"`
+	// the base URL of the scheduling-service API. required.
+	SchedulingServiceURL string `yaml:"scheduling_service_url" env:"SCHEDULING_SERVICE_URL"`
+	// timeout of requests to the scheduling-service API in seconds. Defaults to 5 seconds
+	SchedulingServiceTimeout int `yaml:"scheduling_service_timeout" env:"SCHEDULING_SERVICE_TIMEOUT"`
+	// number of times failed GET requests to other services are retried. Defaults to 2
+	PeerRetries int `yaml:"peer_retries" env:"PEER_RETRIES"`
+	// delay before the first retry in milliseconds, doubled for every further retry. Defaults to 100 milliseconds
+	PeerRetryBackoff int `yaml:"peer_retry_backoff" env:"PEER_RETRY_BACKOFF"`
+	// number of consecutive failed requests after which requests to a service are stopped. Defaults to 5
+	CircuitBreakerThreshold int `yaml:"circuit_breaker_threshold" env:"CIRCUIT_BREAKER_THRESHOLD"`
+	// time in seconds after which a stopped service is tried again. Defaults to 30 seconds
+	CircuitBreakerCooldown int `yaml:"circuit_breaker_cooldown" env:"CIRCUIT_BREAKER_COOLDOWN"`
 }
 
 // Validate validates the application configuration.
 func (c Config) Validate() error {
 	return validation.ValidateStruct(&c,
 		validation.Field(&c.DSN, validation.Required),
-		validation.Field(&c.JWTSigningKey, validation.Required),
+		validation.Field(&c.JWTSigningKey, validation.Required.When(c.JWTVerificationKeyFile == "" && c.JWKSLocation == "")),
+		validation.Field(&c.JWKSRefreshInterval, validation.Min(1)),
+		validation.Field(&c.RatingServiceURL, validation.Required),
+		validation.Field(&c.RatingServiceTimeout, validation.Min(1)),
+		validation.Field(&c.SchedulingServiceURL, validation.Required),
+		validation.Field(&c.SchedulingServiceTimeout, validation.Min(1)),
+		validation.Field(&c.PeerRetries, validation.Min(0)),
+		validation.Field(&c.PeerRetryBackoff, validation.Min(1)),
+		validation.Field(&c.CircuitBreakerThreshold, validation.Min(1)),
+		validation.Field(&c.CircuitBreakerCooldown, validation.Min(1)),
 	)
 }
 
@@ -37,8 +77,15 @@ func (c Config) Validate() error {
 func Load(file string, logger log.Logger) (*Config, error) {
 	// default config
 	c := Config{
-		ServerPort:    defaultServerPort,
-		JWTExpiration: defaultJWTExpirationHours,
+		ServerPort:               defaultServerPort,
+		JWTExpiration:            defaultJWTExpirationHours,
+		JWKSRefreshInterval:      defaultJWKSRefreshMinutes,
+		SchedulingServiceTimeout: defaultPeerTimeoutSeconds,
+		RatingServiceTimeout:     defaultPeerTimeoutSeconds,
+		PeerRetries:              defaultPeerRetries,
+		PeerRetryBackoff:         defaultPeerRetryBackoffMs,
+		CircuitBreakerThreshold:  defaultBreakerThreshold,
+		CircuitBreakerCooldown:   defaultBreakerCooldownSec,
 	}
 
 	// load from YAML config file
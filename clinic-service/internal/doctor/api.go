@@ -4,6 +4,8 @@ import (
 	"net/http"
 
 	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
 	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
 )
@@ -17,8 +19,13 @@ func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routin
 	r.Get("/doctors/<id>", res.getById)
 	r.Get("/clinics/<clinicId>/doctors", res.getByClinicId)
 
-	r.Post("/doctors", res.create)
-	r.Put("/doctors/<id>", res.update)
+	r.Post("/doctors", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.create)
+	r.Put("/doctors/<id>", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.update)
+
+	r.Get("/doctors/<id>/schedule", res.getSchedule)
+	r.Put("/doctors/<id>/shifts", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.setShifts)
+	r.Post("/doctors/<id>/time-off", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.addTimeOff)
+	r.Delete("/doctors/<id>/time-off/<timeOffId>", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.deleteTimeOff)
 }
 
 type resource struct {
@@ -29,9 +36,10 @@ type resource struct {
 func (r resource) getByClinicId(c *routing.Context) error {
 	appointmentTypeId := c.Request.URL.Query().Get("appointmentTypeId")
 	date := c.Request.URL.Query().Get("date")
-	doctors, err := r.service.GetByClinicId(c.Request, c.Param("clinicId"), GetByClinicIdRequest{
+	doctors, err := r.service.GetByClinicId(c.Request.Context(), c.Param("clinicId"), GetByClinicIdRequest{
 		AppointmentTypeId: appointmentTypeId,
 		Date:              date,
+		Sort:              c.Request.URL.Query().Get("sort"),
 	})
 	if err != nil {
 		return err
@@ -86,3 +94,54 @@ func (r resource) update(c *routing.Context) error {
 
 	return c.Write(doctor)
 }
+
+func (r resource) getSchedule(c *routing.Context) error {
+	schedule, err := r.service.GetSchedule(c.Request.Context(), c.Param("id"), GetScheduleRequest{
+		StartDate: c.Query("startDate"),
+		EndDate:   c.Query("endDate"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Write(schedule)
+}
+
+func (r resource) setShifts(c *routing.Context) error {
+	var input SetShiftsRequest
+	if err := c.Read(&input); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest("")
+	}
+
+	shifts, err := r.service.SetShifts(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(shifts)
+}
+
+func (r resource) addTimeOff(c *routing.Context) error {
+	var input AddTimeOffRequest
+	if err := c.Read(&input); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest("")
+	}
+
+	timeOff, err := r.service.AddTimeOff(c.Request.Context(), c.Param("id"), input)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteWithStatus(timeOff, http.StatusCreated)
+}
+
+func (r resource) deleteTimeOff(c *routing.Context) error {
+	timeOff, err := r.service.DeleteTimeOff(c.Request.Context(), c.Param("id"), c.Param("timeOffId"))
+	if err != nil {
+		return err
+	}
+
+	return c.Write(timeOff)
+}
@@ -0,0 +1,84 @@
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/test"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+// mockService answers every call with empty results, so that the tests can check which requests reach it.
+type mockService struct{}
+
+func (s mockService) GetById(ctx context.Context, id string) (entity.Doctor, error) {
+	return entity.Doctor{}, nil
+}
+
+func (s mockService) GetAll(ctx context.Context) ([]entity.Doctor, error) {
+	return []entity.Doctor{}, nil
+}
+
+func (s mockService) GetByClinicId(ctx context.Context, clinicId string, req GetByClinicIdRequest) ([]entity.Doctor, error) {
+	return []entity.Doctor{}, nil
+}
+
+func (s mockService) Create(ctx context.Context, req CreateDoctorRequest) (entity.Doctor, error) {
+	return entity.Doctor{}, nil
+}
+
+func (s mockService) Update(ctx context.Context, doctorId string, req UpdateDoctorRequest) (entity.Doctor, error) {
+	return entity.Doctor{}, nil
+}
+
+func (s mockService) GetSchedule(ctx context.Context, doctorId string, req GetScheduleRequest) (entity.Schedule, error) {
+	return entity.Schedule{}, nil
+}
+
+func (s mockService) SetShifts(ctx context.Context, doctorId string, req SetShiftsRequest) ([]entity.DoctorShift, error) {
+	return []entity.DoctorShift{}, nil
+}
+
+func (s mockService) AddTimeOff(ctx context.Context, doctorId string, req AddTimeOffRequest) (entity.DoctorTimeOff, error) {
+	return entity.DoctorTimeOff{}, nil
+}
+
+func (s mockService) DeleteTimeOff(ctx context.Context, doctorId string, id string) (entity.DoctorTimeOff, error) {
+	return entity.DoctorTimeOff{}, nil
+}
+
+func TestAPI_roles(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), mockService{}, test.MockAuthHandler(), logger)
+
+	const (
+		ok        = http.StatusOK
+		created   = http.StatusCreated
+		forbidden = http.StatusForbidden
+		anonymous = http.StatusUnauthorized
+	)
+	test.Routes(t, router, []test.RouteTestCase{
+		{Method: "POST", URL: "/doctors", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: created, entity.RoleClinicAdmin: created, entity.RolePatient: forbidden}},
+		{Method: "PUT", URL: "/doctors/1", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+		{Method: "PUT", URL: "/doctors/1/shifts", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+		{Method: "POST", URL: "/doctors/1/time-off", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: created, entity.RoleClinicAdmin: created, entity.RolePatient: forbidden}},
+		{Method: "DELETE", URL: "/doctors/1/time-off/1", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+	})
+}
+
+func TestAPI_getByClinicId_sortWithoutSearch(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), NewService(nil, nil, nil, nil, nil, nil, logger), test.MockAuthHandler(), logger)
+
+	test.Endpoint(t, router, test.APITestCase{
+		Name:       "sort without search",
+		Method:     "GET",
+		URL:        "/clinics/" + test.ClinicId + "/doctors?sort=" + entity.SortWeightedRating,
+		Header:     test.MockAuthHeader(entity.RolePatient),
+		WantStatus: http.StatusBadRequest,
+	})
+}
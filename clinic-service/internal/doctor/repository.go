@@ -16,6 +16,21 @@ type Repository interface {
 	GetAll(ctx context.Context) ([]entity.Doctor, error)
 	GetByClinicId(ctx context.Context, clinicId string) ([]entity.Doctor, error)
 	GetByClinicIdAndSpecializationId(ctx context.Context, clinicId string, specializationId string) ([]entity.Doctor, error)
+	// GetByClinicIdsAndSpecializationId returns the doctors of any of the clinics with the given specialization.
+	GetByClinicIdsAndSpecializationId(ctx context.Context, clinicIds []string, specializationId string) ([]entity.Doctor, error)
+	// GetShifts returns the doctor's shifts ordered by weekday and start.
+	GetShifts(ctx context.Context, doctorId string) ([]entity.DoctorShift, error)
+	// GetShiftsByDoctorIds returns the shifts of any of the doctors ordered by weekday and start.
+	GetShiftsByDoctorIds(ctx context.Context, doctorIds []string) ([]entity.DoctorShift, error)
+	// ReplaceShifts replaces all shifts of the doctor with the given ones.
+	ReplaceShifts(ctx context.Context, doctorId string, shifts []entity.DoctorShift) error
+	// GetTimeOff returns the doctor's time off including any of the days from startDate to endDate.
+	GetTimeOff(ctx context.Context, doctorId string, startDate string, endDate string) ([]entity.DoctorTimeOff, error)
+	// GetTimeOffByDoctorIds returns the time off of any of the doctors including any of the days from startDate to endDate.
+	GetTimeOffByDoctorIds(ctx context.Context, doctorIds []string, startDate string, endDate string) ([]entity.DoctorTimeOff, error)
+	GetTimeOffById(ctx context.Context, id string) (entity.DoctorTimeOff, error)
+	CreateTimeOff(ctx context.Context, timeOff entity.DoctorTimeOff) error
+	DeleteTimeOff(ctx context.Context, timeOff entity.DoctorTimeOff) error
 }
 
 type repository struct {
@@ -45,6 +60,19 @@ func (r repository) GetByClinicIdAndSpecializationId(ctx context.Context, clinic
 	return doctors, err
 }
 
+func (r repository) GetByClinicIdsAndSpecializationId(ctx context.Context, clinicIds []string, specializationId string) ([]entity.Doctor, error) {
+	var doctors []entity.Doctor
+	b := make([]interface{}, len(clinicIds))
+	for i := range clinicIds {
+		b[i] = clinicIds[i]
+	}
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.And(dbx.In("clinic_id", b...), dbx.HashExp{"specialization_id": specializationId})).
+		All(&doctors)
+	return doctors, err
+}
+
 func (r repository) GetById(ctx context.Context, id string) (entity.Doctor, error) {
 	var doctor entity.Doctor
 	err := r.db.With(ctx).
@@ -66,3 +94,85 @@ func (r repository) Create(ctx context.Context, doctor entity.Doctor) error {
 func (r repository) Update(ctx context.Context, doctor entity.Doctor) error {
 	return r.db.With(ctx).Model(&doctor).Update()
 }
+
+func (r repository) GetShifts(ctx context.Context, doctorId string) ([]entity.DoctorShift, error) {
+	var shifts []entity.DoctorShift
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"doctor_id": doctorId}).
+		OrderBy("weekday", "start_time").
+		All(&shifts)
+	return shifts, err
+}
+
+func (r repository) GetShiftsByDoctorIds(ctx context.Context, doctorIds []string) ([]entity.DoctorShift, error) {
+	var shifts []entity.DoctorShift
+	b := make([]interface{}, len(doctorIds))
+	for i := range doctorIds {
+		b[i] = doctorIds[i]
+	}
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.In("doctor_id", b...)).
+		OrderBy("weekday", "start_time").
+		All(&shifts)
+	return shifts, err
+}
+
+func (r repository) ReplaceShifts(ctx context.Context, doctorId string, shifts []entity.DoctorShift) error {
+	if _, err := r.db.With(ctx).Delete("doctor_shift", dbx.HashExp{"doctor_id": doctorId}).Execute(); err != nil {
+		return err
+	}
+	for _, shift := range shifts {
+		if err := r.db.With(ctx).Model(&shift).Insert(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r repository) GetTimeOff(ctx context.Context, doctorId string, startDate string, endDate string) ([]entity.DoctorTimeOff, error) {
+	var timeOff []entity.DoctorTimeOff
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.And(
+			dbx.HashExp{"doctor_id": doctorId},
+			dbx.NewExp("start_date<={:endDate}", dbx.Params{"endDate": endDate}),
+			dbx.NewExp("end_date>={:startDate}", dbx.Params{"startDate": startDate}),
+		)).
+		OrderBy("start_date").
+		All(&timeOff)
+	return timeOff, err
+}
+
+func (r repository) GetTimeOffByDoctorIds(ctx context.Context, doctorIds []string, startDate string, endDate string) ([]entity.DoctorTimeOff, error) {
+	var timeOff []entity.DoctorTimeOff
+	b := make([]interface{}, len(doctorIds))
+	for i := range doctorIds {
+		b[i] = doctorIds[i]
+	}
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.And(
+			dbx.In("doctor_id", b...),
+			dbx.NewExp("start_date<={:endDate}", dbx.Params{"endDate": endDate}),
+			dbx.NewExp("end_date>={:startDate}", dbx.Params{"startDate": startDate}),
+		)).
+		OrderBy("start_date").
+		All(&timeOff)
+	return timeOff, err
+}
+
+func (r repository) GetTimeOffById(ctx context.Context, id string) (entity.DoctorTimeOff, error) {
+	var timeOff entity.DoctorTimeOff
+	err := r.db.With(ctx).Select().Model(id, &timeOff)
+	return timeOff, err
+}
+
+func (r repository) CreateTimeOff(ctx context.Context, timeOff entity.DoctorTimeOff) error {
+	return r.db.With(ctx).Model(&timeOff).Insert()
+}
+
+func (r repository) DeleteTimeOff(ctx context.Context, timeOff entity.DoctorTimeOff) error {
+	return r.db.With(ctx).Model(&timeOff).Delete()
+}
@@ -0,0 +1,229 @@
+package doctor
+
+import (
+	"context"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
+)
+
+// minutesPerWeek is the number of minutes in a week, the period after which shifts repeat.
+const minutesPerWeek = 7 * 24 * 60
+
+// lastDate is used as the end date when asking for all upcoming time off.
+const lastDate = "9999-12-31"
+
+// GetScheduleRequest represents a request for the schedule of a doctor with the time off including
+// any of the days from StartDate to EndDate. Without dates, all upcoming time off is returned.
+type GetScheduleRequest struct {
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
+
+func (m GetScheduleRequest) Validate() error {
+	startDate, _ := time.Parse(entity.DateLayout, m.StartDate)
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.StartDate, validation.Date(entity.DateLayout)),
+		validation.Field(&m.EndDate, validation.Date(entity.DateLayout).Min(startDate).RangeError("must not be before the start date")),
+	)
+}
+
+// ShiftRequest represents a shift of the weekly schedule of a doctor. A shift ending before it starts spans midnight.
+type ShiftRequest struct {
+	Weekday int         `json:"weekday"`
+	Start   entity.Time `json:"start"`
+	End     entity.Time `json:"end"`
+}
+
+func (m ShiftRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Weekday, validation.Min(0), validation.Max(6)),
+		validation.Field(&m.Start, validation.By(validateTimeOfDay)),
+		validation.Field(&m.End, validation.By(validateTimeOfDay), validation.By(func(value interface{}) error {
+			if value.(entity.Time) == m.Start {
+				return validation.NewError("validation_shift_end", "must differ from the start")
+			}
+			return nil
+		})),
+	)
+}
+
+// SetShiftsRequest represents a request to replace the weekly schedule of a doctor.
+// An empty list of shifts leaves the doctor without shifts, while a missing or null one goes back to
+// the doctor working the working hours of the doctor every day.
+type SetShiftsRequest struct {
+	Shifts []ShiftRequest `json:"shifts"`
+}
+
+func (m SetShiftsRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Shifts),
+	)
+}
+
+// AddTimeOffRequest represents a request to add time off to the schedule of a doctor.
+type AddTimeOffRequest struct {
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+	Reason    string `json:"reason"`
+}
+
+func (m AddTimeOffRequest) Validate() error {
+	startDate, _ := time.Parse(entity.DateLayout, m.StartDate)
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.StartDate, validation.Required, validation.Date(entity.DateLayout)),
+		validation.Field(&m.EndDate, validation.Required, validation.Date(entity.DateLayout).Min(startDate).RangeError("must not be before the start date")),
+		validation.Field(&m.Reason, validation.Required, validation.In(entity.TimeOffVacation, entity.TimeOffSickLeave, entity.TimeOffHoliday, entity.TimeOffOther)),
+	)
+}
+
+// validateTimeOfDay checks that the value is a valid time of day.
+func validateTimeOfDay(value interface{}) error {
+	if t := value.(entity.Time); t.Hour > 23 || t.Minute > 59 {
+		return validation.NewError("validation_time_of_day", "must be a valid time of day")
+	}
+	return nil
+}
+
+// GetSchedule returns the weekly schedule of the doctor and the requested time off, limited by the calendar of the clinic.
+func (s service) GetSchedule(ctx context.Context, doctorId string, req GetScheduleRequest) (entity.Schedule, error) {
+	if err := req.Validate(); err != nil {
+		return entity.Schedule{}, err
+	}
+	if req.StartDate == "" {
+		req.StartDate = time.Now().UTC().Format(entity.DateLayout)
+	}
+	if req.EndDate == "" {
+		req.EndDate = lastDate
+	}
+
+	doctor, err := s.repo.GetById(ctx, doctorId)
+	if err != nil {
+		return entity.Schedule{}, err
+	}
+	return s.availability.GetSchedule(ctx, doctor, req.StartDate, req.EndDate)
+}
+
+// SetShifts replaces the weekly schedule of the doctor. Shifts of the doctor must not overlap.
+func (s service) SetShifts(ctx context.Context, doctorId string, req SetShiftsRequest) ([]entity.DoctorShift, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if shiftsOverlap(req.Shifts) {
+		return nil, errors.UnprocessableEntity(validation.Errors{
+			"shifts": validation.NewError("validation_shifts_overlap", "must not overlap"),
+		})
+	}
+
+	doctor, err := s.repo.GetById(ctx, doctorId)
+	if err != nil {
+		return nil, err
+	}
+	if !auth.CanManageClinic(ctx, doctor.ClinicId) {
+		return nil, errors.Forbidden("")
+	}
+
+	shifts := make([]entity.DoctorShift, len(req.Shifts))
+	for i, shift := range req.Shifts {
+		shifts[i] = entity.DoctorShift{
+			Id:       entity.GenerateID(),
+			DoctorId: doctor.Id,
+			Weekday:  shift.Weekday,
+			Start:    shift.Start.ToString(),
+			End:      shift.End.ToString(),
+		}
+	}
+
+	doctor.ShiftsConfigured = req.Shifts != nil
+	err = s.transactional(ctx, func(ctx context.Context) error {
+		if err := s.repo.Update(ctx, doctor); err != nil {
+			return err
+		}
+		return s.repo.ReplaceShifts(ctx, doctor.Id, shifts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetShifts(ctx, doctor.Id)
+}
+
+// AddTimeOff adds days on which the doctor does not work to the schedule of the doctor.
+func (s service) AddTimeOff(ctx context.Context, doctorId string, req AddTimeOffRequest) (entity.DoctorTimeOff, error) {
+	if err := req.Validate(); err != nil {
+		return entity.DoctorTimeOff{}, err
+	}
+
+	doctor, err := s.repo.GetById(ctx, doctorId)
+	if err != nil {
+		return entity.DoctorTimeOff{}, err
+	}
+	if !auth.CanManageClinic(ctx, doctor.ClinicId) {
+		return entity.DoctorTimeOff{}, errors.Forbidden("")
+	}
+
+	id := entity.GenerateID()
+	err = s.repo.CreateTimeOff(ctx, entity.DoctorTimeOff{
+		Id:        id,
+		DoctorId:  doctor.Id,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Reason:    req.Reason,
+	})
+	if err != nil {
+		return entity.DoctorTimeOff{}, err
+	}
+	return s.repo.GetTimeOffById(ctx, id)
+}
+
+// DeleteTimeOff removes the time off with the given ID from the schedule of the doctor.
+func (s service) DeleteTimeOff(ctx context.Context, doctorId string, id string) (entity.DoctorTimeOff, error) {
+	timeOff, err := s.repo.GetTimeOffById(ctx, id)
+	if err != nil {
+		return entity.DoctorTimeOff{}, err
+	}
+	if timeOff.DoctorId != doctorId {
+		return entity.DoctorTimeOff{}, errors.NotFound("")
+	}
+
+	doctor, err := s.repo.GetById(ctx, doctorId)
+	if err != nil {
+		return entity.DoctorTimeOff{}, err
+	}
+	if !auth.CanManageClinic(ctx, doctor.ClinicId) {
+		return entity.DoctorTimeOff{}, errors.Forbidden("")
+	}
+
+	if err = s.repo.DeleteTimeOff(ctx, timeOff); err != nil {
+		return entity.DoctorTimeOff{}, err
+	}
+	return timeOff, nil
+}
+
+// shiftsOverlap returns whether any two of the shifts overlap, taking into account that the week repeats.
+func shiftsOverlap(shifts []ShiftRequest) bool {
+	for i := range shifts {
+		aStart, aEnd := weekMinutes(shifts[i])
+		for j := i + 1; j < len(shifts); j++ {
+			bStart, bEnd := weekMinutes(shifts[j])
+			for _, shift := range []int{-minutesPerWeek, 0, minutesPerWeek} {
+				if aStart < bEnd+shift && bStart+shift < aEnd {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// weekMinutes returns the start and the end of the shift in minutes since the start of Sunday.
+func weekMinutes(shift ShiftRequest) (int, int) {
+	start := shift.Weekday*24*60 + int(shift.Start.Hour*60+shift.Start.Minute)
+	length := int(shift.End.Hour*60+shift.End.Minute) - int(shift.Start.Hour*60+shift.Start.Minute)
+	if length <= 0 {
+		length += 24 * 60
+	}
+	return start, start + length
+}
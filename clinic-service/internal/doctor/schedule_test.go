@@ -0,0 +1,102 @@
+package doctor
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/availability"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/clinic"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/test"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+// memoryRepository keeps a single doctor and the doctor's shifts in memory.
+type memoryRepository struct {
+	Repository
+	doctor *entity.Doctor
+	shifts *[]entity.DoctorShift
+}
+
+func (r memoryRepository) GetById(ctx context.Context, id string) (entity.Doctor, error) {
+	return *r.doctor, nil
+}
+
+func (r memoryRepository) Update(ctx context.Context, doctor entity.Doctor) error {
+	*r.doctor = doctor
+	return nil
+}
+
+func (r memoryRepository) GetShifts(ctx context.Context, doctorId string) ([]entity.DoctorShift, error) {
+	return *r.shifts, nil
+}
+
+func (r memoryRepository) ReplaceShifts(ctx context.Context, doctorId string, shifts []entity.DoctorShift) error {
+	*r.shifts = shifts
+	return nil
+}
+
+func (r memoryRepository) GetTimeOff(ctx context.Context, doctorId string, startDate string, endDate string) ([]entity.DoctorTimeOff, error) {
+	return nil, nil
+}
+
+// alwaysOpenClinicRepository returns a clinic that is open all day every day.
+type alwaysOpenClinicRepository struct {
+	clinic.Repository
+}
+
+func (r alwaysOpenClinicRepository) GetById(ctx context.Context, id string) (entity.Clinic, error) {
+	return entity.Clinic{Id: id, TimeZone: entity.DefaultTimeZone}, nil
+}
+
+func (r alwaysOpenClinicRepository) GetOpeningHours(ctx context.Context, clinicId string) ([]entity.ClinicOpeningHours, error) {
+	return nil, nil
+}
+
+func (r alwaysOpenClinicRepository) GetHolidays(ctx context.Context, clinicId string, startDate string, endDate string) ([]entity.ClinicHoliday, error) {
+	return nil, nil
+}
+
+func TestService_SetShifts_defaultOnlyUntilConfigured(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantShifts int
+	}{
+		{"shifts", `{"shifts":[{"weekday":1,"start":{"hour":8,"minute":0},"end":{"hour":12,"minute":0}}]}`, 1},
+		{"explicitly empty", `{"shifts":[]}`, 0},
+		{"null goes back to the working hours", `{"shifts":null}`, 7},
+		{"missing goes back to the working hours", `{}`, 7},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, _ := log.NewForTest()
+			doctor := entity.Doctor{Id: "d1", ClinicId: test.ClinicId, WorkStart: "08:00", WorkEnd: "16:00", ShiftsConfigured: true}
+			shifts := []entity.DoctorShift{{Id: "s1", DoctorId: "d1", Weekday: 2, Start: "10:00", End: "11:00"}}
+			repo := memoryRepository{doctor: &doctor, shifts: &shifts}
+			availabilityService := availability.NewService(repo, alwaysOpenClinicRepository{}, nil, logger)
+			transactional := func(ctx context.Context, f func(ctx context.Context) error) error { return f(ctx) }
+			s := NewService(repo, nil, nil, nil, availabilityService, transactional, logger)
+
+			router := test.MockRouter(logger)
+			RegisterHandlers(router.Group(""), s, test.MockAuthHandler(), logger)
+			test.Endpoint(t, router, test.APITestCase{
+				Name:       "set shifts",
+				Method:     "PUT",
+				URL:        "/doctors/d1/shifts",
+				Body:       tc.body,
+				Header:     test.MockAuthHeader(entity.RoleClinicAdmin),
+				WantStatus: http.StatusOK,
+			})
+
+			schedule, err := s.GetSchedule(context.Background(), "d1", GetScheduleRequest{})
+			if err != nil {
+				t.Fatalf("GetSchedule() error = %v", err)
+			}
+			if len(schedule.Shifts) != tc.wantShifts {
+				t.Errorf("GetSchedule() shifts = %+v, want %d shifts", schedule.Shifts, tc.wantShifts)
+			}
+		})
+	}
+}
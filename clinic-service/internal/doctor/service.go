@@ -2,27 +2,31 @@ package doctor
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
-	"net/http"
-	"net/url"
 	"sort"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	appointment_type "github.com/matijapetrovic/clinichub/clinic-service/internal/appointment-type"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/availability"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/client/rating"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/clinic"
 	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
-	"github.com/matijapetrovic/clinichub/clinic-service/pkg/httpclient"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/dbcontext"
 	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
 )
 
 type Service interface {
 	GetById(ctx context.Context, id string) (entity.Doctor, error)
 	GetAll(ctx context.Context) ([]entity.Doctor, error)
-	GetByClinicId(request *http.Request, clinicId string, req GetByClinicIdRequest) ([]entity.Doctor, error)
+	GetByClinicId(ctx context.Context, clinicId string, req GetByClinicIdRequest) ([]entity.Doctor, error)
 	Create(ctx context.Context, req CreateDoctorRequest) (entity.Doctor, error)
 	Update(ctx context.Context, doctorId string, req UpdateDoctorRequest) (entity.Doctor, error)
+	GetSchedule(ctx context.Context, doctorId string, req GetScheduleRequest) (entity.Schedule, error)
+	SetShifts(ctx context.Context, doctorId string, req SetShiftsRequest) ([]entity.DoctorShift, error)
+	AddTimeOff(ctx context.Context, doctorId string, req AddTimeOffRequest) (entity.DoctorTimeOff, error)
+	DeleteTimeOff(ctx context.Context, doctorId string, id string) (entity.DoctorTimeOff, error)
 }
 
 type CreateDoctorRequest struct {
@@ -59,9 +63,15 @@ func (m UpdateDoctorRequest) Validate() error {
 	)
 }
 
+// errSortWithoutSearch is returned when the doctors of a plain listing are to be sorted by rating.
+var errSortWithoutSearch = validation.NewError("validation_sort_without_search", "can only be used when searching by appointment type and date")
+
 type GetByClinicIdRequest struct {
 	AppointmentTypeId string `json:"appointmentTypeId"`
 	Date              string `json:"date"`
+	// Sort orders the doctors found by appointment type and date by their rating or weighted rating, highest first.
+	// It is rejected without AppointmentTypeId, as the doctors of the plain listing come without ratings.
+	Sort string `json:"sort"`
 }
 
 func (m GetByClinicIdRequest) Validate() error {
@@ -76,11 +86,14 @@ type service struct {
 	repo                Repository
 	clinicRepo          clinic.Repository
 	appointmentTypeRepo appointment_type.Repository
+	ratingClient        rating.Client
+	availability        availability.Service
+	transactional       dbcontext.TransactionFunc
 	logger              log.Logger
 }
 
-func NewService(repo Repository, clinicRepo clinic.Repository, appointmentTypeRepo appointment_type.Repository, logger log.Logger) Service {
-	return service{repo, clinicRepo, appointmentTypeRepo, logger}
+func NewService(repo Repository, clinicRepo clinic.Repository, appointmentTypeRepo appointment_type.Repository, ratingClient rating.Client, availability availability.Service, transactional dbcontext.TransactionFunc, logger log.Logger) Service {
+	return service{repo, clinicRepo, appointmentTypeRepo, ratingClient, availability, transactional, logger}
 }
 
 func (s service) GetById(ctx context.Context, id string) (entity.Doctor, error) {
@@ -99,8 +112,14 @@ func (s service) GetById(ctx context.Context, id string) (entity.Doctor, error)
 		return entity.Doctor{}, err
 	}
 
+	clinic, err := s.clinicRepo.GetById(ctx, doctor.ClinicId)
+	if err != nil {
+		return entity.Doctor{}, err
+	}
+
 	doctor.AppointmentType = appointmentType
 	doctor.AppointmentTypePrice = appointmentPrice.Price
+	doctor.TimeZone = clinic.TimeZone
 
 	return doctor, nil
 }
@@ -110,6 +129,10 @@ func (s service) Create(ctx context.Context, req CreateDoctorRequest) (entity.Do
 		return entity.Doctor{}, err
 	}
 
+	if !auth.CanManageClinic(ctx, req.ClinicId) {
+		return entity.Doctor{}, errors.Forbidden("")
+	}
+
 	clinic, err := s.clinicRepo.GetById(ctx, req.ClinicId)
 	if err != nil {
 		return entity.Doctor{}, err
@@ -147,6 +170,10 @@ func (s service) Update(ctx context.Context, doctorId string, req UpdateDoctorRe
 		return entity.Doctor{}, err
 	}
 
+	if !auth.CanManageClinic(ctx, doctor.ClinicId) {
+		return entity.Doctor{}, errors.Forbidden("")
+	}
+
 	doctor.FirstName = req.FirstName
 	doctor.LastName = req.LastName
 	doctor.WorkStart = req.WorkStart.ToString()
@@ -165,25 +192,21 @@ func (s service) Update(ctx context.Context, doctorId string, req UpdateDoctorRe
 	return doctor, nil
 }
 
-type Appointment struct {
-	Id                string    `json:"id"`
-	ClinicId          string    `json:"clinicId"`
-	DoctorId          string    `json:"doctorId"`
-	PatientId         string    `json:"patientId"`
-	AppointmentTypeId string    `json:"appointmentTypeId"`
-	Price             uint      `json:"price"`
-	Time              time.Time `json:"time"`
-}
-
-func (s service) GetByClinicId(request *http.Request, clinicId string, req GetByClinicIdRequest) ([]entity.Doctor, error) {
+func (s service) GetByClinicId(ctx context.Context, clinicId string, req GetByClinicIdRequest) ([]entity.Doctor, error) {
+	if err := validation.Validate(req.Sort, validation.In(entity.SortRating, entity.SortWeightedRating)); err != nil {
+		return nil, validation.Errors{"sort": err}
+	}
+	if req.Sort != "" && req.AppointmentTypeId == "" {
+		return nil, validation.Errors{"sort": errSortWithoutSearch}
+	}
 	if req.AppointmentTypeId == "" {
-		doctors, err := s.repo.GetByClinicId(request.Context(), clinicId)
+		doctors, err := s.repo.GetByClinicId(ctx, clinicId)
 		if err != nil {
 			return nil, err
 		}
 
 		for i, doctor := range doctors {
-			specialization, err := s.appointmentTypeRepo.GetById(request.Context(), doctor.SpecializationId)
+			specialization, err := s.appointmentTypeRepo.GetById(ctx, doctor.SpecializationId)
 			if err != nil {
 				return nil, err
 			}
@@ -193,120 +216,80 @@ func (s service) GetByClinicId(request *http.Request, clinicId string, req GetBy
 
 		return doctors, nil
 	}
-	doctors, err := s.repo.GetByClinicIdAndSpecializationId(request.Context(), clinicId, req.AppointmentTypeId)
+	doctors, err := s.repo.GetByClinicIdAndSpecializationId(ctx, clinicId, req.AppointmentTypeId)
 	if err != nil {
 		return nil, err
 	}
 
-	appointmentPrice, err := s.clinicRepo.GetAppointmentTypePrice(request.Context(), clinicId, req.AppointmentTypeId)
+	appointmentPrice, err := s.clinicRepo.GetAppointmentTypePrice(ctx, clinicId, req.AppointmentTypeId)
 	if err != nil {
 		return nil, err
 	}
 
-	for i, doctor := range doctors {
-		appointments, err := getDoctorAppointments(doctor.Id, req.Date, request.Header.Get("Authorization"))
-		if err != nil {
-			return nil, err
-		}
-
-		workStart, _ := entity.ParseTime(doctor.WorkStart)
-		workEnd, _ := entity.ParseTime(doctor.WorkEnd)
-		workingHours := entity.GetHours(workStart, workEnd)
-
-		for _, appointment := range appointments {
-			hour, _, _ := appointment.Time.Clock()
-			delete(workingHours, uint(hour))
-		}
-
-		sortedWorkingHours := make([]string, 0, len(workingHours))
-		for k, _ := range workingHours {
-			time := entity.Time{Hour: k, Minute: 0}.ToString()
-			sortedWorkingHours = append(sortedWorkingHours, time)
-		}
-
-		sort.Strings(sortedWorkingHours)
-		doctor.AvailableHours = sortedWorkingHours
-		doctor.AppointmentTypePrice = appointmentPrice.Price
-
-		rating, err := getDoctorRating(doctor.Id, request.Header.Get("Authorization"))
-		if err != nil {
-			return nil, err
-		}
-		doctor.Rating = rating
-		doctors[i] = doctor
+	appointmentType, err := s.appointmentTypeRepo.GetById(ctx, req.AppointmentTypeId)
+	if err != nil {
+		return nil, err
 	}
 
-	return doctors, nil
-}
-
-func getDoctorAppointments(doctorId string, date string, token string) ([]Appointment, error) {
-	url, err := url.Parse("http://localhost:8083/v1/doctors/" + doctorId + "/appointments")
+	clinic, err := s.clinicRepo.GetById(ctx, clinicId)
 	if err != nil {
 		return nil, err
 	}
 
-	queryParamMap := map[string]string{
-		"date": date,
+	date, err := time.Parse(entity.DateLayout, req.Date)
+	if err != nil {
+		return nil, validation.Errors{"date": validation.NewError("validation_date", "must be a date in the "+entity.DateLayout+" format")}
 	}
-
-	client := httpclient.NewJsonClient(
-		"GET",
-		url,
-		func(ctx context.Context, r *http.Response) (interface{}, error) {
-			var appointment []Appointment
-			err := json.NewDecoder(r.Body).Decode(&appointment)
-			if err != nil {
-				return nil, err
-			}
-			return appointment, nil
-		},
-		token,
-		httpclient.QueryParamBeforeFunc(queryParamMap),
-	)
-
-	res, err := client.Endpoint()(context.Background(), struct{}{})
+	slots, err := s.availability.GetFreeSlots(ctx, doctors, appointmentType, date)
 	if err != nil {
 		return nil, err
 	}
-	appointments, ok := res.([]Appointment)
-	if !ok {
-		return nil, errors.New("unexpected error")
+
+	doctorIds := make([]string, len(doctors))
+	for i, doctor := range doctors {
+		doctorIds[i] = doctor.Id
 	}
+	ratings := s.getDoctorRatings(ctx, doctorIds)
 
-	return appointments, nil
-}
+	for i, doctor := range doctors {
+		availableHours := make([]string, len(slots[doctor.Id]))
+		for j, slot := range slots[doctor.Id] {
+			availableHours[j] = entity.Time{Hour: uint(slot.Hour()), Minute: uint(slot.Minute())}.ToString()
+		}
 
-func getDoctorRating(doctorId string, token string) (entity.Rating, error) {
-	url, err := url.Parse("http://localhost:8082/v1/doctors/" + doctorId + "/average-rating")
-	if err != nil {
-		return entity.Rating{}, err
+		doctor.AvailableHours = availableHours
+		doctor.AvailableSlots = slots[doctor.Id]
+		doctor.AppointmentType = appointmentType
+		doctor.AppointmentTypePrice = appointmentPrice.Price
+		doctor.TimeZone = clinic.TimeZone
+
+		doctor.Rating = ratings[doctor.Id]
+		doctors[i] = doctor
+	}
+	if req.Sort != "" {
+		sort.SliceStable(doctors, func(i, j int) bool {
+			return doctors[i].Rating.SortKey(req.Sort) > doctors[j].Rating.SortKey(req.Sort)
+		})
 	}
 
-	client := httpclient.NewJsonClient(
-		"GET",
-		url,
-		func(ctx context.Context, r *http.Response) (interface{}, error) {
-			var rating entity.Rating
-			err := json.NewDecoder(r.Body).Decode(&rating)
-			if err != nil {
-				return nil, err
-			}
-			return rating, nil
-		},
-		token,
-		nil,
-	)
+	return doctors, nil
+}
 
-	res, err := client.Endpoint()(context.Background(), struct{}{})
-	if err != nil {
-		return entity.Rating{}, err
+// getDoctorRatings returns the ratings of the doctors keyed by doctor ID. If the rating-service cannot
+// provide them, every doctor gets a rating marked as unavailable.
+func (s service) getDoctorRatings(ctx context.Context, doctorIds []string) map[string]entity.Rating {
+	if len(doctorIds) == 0 {
+		return map[string]entity.Rating{}
 	}
-	rating, ok := res.(entity.Rating)
-	if !ok {
-		return entity.Rating{}, errors.New("unexpected error")
+	ratings, err := s.ratingClient.GetDoctorRatings(ctx, doctorIds)
+	if err != nil {
+		s.logger.With(ctx).Infof("ratings of doctors are unavailable: %v", err)
+		ratings = make(map[string]entity.Rating, len(doctorIds))
+		for _, id := range doctorIds {
+			ratings[id] = entity.Rating{Unavailable: true}
+		}
 	}
-
-	return rating, nil
+	return ratings
 }
 
 func (s service) GetAll(ctx context.Context) ([]entity.Doctor, error) {
@@ -1,6 +1,17 @@
 package entity
 
+import "time"
+
 type AppointmentType struct {
 	Id   string `json:"id"`
 	Name string `json:"name"`
+	// Duration is the length of an appointment in minutes.
+	Duration uint `json:"duration"`
+	// Buffer is the time in minutes the doctor needs after an appointment before the next one can start.
+	Buffer uint `json:"buffer"`
+}
+
+// SlotLength returns the time an appointment of this type keeps the doctor busy, including the buffer.
+func (t AppointmentType) SlotLength() time.Duration {
+	return time.Duration(t.Duration+t.Buffer) * time.Minute
 }
@@ -5,8 +5,13 @@ type Clinic struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Address     `json:"address"`
-	Rating      `json:"rating" db:"-"`
-	Price       uint `json:"price" db:"-"`
+	// TimeZone is the IANA time zone of the clinic, e.g. "Europe/Belgrade". Opening hours and
+	// the shifts of its doctors are given in the local time of the clinic.
+	TimeZone string `json:"timeZone"`
+	Rating   `json:"rating" db:"-"`
+	Price    uint `json:"price" db:"-"`
+	// OpeningCalendar holds the opening hours and the upcoming holidays of the clinic.
+	OpeningCalendar `db:"-"`
 }
 
 type Address struct {
@@ -25,4 +30,22 @@ type AppointmentTypePrice struct {
 type Rating struct {
 	Rating float32 `json:"rating"`
 	Count  int     `json:"count"`
+	// WeightedRating is the average rating weighted by the number of ratings, used to rank search results.
+	WeightedRating float32 `json:"weightedRating"`
+	// Unavailable is set when the rating could not be fetched from the rating-service.
+	Unavailable bool `json:"unavailable,omitempty"`
+}
+
+// Orders of search results.
+const (
+	SortRating         = "rating"
+	SortWeightedRating = "weightedRating"
+)
+
+// SortKey returns the value by which the rating is ranked in the given order, higher first.
+func (r Rating) SortKey(sort string) float32 {
+	if sort == SortWeightedRating {
+		return r.WeightedRating
+	}
+	return r.Rating
 }
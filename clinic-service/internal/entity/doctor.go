@@ -1,9 +1,7 @@
 package entity
 
 import (
-	"fmt"
-	"strconv"
-	"strings"
+	"time"
 )
 
 type Doctor struct {
@@ -17,70 +15,13 @@ type Doctor struct {
 	AppointmentType      `json:"specialization" db:"-"`
 	AppointmentTypePrice uint     `json:"specializationPrice" db:"-"`
 	AvailableHours       []string `json:"availableHours" db:"-"`
-	Rating               `json:"rating" db:"-"`
-}
-
-type Time struct {
-	Hour   uint `json:"hour"`
-	Minute uint `json:"minute"`
-}
-
-func ParseTime(s string) (Time, error) {
-	split := strings.Split(s, ":")
-	hour, err := strconv.Atoi(split[0])
-	if err != nil {
-		return Time{}, err
-	}
-	minute, err := strconv.Atoi(split[1])
-	if err != nil {
-		return Time{}, err
-	}
-	return Time{Hour: uint(hour), Minute: uint(minute)}, nil
-}
-
-func (t Time) ToString() string {
-	hour := fmt.Sprintf("%d", t.Hour)
-	if t.Hour < 10 {
-		hour = "0" + hour
-	}
-	minute := fmt.Sprintf("%d", t.Minute)
-	if t.Minute < 10 {
-		minute = "0" + minute
-	}
-	return fmt.Sprintf("%s:%s", hour, minute)
-}
-
-func (t Time) Before(o Time) bool {
-	if t.Hour < o.Hour {
-		return true
-	}
-
-	if t.Hour > o.Hour {
-		return false
-	}
-
-	if t.Minute < o.Minute {
-		return true
-	}
-	return false
-}
-
-func GetHours(from Time, to Time) map[uint]struct{} {
-	hours := make(map[uint]struct{})
-
-	if from.Before(to) {
-		for hour := from.Hour; hour < to.Hour; hour++ {
-			hours[hour] = struct{}{}
-		}
-	} else {
-		for hour := from.Hour; hour < 24; hour++ {
-			hours[hour] = struct{}{}
-		}
-
-		for hour := uint(0); hour < to.Hour; hour++ {
-			hours[hour] = struct{}{}
-		}
-	}
-	return hours
-
+	// AvailableSlots are the start times of the available hours with the offset of the clinic's time zone,
+	// telling apart the hours repeated when daylight saving time ends.
+	AvailableSlots []time.Time `json:"availableSlots,omitempty" db:"-"`
+	// ShiftsConfigured tells whether the shifts of the doctor have been set. Until then the doctor works
+	// the working hours every day, while a doctor whose shifts were set to none does not work at all.
+	ShiftsConfigured bool `json:"shiftsConfigured" db:"shifts_configured"`
+	// TimeZone is the time zone of the doctor's clinic, in which the working and available hours are given.
+	TimeZone string `json:"timeZone" db:"-"`
+	Rating   `json:"rating" db:"-"`
 }
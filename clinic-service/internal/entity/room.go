@@ -0,0 +1,15 @@
+package entity
+
+// Room is a room of a clinic in which appointments of the supported appointment types can take place.
+type Room struct {
+	Id                 string   `json:"id"`
+	ClinicId           string   `json:"clinicId"`
+	Name               string   `json:"name"`
+	AppointmentTypeIds []string `json:"appointmentTypeIds" db:"-"`
+}
+
+// RoomAppointmentType records that an appointment type is supported by a room.
+type RoomAppointmentType struct {
+	RoomId            string `db:"pk"`
+	AppointmentTypeId string `db:"pk"`
+}
@@ -0,0 +1,239 @@
+package entity
+
+import (
+	"sort"
+	"time"
+)
+
+// DateLayout is the layout of dates such as the first and last day of a doctor's time off.
+const DateLayout = "2006-01-02"
+
+// DefaultTimeZone is the time zone of clinics for which no time zone is set.
+const DefaultTimeZone = "UTC"
+
+// LoadLocation returns the location of the IANA time zone with the given name, or UTC if the time zone is unknown.
+func LoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		return time.UTC
+	}
+	return location
+}
+
+// Reasons for which a doctor takes time off.
+const (
+	TimeOffVacation  = "vacation"
+	TimeOffSickLeave = "sick_leave"
+	TimeOffHoliday   = "holiday"
+	TimeOffOther     = "other"
+)
+
+// DoctorShift is a period of the week in which a doctor works. A shift ending before it starts spans midnight.
+type DoctorShift struct {
+	Id       string `json:"id"`
+	DoctorId string `json:"doctorId"`
+	// Weekday is the day the shift starts on, from 0 (Sunday) to 6 (Saturday).
+	Weekday int    `json:"weekday"`
+	Start   string `json:"start" db:"start_time"`
+	End     string `json:"end" db:"end_time"`
+}
+
+// DoctorTimeOff is a period of whole days, such as a vacation, in which a doctor does not work.
+// Both the first and the last day are included.
+type DoctorTimeOff struct {
+	Id        string `json:"id"`
+	DoctorId  string `json:"doctorId"`
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+	Reason    string `json:"reason"`
+}
+
+// Includes returns whether the time off includes the given date.
+func (t DoctorTimeOff) Includes(date time.Time) bool {
+	d := date.Format(DateLayout)
+	return t.StartDate <= d && d <= t.EndDate
+}
+
+// ClinicOpeningHours are the hours in which a clinic is open on a day of the week.
+type ClinicOpeningHours struct {
+	ClinicId string `json:"clinicId" db:"pk"`
+	// Weekday is the day of the week, from 0 (Sunday) to 6 (Saturday).
+	Weekday int    `json:"weekday" db:"pk"`
+	Open    string `json:"open" db:"open_time"`
+	Close   string `json:"close" db:"close_time"`
+}
+
+// ClinicHoliday is a day on which a clinic is closed.
+type ClinicHoliday struct {
+	Id       string `json:"id"`
+	ClinicId string `json:"clinicId"`
+	Date     string `json:"date"`
+	Name     string `json:"name"`
+}
+
+// OpeningCalendar describes when a clinic is open. A clinic without opening hours is open all day every day
+// except on its holidays.
+type OpeningCalendar struct {
+	OpeningHours []ClinicOpeningHours `json:"openingHours"`
+	Holidays     []ClinicHoliday      `json:"holidays"`
+}
+
+// IsOpenOn returns whether the clinic is open at some time on the given date (in the clinic's time zone).
+func (c OpeningCalendar) IsOpenOn(date time.Time) bool {
+	if c.isHoliday(date) {
+		return false
+	}
+	if len(c.OpeningHours) == 0 {
+		return true
+	}
+	for _, hours := range c.OpeningHours {
+		if hours.Weekday == int(date.Weekday()) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpenBetween returns whether the clinic is open the whole time from start to end, which must be on the same day.
+// Both are expected in the clinic's time zone.
+func (c OpeningCalendar) IsOpenBetween(start time.Time, end time.Time) bool {
+	if c.isHoliday(start) {
+		return false
+	}
+	if len(c.OpeningHours) == 0 {
+		return true
+	}
+	for _, hours := range c.OpeningHours {
+		if hours.Weekday != int(start.Weekday()) {
+			continue
+		}
+		open, err := ParseTime(hours.Open)
+		if err != nil {
+			continue
+		}
+		closing, err := ParseTime(hours.Close)
+		if err != nil {
+			continue
+		}
+		if !start.Before(open.on(start)) && !end.After(closing.on(start)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHoliday returns whether the given date is one of the holidays.
+func (c OpeningCalendar) isHoliday(date time.Time) bool {
+	d := date.Format(DateLayout)
+	for _, holiday := range c.Holidays {
+		if holiday.Date == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Schedule is the weekly schedule of a doctor together with the doctor's time off.
+// The doctor only works while the clinic of the doctor is open. Shifts and opening hours are
+// given in the local time of the clinic.
+type Schedule struct {
+	Shifts  []DoctorShift   `json:"shifts"`
+	TimeOff []DoctorTimeOff `json:"timeOff"`
+	// TimeZone is the IANA time zone of the clinic, e.g. "Europe/Belgrade".
+	TimeZone string `json:"timeZone"`
+	OpeningCalendar
+}
+
+// Location returns the location of the schedule's time zone.
+func (s Schedule) Location() *time.Location {
+	return LoadLocation(s.TimeZone)
+}
+
+// DefaultShifts returns the shifts of a doctor working from workStart to workEnd every day of the week.
+func DefaultShifts(doctorId string, workStart string, workEnd string) []DoctorShift {
+	shifts := make([]DoctorShift, 7)
+	for weekday := range shifts {
+		shifts[weekday] = DoctorShift{DoctorId: doctorId, Weekday: weekday, Start: workStart, End: workEnd}
+	}
+	return shifts
+}
+
+// GetSlots returns the start times of the appointment slots on the given date, in the local time of the clinic.
+// Only the year, month and day of the date are used. Slots start every step from the start of a shift and
+// each has to end, after length, by the end of the shift. Shifts starting on a day off are left out, while
+// shifts that started the day before and span midnight are included. Slots in which the clinic is not open
+// the whole time are left out as well. On days with a daylight saving time transition, shifts keep their
+// local start and end, so they are shorter or longer than usual.
+func (s Schedule) GetSlots(date time.Time, length time.Duration, step time.Duration) []time.Time {
+	if step <= 0 {
+		return nil
+	}
+	// days are represented by their noon, as midnight may be skipped by a daylight saving time transition
+	noon := time.Date(date.Year(), date.Month(), date.Day(), 12, 0, 0, 0, s.Location())
+	day := Time{}.on(noon)
+	nextDay := Time{}.on(noon.AddDate(0, 0, 1))
+
+	var slots []time.Time
+	for _, shiftDay := range []time.Time{noon.AddDate(0, 0, -1), noon} {
+		if s.isDayOff(shiftDay) {
+			continue
+		}
+		for _, shift := range s.Shifts {
+			if shift.Weekday != int(shiftDay.Weekday()) {
+				continue
+			}
+			start, end, ok := shift.interval(shiftDay)
+			if !ok {
+				continue
+			}
+			for slot := start; !slot.Add(length).After(end); slot = slot.Add(step) {
+				if !slot.Before(day) && slot.Before(nextDay) && s.IsOpenBetween(slot, slot.Add(length)) {
+					slots = append(slots, slot)
+				}
+			}
+		}
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Before(slots[j]) })
+	return slots
+}
+
+// isDayOff returns whether the doctor has time off on the given date.
+func (s Schedule) isDayOff(date time.Time) bool {
+	for _, timeOff := range s.TimeOff {
+		if timeOff.Includes(date) {
+			return true
+		}
+	}
+	return false
+}
+
+// interval returns the start and the end of the shift starting on the given day, in the day's location.
+func (s DoctorShift) interval(day time.Time) (time.Time, time.Time, bool) {
+	start, err := ParseTime(s.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	end, err := ParseTime(s.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	startTime := start.on(day)
+	endTime := end.on(day)
+	if !start.Before(end) {
+		endTime = end.on(day.AddDate(0, 0, 1))
+	}
+	return startTime, endTime, true
+}
+
+// on returns the time of day on the date of the given day, in the day's location. A time of day skipped
+// by a daylight saving time transition is moved forward by the length of the transition, e.g. 02:30 becomes 03:30.
+func (t Time) on(day time.Time) time.Time {
+	result := time.Date(day.Year(), day.Month(), day.Day(), int(t.Hour), int(t.Minute), 0, 0, day.Location())
+	wall := time.Date(day.Year(), day.Month(), day.Day(), int(t.Hour), int(t.Minute), 0, 0, time.UTC)
+	resultWall := time.Date(result.Year(), result.Month(), result.Day(), result.Hour(), result.Minute(), 0, 0, time.UTC)
+	if skipped := wall.Sub(resultWall); skipped > 0 {
+		result = result.Add(skipped)
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package entity
+
+import "testing"
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Time
+		wantErr bool
+	}{
+		{"08:30", Time{Hour: 8, Minute: 30}, false},
+		{"0:05", Time{Hour: 0, Minute: 5}, false},
+		{"23:59", Time{Hour: 23, Minute: 59}, false},
+		{"", Time{}, true},
+		{"08", Time{}, true},
+		{"08:30:00", Time{}, true},
+		{"24:00", Time{}, true},
+		{"08:60", Time{}, true},
+		{"-1:30", Time{}, true},
+		{"08:", Time{}, true},
+		{"ab:cd", Time{}, true},
+	}
+	for _, tc := range tests {
+		got, err := ParseTime(tc.s)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ParseTime(%q) = %v, %v, want %v, error %v", tc.s, got, err, tc.want, tc.wantErr)
+		}
+	}
+}
+
+func TestTime_ToString(t *testing.T) {
+	for _, s := range []string{"00:00", "08:05", "23:59"} {
+		parsed, err := ParseTime(s)
+		if err != nil || parsed.ToString() != s {
+			t.Errorf("ParseTime(%q).ToString() = %q, %v, want %q", s, parsed.ToString(), err, s)
+		}
+	}
+}
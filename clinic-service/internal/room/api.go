@@ -0,0 +1,85 @@
+package room
+
+import (
+	"net/http"
+
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routing.Handler, logger log.Logger) {
+	res := resource{service, logger}
+
+	// rooms are public so that the scheduling-service can assign them without acting on behalf of a user
+	r.Get("/clinics/<id>/rooms", res.getByClinicId)
+	r.Get("/clinics/<id>/rooms/<roomId>", res.getById)
+
+	r.Use(authHandler)
+
+	r.Post("/clinics/<id>/rooms", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.create)
+	r.Put("/clinics/<id>/rooms/<roomId>", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.update)
+	r.Delete("/clinics/<id>/rooms/<roomId>", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.delete)
+}
+
+type resource struct {
+	service Service
+	logger  log.Logger
+}
+
+func (r resource) getByClinicId(c *routing.Context) error {
+	rooms, err := r.service.GetByClinicId(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Write(rooms)
+}
+
+func (r resource) getById(c *routing.Context) error {
+	room, err := r.service.GetById(c.Request.Context(), c.Param("id"), c.Param("roomId"))
+	if err != nil {
+		return err
+	}
+
+	return c.Write(room)
+}
+
+func (r resource) create(c *routing.Context) error {
+	var request RoomRequest
+	if err := c.Read(&request); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest("")
+	}
+	room, err := r.service.Create(c.Request.Context(), c.Param("id"), request)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteWithStatus(room, http.StatusCreated)
+}
+
+func (r resource) update(c *routing.Context) error {
+	var request RoomRequest
+	if err := c.Read(&request); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest("")
+	}
+	room, err := r.service.Update(c.Request.Context(), c.Param("id"), c.Param("roomId"), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(room)
+}
+
+func (r resource) delete(c *routing.Context) error {
+	room, err := r.service.Delete(c.Request.Context(), c.Param("id"), c.Param("roomId"))
+	if err != nil {
+		return err
+	}
+
+	return c.Write(room)
+}
@@ -0,0 +1,52 @@
+package room
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/test"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+// mockService answers every call with empty results, so that the tests can check which requests reach it.
+type mockService struct{}
+
+func (s mockService) GetById(ctx context.Context, clinicId string, id string) (entity.Room, error) {
+	return entity.Room{}, nil
+}
+
+func (s mockService) GetByClinicId(ctx context.Context, clinicId string) ([]entity.Room, error) {
+	return []entity.Room{}, nil
+}
+
+func (s mockService) Create(ctx context.Context, clinicId string, req RoomRequest) (entity.Room, error) {
+	return entity.Room{}, nil
+}
+
+func (s mockService) Update(ctx context.Context, clinicId string, id string, req RoomRequest) (entity.Room, error) {
+	return entity.Room{}, nil
+}
+
+func (s mockService) Delete(ctx context.Context, clinicId string, id string) (entity.Room, error) {
+	return entity.Room{}, nil
+}
+
+func TestAPI_roles(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), mockService{}, test.MockAuthHandler(), logger)
+
+	const (
+		ok        = http.StatusOK
+		created   = http.StatusCreated
+		forbidden = http.StatusForbidden
+		anonymous = http.StatusUnauthorized
+	)
+	test.Routes(t, router, []test.RouteTestCase{
+		{Method: "POST", URL: "/clinics/" + test.ClinicId + "/rooms", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: created, entity.RoleClinicAdmin: created, entity.RolePatient: forbidden}},
+		{Method: "PUT", URL: "/clinics/" + test.ClinicId + "/rooms/1", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+		{Method: "DELETE", URL: "/clinics/" + test.ClinicId + "/rooms/1", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+	})
+}
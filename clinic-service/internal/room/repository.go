@@ -0,0 +1,123 @@
+package room
+
+import (
+	"context"
+
+	dbx "github.com/go-ozzo/ozzo-dbx"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/dbcontext"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+type Repository interface {
+	GetById(ctx context.Context, id string) (entity.Room, error)
+	GetByClinicId(ctx context.Context, clinicId string) ([]entity.Room, error)
+	Create(ctx context.Context, room entity.Room) error
+	Update(ctx context.Context, room entity.Room) error
+	Delete(ctx context.Context, id string) error
+}
+
+type repository struct {
+	db     *dbcontext.DB
+	logger log.Logger
+}
+
+func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
+	return repository{db, logger}
+}
+
+func (r repository) GetById(ctx context.Context, id string) (entity.Room, error) {
+	var room entity.Room
+	if err := r.db.With(ctx).Select().Model(id, &room); err != nil {
+		return entity.Room{}, err
+	}
+	rooms := []entity.Room{room}
+	if err := r.loadAppointmentTypes(ctx, rooms); err != nil {
+		return entity.Room{}, err
+	}
+	return rooms[0], nil
+}
+
+func (r repository) GetByClinicId(ctx context.Context, clinicId string) ([]entity.Room, error) {
+	var rooms []entity.Room
+	err := r.db.With(ctx).Select().Where(dbx.HashExp{"clinic_id": clinicId}).OrderBy("name").All(&rooms)
+	if err != nil {
+		return nil, err
+	}
+	if err = r.loadAppointmentTypes(ctx, rooms); err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
+func (r repository) Create(ctx context.Context, room entity.Room) error {
+	if err := r.db.With(ctx).Model(&room).Insert(); err != nil {
+		return err
+	}
+	return r.insertAppointmentTypes(ctx, room)
+}
+
+func (r repository) Update(ctx context.Context, room entity.Room) error {
+	if err := r.db.With(ctx).Model(&room).Update(); err != nil {
+		return err
+	}
+	if err := r.deleteAppointmentTypes(ctx, room.Id); err != nil {
+		return err
+	}
+	return r.insertAppointmentTypes(ctx, room)
+}
+
+func (r repository) Delete(ctx context.Context, id string) error {
+	room, err := r.GetById(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err = r.deleteAppointmentTypes(ctx, id); err != nil {
+		return err
+	}
+	return r.db.With(ctx).Model(&room).Delete()
+}
+
+// loadAppointmentTypes fills in the IDs of the appointment types supported by the given rooms.
+func (r repository) loadAppointmentTypes(ctx context.Context, rooms []entity.Room) error {
+	if len(rooms) == 0 {
+		return nil
+	}
+	b := make([]interface{}, len(rooms))
+	for i := range rooms {
+		b[i] = rooms[i].Id
+	}
+	var roomAppointmentTypes []entity.RoomAppointmentType
+	err := r.db.With(ctx).Select().Where(dbx.In("room_id", b...)).All(&roomAppointmentTypes)
+	if err != nil {
+		return err
+	}
+
+	appointmentTypeIds := make(map[string][]string)
+	for _, roomAppointmentType := range roomAppointmentTypes {
+		appointmentTypeIds[roomAppointmentType.RoomId] = append(appointmentTypeIds[roomAppointmentType.RoomId], roomAppointmentType.AppointmentTypeId)
+	}
+	for i, room := range rooms {
+		room.AppointmentTypeIds = appointmentTypeIds[room.Id]
+		if room.AppointmentTypeIds == nil {
+			room.AppointmentTypeIds = []string{}
+		}
+		rooms[i] = room
+	}
+	return nil
+}
+
+func (r repository) insertAppointmentTypes(ctx context.Context, room entity.Room) error {
+	for _, appointmentTypeId := range room.AppointmentTypeIds {
+		roomAppointmentType := entity.RoomAppointmentType{RoomId: room.Id, AppointmentTypeId: appointmentTypeId}
+		if err := r.db.With(ctx).Model(&roomAppointmentType).Insert(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r repository) deleteAppointmentTypes(ctx context.Context, roomId string) error {
+	_, err := r.db.With(ctx).Delete("room_appointment_type", dbx.HashExp{"room_id": roomId}).Execute()
+	return err
+}
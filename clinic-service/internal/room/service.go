@@ -0,0 +1,170 @@
+package room
+
+import (
+	"context"
+	"database/sql"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	appointment_type "github.com/matijapetrovic/clinichub/clinic-service/internal/appointment-type"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/clinic"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/clinic-service/internal/errors"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/dbcontext"
+	"github.com/matijapetrovic/clinichub/clinic-service/pkg/log"
+)
+
+type Service interface {
+	GetById(ctx context.Context, clinicId string, id string) (entity.Room, error)
+	GetByClinicId(ctx context.Context, clinicId string) ([]entity.Room, error)
+	Create(ctx context.Context, clinicId string, req RoomRequest) (entity.Room, error)
+	Update(ctx context.Context, clinicId string, id string, req RoomRequest) (entity.Room, error)
+	Delete(ctx context.Context, clinicId string, id string) (entity.Room, error)
+}
+
+// RoomRequest represents a request to create or update a room.
+type RoomRequest struct {
+	Name               string   `json:"name"`
+	AppointmentTypeIds []string `json:"appointmentTypeIds"`
+}
+
+func (m RoomRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Name, validation.Required, validation.Length(1, 50)),
+		validation.Field(&m.AppointmentTypeIds, validation.Each(validation.Required, validation.Length(36, 36))),
+	)
+}
+
+type service struct {
+	repo                Repository
+	clinicRepo          clinic.Repository
+	appointmentTypeRepo appointment_type.Repository
+	transactional       dbcontext.TransactionFunc
+	logger              log.Logger
+}
+
+func NewService(repo Repository, clinicRepo clinic.Repository, appointmentTypeRepo appointment_type.Repository, transactional dbcontext.TransactionFunc, logger log.Logger) Service {
+	return service{repo, clinicRepo, appointmentTypeRepo, transactional, logger}
+}
+
+// GetById returns the room of the clinic with the given ID.
+func (s service) GetById(ctx context.Context, clinicId string, id string) (entity.Room, error) {
+	room, err := s.repo.GetById(ctx, id)
+	if err != nil {
+		return entity.Room{}, err
+	}
+	if room.ClinicId != clinicId {
+		return entity.Room{}, errors.NotFound("")
+	}
+	return room, nil
+}
+
+func (s service) GetByClinicId(ctx context.Context, clinicId string) ([]entity.Room, error) {
+	if _, err := s.clinicRepo.GetById(ctx, clinicId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByClinicId(ctx, clinicId)
+}
+
+func (s service) Create(ctx context.Context, clinicId string, req RoomRequest) (entity.Room, error) {
+	if err := req.Validate(); err != nil {
+		return entity.Room{}, err
+	}
+	if !auth.CanManageClinic(ctx, clinicId) {
+		return entity.Room{}, errors.Forbidden("")
+	}
+	if _, err := s.clinicRepo.GetById(ctx, clinicId); err != nil {
+		return entity.Room{}, err
+	}
+	if err := s.checkAppointmentTypes(ctx, req.AppointmentTypeIds); err != nil {
+		return entity.Room{}, err
+	}
+
+	id := entity.GenerateID()
+	err := s.transactional(ctx, func(ctx context.Context) error {
+		return s.repo.Create(ctx, entity.Room{
+			Id:                 id,
+			ClinicId:           clinicId,
+			Name:               req.Name,
+			AppointmentTypeIds: uniqueIds(req.AppointmentTypeIds),
+		})
+	})
+	if err != nil {
+		return entity.Room{}, err
+	}
+
+	return s.repo.GetById(ctx, id)
+}
+
+func (s service) Update(ctx context.Context, clinicId string, id string, req RoomRequest) (entity.Room, error) {
+	if err := req.Validate(); err != nil {
+		return entity.Room{}, err
+	}
+	if !auth.CanManageClinic(ctx, clinicId) {
+		return entity.Room{}, errors.Forbidden("")
+	}
+	room, err := s.GetById(ctx, clinicId, id)
+	if err != nil {
+		return entity.Room{}, err
+	}
+	if err = s.checkAppointmentTypes(ctx, req.AppointmentTypeIds); err != nil {
+		return entity.Room{}, err
+	}
+
+	room.Name = req.Name
+	room.AppointmentTypeIds = uniqueIds(req.AppointmentTypeIds)
+	err = s.transactional(ctx, func(ctx context.Context) error {
+		return s.repo.Update(ctx, room)
+	})
+	if err != nil {
+		return entity.Room{}, err
+	}
+
+	return s.repo.GetById(ctx, id)
+}
+
+func (s service) Delete(ctx context.Context, clinicId string, id string) (entity.Room, error) {
+	if !auth.CanManageClinic(ctx, clinicId) {
+		return entity.Room{}, errors.Forbidden("")
+	}
+	room, err := s.GetById(ctx, clinicId, id)
+	if err != nil {
+		return entity.Room{}, err
+	}
+
+	err = s.transactional(ctx, func(ctx context.Context) error {
+		return s.repo.Delete(ctx, id)
+	})
+	if err != nil {
+		return entity.Room{}, err
+	}
+	return room, nil
+}
+
+// checkAppointmentTypes rejects appointment type IDs that do not exist.
+func (s service) checkAppointmentTypes(ctx context.Context, appointmentTypeIds []string) error {
+	for _, id := range appointmentTypeIds {
+		_, err := s.appointmentTypeRepo.GetById(ctx, id)
+		if err == sql.ErrNoRows {
+			return validation.Errors{
+				"appointmentTypeIds": validation.NewError("validation_appointment_type", "appointment type "+id+" does not exist"),
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueIds returns the given IDs without duplicates, keeping their order.
+func uniqueIds(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,2 @@
+DROP TABLE room_appointment_type;
+DROP TABLE room;
@@ -0,0 +1,17 @@
+CREATE TABLE room (
+  id VARCHAR(255) NOT NULL,
+  clinic_id VARCHAR(255) NOT NULL,
+  name VARCHAR(255) NOT NULL,
+
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`clinic_id`) REFERENCES clinic(`id`)
+);
+
+CREATE TABLE room_appointment_type (
+  room_id VARCHAR(255) NOT NULL,
+  appointment_type_id VARCHAR(255) NOT NULL,
+
+  PRIMARY KEY (`room_id`, `appointment_type_id`),
+  FOREIGN KEY (`room_id`) REFERENCES room(`id`),
+  FOREIGN KEY (`appointment_type_id`) REFERENCES appointment_type(`id`)
+);
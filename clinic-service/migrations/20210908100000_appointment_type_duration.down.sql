@@ -0,0 +1,2 @@
+ALTER TABLE appointment_type DROP COLUMN buffer;
+ALTER TABLE appointment_type DROP COLUMN duration;
@@ -0,0 +1,2 @@
+ALTER TABLE appointment_type ADD COLUMN duration INT NOT NULL DEFAULT 60;
+ALTER TABLE appointment_type ADD COLUMN buffer INT NOT NULL DEFAULT 0;
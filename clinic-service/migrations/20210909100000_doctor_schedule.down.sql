@@ -0,0 +1,2 @@
+DROP TABLE doctor_time_off;
+DROP TABLE doctor_shift;
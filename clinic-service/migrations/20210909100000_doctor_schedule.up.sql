@@ -0,0 +1,22 @@
+CREATE TABLE doctor_shift (
+  id VARCHAR(255) NOT NULL,
+  doctor_id VARCHAR(255) NOT NULL,
+  weekday INT NOT NULL,
+  start_time VARCHAR(255) NOT NULL,
+  end_time VARCHAR(255) NOT NULL,
+
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`doctor_id`) REFERENCES doctor(`id`)
+);
+
+CREATE TABLE doctor_time_off (
+  id VARCHAR(255) NOT NULL,
+  doctor_id VARCHAR(255) NOT NULL,
+  start_date VARCHAR(10) NOT NULL,
+  end_date VARCHAR(10) NOT NULL,
+  reason VARCHAR(255) NOT NULL,
+
+  PRIMARY KEY (`id`),
+  FOREIGN KEY (`doctor_id`) REFERENCES doctor(`id`)
+);
+CREATE INDEX doctor_time_off_doctor_id_dates ON doctor_time_off (doctor_id, start_date, end_date);
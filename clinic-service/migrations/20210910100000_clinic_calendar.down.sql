@@ -0,0 +1,2 @@
+DROP TABLE clinic_holiday;
+DROP TABLE clinic_opening_hours;
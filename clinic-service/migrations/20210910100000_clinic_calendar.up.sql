@@ -0,0 +1,20 @@
+CREATE TABLE clinic_opening_hours (
+  clinic_id VARCHAR(255) NOT NULL,
+  weekday INT NOT NULL,
+  open_time VARCHAR(255) NOT NULL,
+  close_time VARCHAR(255) NOT NULL,
+
+  PRIMARY KEY (`clinic_id`, `weekday`),
+  FOREIGN KEY (`clinic_id`) REFERENCES clinic(`id`)
+);
+
+CREATE TABLE clinic_holiday (
+  id VARCHAR(255) NOT NULL,
+  clinic_id VARCHAR(255) NOT NULL,
+  date VARCHAR(10) NOT NULL,
+  name VARCHAR(255) NOT NULL,
+
+  PRIMARY KEY (`id`),
+  UNIQUE KEY clinic_holiday_clinic_id_date (`clinic_id`, `date`),
+  FOREIGN KEY (`clinic_id`) REFERENCES clinic(`id`)
+);
@@ -0,0 +1 @@
+ALTER TABLE clinic DROP COLUMN time_zone;
@@ -0,0 +1 @@
+ALTER TABLE doctor DROP COLUMN shifts_configured;
@@ -0,0 +1,2 @@
+ALTER TABLE doctor ADD COLUMN shifts_configured BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE doctor SET shifts_configured = TRUE WHERE id IN (SELECT doctor_id FROM doctor_shift);
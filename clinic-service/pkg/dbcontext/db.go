@@ -4,9 +4,11 @@ package dbcontext
 
 import (
 	"context"
+	"errors"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-sql-driver/mysql"
 )
 
 // DB represents a DB connection that can be used to run SQL queries.
@@ -23,6 +25,9 @@ const (
 	txKey contextKey = iota
 )
 
+// mysqlDuplicateEntry is the MySQL error number reported when a unique index is violated.
+const mysqlDuplicateEntry = 1062
+
 // New returns a new DB connection that wraps the given dbx.DB instance.
 func New(db *dbx.DB) *DB {
 	return &DB{db}
@@ -62,3 +67,9 @@ func (db *DB) TransactionHandler() routing.Handler {
 		})
 	}
 }
+
+// IsUniqueViolation returns whether the error was caused by a statement violating a unique index.
+func IsUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
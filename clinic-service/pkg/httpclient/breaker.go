@@ -0,0 +1,84 @@
+package httpclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the service while its circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type breakerState int
+
+const (
+	closed breakerState = iota
+	open
+	halfOpen
+)
+
+// circuitBreaker stops requests to a service after a number of consecutive failures.
+// Once the cooldown has passed, a single trial request is let through: the circuit closes again
+// if it succeeds and stays open for another cooldown otherwise.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow returns whether a request may be sent. Every allowed request must be followed by a call
+// to success, failure or cancel.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case open:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = halfOpen
+		return true
+	case halfOpen:
+		// a trial request is already in flight
+		return false
+	}
+	return true
+}
+
+// success records a request that reached the service and closes the circuit.
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state = closed
+	b.failures = 0
+}
+
+// failure records a failed request and opens the circuit when the threshold is reached or the trial request failed.
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == halfOpen || b.failures >= b.threshold {
+		b.state = open
+		b.openedAt = b.now()
+	}
+}
+
+// cancel records a request abandoned by the caller, which says nothing about the health of the service.
+// An abandoned trial request lets the next request through as a new trial.
+func (b *circuitBreaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == halfOpen {
+		b.state = open
+	}
+}
@@ -0,0 +1,262 @@
+// Package ical reads events from iCalendar (RFC 5545) files such as public holiday calendars.
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrTooManyDates is returned by Event.Dates for events taking place on more days than allowed.
+var ErrTooManyDates = errors.New("the event takes place on too many days")
+
+// Event is an event of a calendar. Recurring events are not supported, so Parse rejects them.
+type Event struct {
+	Summary string
+	Start   time.Time
+	// End is the exclusive end of the event.
+	End time.Time
+	// AllDay is set for events given with dates instead of date-times. Their start and end are at midnight UTC.
+	AllDay bool
+}
+
+// Dates returns the dates, in the given layout, of the days the event takes place on.
+// ErrTooManyDates is returned without listing them if the event takes place on more than limit days.
+func (e Event) Dates(layout string, limit int) ([]string, error) {
+	last := e.End
+	if last.After(e.Start) {
+		last = last.Add(-time.Nanosecond)
+	}
+	var dates []string
+	day := time.Date(e.Start.Year(), e.Start.Month(), e.Start.Day(), 0, 0, 0, 0, e.Start.Location())
+	for ; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if len(dates) == limit {
+			return nil, ErrTooManyDates
+		}
+		dates = append(dates, day.Format(layout))
+	}
+	return dates, nil
+}
+
+const (
+	dateLayout        = "20060102"
+	dateTimeLayout    = "20060102T150405"
+	utcDateTimeLayout = "20060102T150405Z"
+)
+
+// property is a content line of a calendar, e.g. "DTSTART;VALUE=DATE:20210101".
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the events of the calendar. Calendars with recurring events are rejected.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var components []string
+	var event *Event
+	var duration time.Duration
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		p, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		switch p.name {
+		case "BEGIN":
+			components = append(components, strings.ToUpper(p.value))
+			if components[len(components)-1] == "VEVENT" {
+				event, duration = &Event{}, 0
+			}
+			continue
+		case "END":
+			if len(components) == 0 || components[len(components)-1] != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", i+1, p.value)
+			}
+			components = components[:len(components)-1]
+			if strings.ToUpper(p.value) == "VEVENT" {
+				if event.Start.IsZero() {
+					return nil, fmt.Errorf("line %d: event without DTSTART", i+1)
+				}
+				events = append(events, finish(*event, duration))
+				event = nil
+			}
+			continue
+		}
+
+		if event == nil || components[len(components)-1] != "VEVENT" {
+			continue
+		}
+		switch p.name {
+		case "SUMMARY":
+			event.Summary = unescape(p.value)
+		case "DTSTART":
+			if event.Start, event.AllDay, err = parseTime(p); err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+		case "DTEND":
+			if event.End, _, err = parseTime(p); err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+		case "DURATION":
+			if duration, err = parseDuration(p.value); err != nil {
+				return nil, fmt.Errorf("line %d: %v", i+1, err)
+			}
+		case "RRULE", "RDATE":
+			return nil, fmt.Errorf("line %d: recurring events are not supported", i+1)
+		}
+	}
+	if len(components) > 0 {
+		return nil, fmt.Errorf("%s is not closed", components[len(components)-1])
+	}
+	return events, nil
+}
+
+// finish sets the end of an event without DTEND. All-day events last a day by default, other events have no length.
+func finish(event Event, duration time.Duration) Event {
+	if !event.End.IsZero() {
+		return event
+	}
+	switch {
+	case duration > 0:
+		event.End = event.Start.Add(duration)
+	case event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1)
+	default:
+		event.End = event.Start
+	}
+	return event
+}
+
+// unfold reads the content lines of the calendar, joining lines continued on the next line.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseProperty parses a content line in the "NAME;PARAM=VALUE:value" format.
+func parseProperty(line string) (property, error) {
+	inQuotes := false
+	colon := -1
+	for i, c := range line {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return property{}, fmt.Errorf("invalid content line %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	p := property{
+		name:   strings.ToUpper(parts[0]),
+		params: make(map[string]string),
+		value:  line[colon+1:],
+	}
+	for _, param := range parts[1:] {
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return p, nil
+}
+
+// parseTime parses a date or date-time property and returns whether it is a date.
+// Date-times without a time zone are taken as UTC, while unknown time zones are an error.
+func parseTime(p property) (time.Time, bool, error) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == len(dateLayout) {
+		t, err := time.Parse(dateLayout, p.value)
+		return t, true, err
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		t, err := time.Parse(utcDateTimeLayout, p.value)
+		return t, false, err
+	}
+	location := time.UTC
+	if tzid, ok := p.params["TZID"]; ok {
+		l, err := time.LoadLocation(tzid)
+		if err != nil || tzid == "" || tzid == "Local" {
+			return time.Time{}, false, fmt.Errorf("unknown time zone %q", tzid)
+		}
+		location = l
+	}
+	t, err := time.ParseInLocation(dateTimeLayout, p.value, location)
+	return t, false, err
+}
+
+// parseDuration parses a duration such as "P1D", "P2W" or "PT1H30M".
+func parseDuration(value string) (time.Duration, error) {
+	s := strings.TrimPrefix(value, "+")
+	if !strings.HasPrefix(s, "P") {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	s = s[1:]
+
+	var d time.Duration
+	inTime := false
+	number := ""
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+		number = ""
+		switch {
+		case c == 'W' && !inTime:
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			d += time.Duration(n) * 24 * time.Hour
+		case c == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("invalid duration %q", value)
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+// unescape replaces the escape sequences of a text value.
+func unescape(value string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, `;`, `\,`, `,`, `\n`, "\n", `\N`, "\n").Replace(value)
+}
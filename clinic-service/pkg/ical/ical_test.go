@@ -0,0 +1,159 @@
+package ical
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// calendar returns a calendar with a single event having the given properties.
+func calendar(properties ...string) string {
+	lines := append([]string{"BEGIN:VCALENDAR", "VERSION:2.0", "BEGIN:VEVENT"}, properties...)
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func TestParse(t *testing.T) {
+	belgrade, err := time.LoadLocation("Europe/Belgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		calendar string
+		want     Event
+	}{
+		{
+			"all-day event",
+			calendar("SUMMARY:New Year", "DTSTART;VALUE=DATE:20210101", "DTEND;VALUE=DATE:20210103"),
+			Event{Summary: "New Year", Start: date(2021, 1, 1), End: date(2021, 1, 3), AllDay: true},
+		},
+		{
+			"all-day event without DTEND",
+			calendar("SUMMARY:Christmas", "DTSTART;VALUE=DATE:20210107"),
+			Event{Summary: "Christmas", Start: date(2021, 1, 7), End: date(2021, 1, 8), AllDay: true},
+		},
+		{
+			"timed event",
+			calendar("SUMMARY:Inventory", "DTSTART:20210310T080000Z", "DTEND:20210310T120000Z"),
+			Event{Summary: "Inventory", Start: dateTime(2021, 3, 10, 8, time.UTC), End: dateTime(2021, 3, 10, 12, time.UTC)},
+		},
+		{
+			"timed event with DURATION",
+			calendar("SUMMARY:Inventory", "DTSTART:20210310T080000Z", "DURATION:PT1H30M"),
+			Event{Summary: "Inventory", Start: dateTime(2021, 3, 10, 8, time.UTC), End: dateTime(2021, 3, 10, 8, time.UTC).Add(90 * time.Minute)},
+		},
+		{
+			"timed event without DTEND",
+			calendar("SUMMARY:Inventory", "DTSTART:20210310T080000Z"),
+			Event{Summary: "Inventory", Start: dateTime(2021, 3, 10, 8, time.UTC), End: dateTime(2021, 3, 10, 8, time.UTC)},
+		},
+		{
+			"timed event with TZID",
+			calendar("SUMMARY:Inventory", "DTSTART;TZID=Europe/Belgrade:20210310T080000", "DTEND;TZID=\"Europe/Belgrade\":20210310T120000"),
+			Event{Summary: "Inventory", Start: dateTime(2021, 3, 10, 8, belgrade), End: dateTime(2021, 3, 10, 12, belgrade)},
+		},
+		{
+			"floating timed event",
+			calendar("SUMMARY:Inventory", "DTSTART:20210310T080000"),
+			Event{Summary: "Inventory", Start: dateTime(2021, 3, 10, 8, time.UTC), End: dateTime(2021, 3, 10, 8, time.UTC)},
+		},
+		{
+			"folded and escaped summary",
+			calendar("SUMMARY:Day of the Republic\\, first", "  day", "DTSTART;VALUE=DATE:20211111"),
+			Event{Summary: "Day of the Republic, first day", Start: date(2021, 11, 11), End: date(2021, 11, 12), AllDay: true},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			events, err := Parse(strings.NewReader(tc.calendar))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(events) != 1 {
+				t.Fatalf("Parse() = %+v, want a single event", events)
+			}
+			got := events[0]
+			if got.Summary != tc.want.Summary || got.AllDay != tc.want.AllDay ||
+				!got.Start.Equal(tc.want.Start) || !got.End.Equal(tc.want.End) ||
+				got.Start.Location().String() != tc.want.Start.Location().String() {
+				t.Errorf("Parse() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParse_invalidCalendar(t *testing.T) {
+	tests := []struct {
+		name     string
+		calendar string
+		want     string
+	}{
+		{"recurrence rule", calendar("DTSTART;VALUE=DATE:20210101", "RRULE:FREQ=YEARLY"), "recurring events are not supported"},
+		{"recurrence dates", calendar("DTSTART;VALUE=DATE:20210101", "RDATE;VALUE=DATE:20220101"), "recurring events are not supported"},
+		{"unknown time zone", calendar("DTSTART;TZID=Mars/Olympus:20210310T080000"), `unknown time zone "Mars/Olympus"`},
+		{"missing DTSTART", calendar("SUMMARY:New Year"), "event without DTSTART"},
+		{"invalid date", calendar("DTSTART;VALUE=DATE:2021-01-01"), "cannot parse"},
+		{"invalid duration", calendar("DTSTART:20210310T080000Z", "DURATION:1H"), "invalid duration"},
+		{"unclosed event", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20210101\r\n", "VEVENT is not closed"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tc.calendar))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Parse() error = %v, want an error containing %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestEvent_Dates(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		want  []string
+	}{
+		{"single day", Event{Start: date(2021, 1, 7), End: date(2021, 1, 8), AllDay: true}, []string{"2021-01-07"}},
+		{"several days", Event{Start: date(2021, 1, 1), End: date(2021, 1, 3), AllDay: true}, []string{"2021-01-01", "2021-01-02"}},
+		{"over midnight", Event{Start: dateTime(2021, 3, 10, 22, time.UTC), End: dateTime(2021, 3, 11, 2, time.UTC)}, []string{"2021-03-10", "2021-03-11"}},
+		{"ending at midnight", Event{Start: dateTime(2021, 3, 10, 8, time.UTC), End: date(2021, 3, 11)}, []string{"2021-03-10"}},
+		{"without length", Event{Start: dateTime(2021, 3, 10, 8, time.UTC), End: dateTime(2021, 3, 10, 8, time.UTC)}, []string{"2021-03-10"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.event.Dates("2006-01-02", 10)
+			if err != nil {
+				t.Fatalf("Dates() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Dates() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEvent_Dates_tooManyDays(t *testing.T) {
+	event := Event{Start: date(2021, 1, 1), End: date(2021, 1, 4), AllDay: true}
+	if got, err := event.Dates("2006-01-02", 3); err != nil || len(got) != 3 {
+		t.Errorf("Dates() = %v, %v, want 3 dates within the limit", got, err)
+	}
+	if got, err := event.Dates("2006-01-02", 2); err != ErrTooManyDates || got != nil {
+		t.Errorf("Dates() = %v, %v, want ErrTooManyDates", got, err)
+	}
+
+	// the span is rejected once the limit is reached, without going through all of its days
+	event = Event{Start: date(1, 1, 1), End: date(9999, 1, 1), AllDay: true}
+	if _, err := event.Dates("2006-01-02", 1000); err != ErrTooManyDates {
+		t.Errorf("Dates() error = %v, want ErrTooManyDates", err)
+	}
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func dateTime(year int, month time.Month, day, hour int, location *time.Location) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, location)
+}
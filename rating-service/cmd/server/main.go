@@ -5,12 +5,15 @@ import (
 	"database/sql"
 	"flag"
 	"fmt"
+	"github.com/dgrijalva/jwt-go"
 	"github.com/go-ozzo/ozzo-dbx"
 	"github.com/go-ozzo/ozzo-routing/v2"
 	"github.com/go-ozzo/ozzo-routing/v2/content"
 	"github.com/go-ozzo/ozzo-routing/v2/cors"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/matijapetrovic/clinichub/rating-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/client/clinic"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/client/scheduling"
 	doctor_rating "github.com/matijapetrovic/clinichub/rating-service/internal/clinic-rating"
 	"github.com/matijapetrovic/clinichub/rating-service/internal/config"
 	clinic_rating "github.com/matijapetrovic/clinichub/rating-service/internal/doctor-rating"
@@ -18,6 +21,7 @@ import (
 	"github.com/matijapetrovic/clinichub/rating-service/internal/healthcheck"
 	"github.com/matijapetrovic/clinichub/rating-service/pkg/accesslog"
 	"github.com/matijapetrovic/clinichub/rating-service/pkg/dbcontext"
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/httpclient"
 	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
 	"net/http"
 	"os"
@@ -41,6 +45,21 @@ func main() {
 		os.Exit(-1)
 	}
 
+	// load the keys used to verify JWTs
+	var keySet *auth.KeySet
+	if cfg.JWKSLocation != "" {
+		if keySet, err = auth.NewKeySet(cfg.JWKSLocation, logger); err != nil {
+			logger.Errorf("failed to load JWKS: %s", err)
+			os.Exit(-1)
+		}
+		go keySet.RefreshEvery(time.Duration(cfg.JWKSRefreshInterval) * time.Minute)
+	}
+	keyFunc, err := auth.NewKeyFunc(keySet, cfg.JWTSigningKey, cfg.JWTVerificationKeyFile)
+	if err != nil {
+		logger.Errorf("failed to load JWT verification keys: %s", err)
+		os.Exit(-1)
+	}
+
 	// connect to the database
 	db, err := dbx.MustOpen("mysql", cfg.DSN)
 	if err != nil {
@@ -59,7 +78,7 @@ func main() {
 	address := fmt.Sprintf(":%v", cfg.ServerPort)
 	hs := &http.Server{
 		Addr:    address,
-		Handler: buildHandler(logger, dbcontext.New(db), cfg),
+		Handler: buildHandler(logger, dbcontext.New(db), cfg, keyFunc),
 	}
 
 	// start the HTTP server with graceful shutdown
@@ -72,7 +91,7 @@ func main() {
 }
 
 // buildHandler sets up the HTTP routing and builds an HTTP handler.
-func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.Handler {
+func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config, keyFunc jwt.Keyfunc) http.Handler {
 	router := routing.New()
 
 	router.Use(
@@ -86,15 +105,18 @@ func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.
 
 	rg := router.Group("/v1")
 
-	authHandler := auth.Handler(cfg.JWTSigningKey)
+	authHandler := auth.Handler(keyFunc, cfg.JWTIssuer)
+
+	schedulingClient := scheduling.NewClient(newPeerClient(cfg, cfg.SchedulingServiceURL, cfg.SchedulingServiceTimeout))
+	clinicClient := clinic.NewClient(newPeerClient(cfg, cfg.ClinicServiceURL, cfg.ClinicServiceTimeout))
 
 	doctor_rating.RegisterHandlers(rg.Group(""),
-		doctor_rating.NewService(doctor_rating.NewRepository(db, logger), logger),
+		doctor_rating.NewService(doctor_rating.NewRepository(db, cfg.RatingPriorWeight, logger), schedulingClient, clinicClient, cfg.DoctorRatingDimensions, db.Transactional, logger),
 		authHandler, logger,
 	)
 
 	clinic_rating.RegisterHandlers(rg.Group(""),
-		clinic_rating.NewService(clinic_rating.NewRepository(db, logger), logger),
+		clinic_rating.NewService(clinic_rating.NewRepository(db, cfg.RatingPriorWeight, logger), schedulingClient, clinicClient, cfg.ClinicRatingDimensions, db.Transactional, logger),
 		authHandler, logger,
 	)
 
@@ -122,3 +144,14 @@ func logDBExec(logger log.Logger) dbx.ExecLogFunc {
 		}
 	}
 }
+
+// newPeerClient creates a client for the API of another service with the given base URL and timeout in seconds.
+// Every peer gets its own circuit breaker.
+func newPeerClient(cfg *config.Config, baseURL string, timeout int) *httpclient.Client {
+	return httpclient.New(baseURL,
+		httpclient.WithRequestFunc(auth.ForwardToken),
+		httpclient.WithTimeout(time.Duration(timeout)*time.Second),
+		httpclient.WithRetries(cfg.PeerRetries, time.Duration(cfg.PeerRetryBackoff)*time.Millisecond),
+		httpclient.WithCircuitBreaker(cfg.CircuitBreakerThreshold, time.Duration(cfg.CircuitBreakerCooldown)*time.Second),
+	)
+}
@@ -0,0 +1,50 @@
+// Package clinic provides a client for the clinic-service API.
+package clinic
+
+import (
+	"context"
+
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/httpclient"
+)
+
+// Clinic represents a clinic.
+type Clinic struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Doctor represents a doctor working in a clinic.
+type Doctor struct {
+	Id        string `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+// Client sends requests to the clinic-service API.
+type Client interface {
+	// GetClinic returns the clinic with the given ID.
+	GetClinic(ctx context.Context, clinicId string) (Clinic, error)
+	// GetDoctor returns the doctor with the given ID.
+	GetDoctor(ctx context.Context, doctorId string) (Doctor, error)
+}
+
+type client struct {
+	http *httpclient.Client
+}
+
+// NewClient creates a new clinic-service client sending requests with the given HTTP client.
+func NewClient(http *httpclient.Client) Client {
+	return client{http}
+}
+
+func (c client) GetClinic(ctx context.Context, clinicId string) (Clinic, error) {
+	var clinic Clinic
+	err := c.http.Get(ctx, "/v1/clinics/"+clinicId, nil, &clinic)
+	return clinic, err
+}
+
+func (c client) GetDoctor(ctx context.Context, doctorId string) (Doctor, error) {
+	var doctor Doctor
+	err := c.http.Get(ctx, "/v1/doctors/"+doctorId, nil, &doctor)
+	return doctor, err
+}
@@ -0,0 +1,57 @@
+// Package scheduling provides a client for the scheduling-service API.
+package scheduling
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/httpclient"
+)
+
+// Appointment represents an appointment booked in the scheduling-service.
+type Appointment struct {
+	Id                string    `json:"id"`
+	ClinicId          string    `json:"clinicId"`
+	DoctorId          string    `json:"doctorId"`
+	PatientId         string    `json:"patientId"`
+	AppointmentTypeId string    `json:"appointmentTypeId"`
+	Price             uint      `json:"price"`
+	Time              time.Time `json:"time"`
+	Status            string    `json:"status"`
+}
+
+// StatusCompleted is the status of appointments the clinic marked as attended.
+const StatusCompleted = "completed"
+
+// HasTakenPlace returns whether the appointment has taken place, i.e. the clinic marked it as completed.
+// An approved appointment in the past may have been a no-show, so it does not count.
+func (a Appointment) HasTakenPlace() bool {
+	return a.Status == StatusCompleted
+}
+
+// Client sends requests to the scheduling-service API.
+type Client interface {
+	// GetPatientAppointments returns the appointments of the patient found in the context
+	// having the given status, or all of them if the status is empty.
+	GetPatientAppointments(ctx context.Context, status string) ([]Appointment, error)
+}
+
+type client struct {
+	http *httpclient.Client
+}
+
+// NewClient creates a new scheduling-service client sending requests with the given HTTP client.
+func NewClient(http *httpclient.Client) Client {
+	return client{http}
+}
+
+func (c client) GetPatientAppointments(ctx context.Context, status string) ([]Appointment, error) {
+	var appointments []Appointment
+	query := url.Values{}
+	if status != "" {
+		query.Set("status", status)
+	}
+	err := c.http.Get(ctx, "/v1/appointments", query, &appointments)
+	return appointments, err
+}
@@ -2,19 +2,31 @@ package doctor_rating
 
 import (
 	"github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/entity"
 	"github.com/matijapetrovic/clinichub/rating-service/internal/errors"
 	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/pagination"
 	"net/http"
+	"strings"
 )
 
 func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routing.Handler, logger log.Logger) {
 	res := resource{service, logger}
 	r.Get("/clinics/<id>/average-rating", res.getRating)
+	r.Get("/clinics/average-ratings", res.getRatings)
+	r.Get("/clinics/<id>/reviews", res.getReviews)
+	r.Get("/clinics/rating-dimensions", res.getDimensions)
 
 	r.Use(authHandler)
 
-	r.Get("/clinics/to-rate", res.getAvailableRatings)
-	r.Post("/clinics/<id>/ratings", res.rateDoctor)
+	r.Get("/clinics/reviews/pending", auth.RequireRole(entity.RoleAdmin), res.getPendingReviews)
+	r.Put("/clinics/reviews/<id>/status", auth.RequireRole(entity.RoleAdmin), res.moderateReview)
+
+	r.Get("/clinics/to-rate", auth.RequireRole(entity.RolePatient), res.getAvailableRatings)
+	r.Post("/clinics/<id>/ratings", auth.RequireRole(entity.RolePatient), res.rateDoctor)
+	r.Put("/clinics/<id>/ratings/mine", auth.RequireRole(entity.RolePatient), res.updateMyRating)
+	r.Delete("/clinics/<id>/ratings/mine", auth.RequireRole(entity.RolePatient), res.deleteMyRating)
 }
 
 type resource struct {
@@ -31,8 +43,34 @@ func (r resource) getRating(c *routing.Context) error {
 	return c.Write(rating)
 }
 
+func (r resource) getDimensions(c *routing.Context) error {
+	return c.Write(r.service.GetDimensions())
+}
+
+func (r resource) getRatings(c *routing.Context) error {
+	ratings, err := r.service.GetClinicRatings(c.Request.Context(), GetClinicRatingsRequest{
+		Ids: splitIds(c.Query("ids")),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Write(ratings)
+}
+
+// splitIds returns the non-empty IDs in the given comma-separated list.
+func splitIds(ids string) []string {
+	result := []string{}
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
 func (r resource) getAvailableRatings(c *routing.Context) error {
-	clinics, err := r.service.GetAvaialableRatings(c.Request)
+	clinics, err := r.service.GetAvaialableRatings(c.Request.Context())
 	if err != nil {
 		return err
 	}
@@ -53,3 +91,72 @@ func (r resource) rateDoctor(c *routing.Context) error {
 
 	return c.WriteWithStatus(rating, http.StatusCreated)
 }
+
+func (r resource) getReviews(c *routing.Context) error {
+	ctx := c.Request.Context()
+	count, err := r.service.CountReviews(ctx, c.Param("id"))
+	if err != nil {
+		return err
+	}
+	pages := pagination.NewFromRequest(c.Request, count)
+	reviews, err := r.service.GetReviews(ctx, c.Param("id"), pages.Offset(), pages.Limit())
+	if err != nil {
+		return err
+	}
+	pages.Items = reviews
+
+	return c.Write(pages)
+}
+
+func (r resource) getPendingReviews(c *routing.Context) error {
+	ctx := c.Request.Context()
+	count, err := r.service.CountPendingReviews(ctx)
+	if err != nil {
+		return err
+	}
+	pages := pagination.NewFromRequest(c.Request, count)
+	reviews, err := r.service.GetPendingReviews(ctx, pages.Offset(), pages.Limit())
+	if err != nil {
+		return err
+	}
+	pages.Items = reviews
+
+	return c.Write(pages)
+}
+
+func (r resource) moderateReview(c *routing.Context) error {
+	var request ModerateReviewRequest
+	if err := c.Read(&request); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest("")
+	}
+	rating, err := r.service.ModerateReview(c.Request.Context(), c.Param("id"), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(rating)
+}
+
+func (r resource) updateMyRating(c *routing.Context) error {
+	var request RateClinicRequest
+	if err := c.Read(&request); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest("")
+	}
+	rating, err := r.service.UpdateMyRating(c.Request.Context(), c.Param("id"), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(rating)
+}
+
+func (r resource) deleteMyRating(c *routing.Context) error {
+	rating, err := r.service.DeleteMyRating(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Write(rating)
+}
@@ -0,0 +1,84 @@
+package doctor_rating
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matijapetrovic/clinichub/rating-service/internal/client/clinic"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/test"
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
+)
+
+// mockService answers every call with empty results, so that the tests can check which requests reach it.
+type mockService struct{}
+
+func (s mockService) GetAvaialableRatings(ctx context.Context) ([]clinic.Clinic, error) {
+	return []clinic.Clinic{}, nil
+}
+
+func (s mockService) RateClinic(ctx context.Context, clinicId string, request RateClinicRequest) (entity.ClinicRating, error) {
+	return entity.ClinicRating{}, nil
+}
+
+func (s mockService) GetClinicRating(ctx context.Context, clinicId string) (entity.AverageRating, error) {
+	return entity.AverageRating{}, nil
+}
+
+func (s mockService) GetClinicRatings(ctx context.Context, req GetClinicRatingsRequest) (map[string]entity.AverageRating, error) {
+	return map[string]entity.AverageRating{}, nil
+}
+
+func (s mockService) CountReviews(ctx context.Context, clinicId string) (int, error) {
+	return 0, nil
+}
+
+func (s mockService) GetReviews(ctx context.Context, clinicId string, offset int, limit int) ([]entity.ClinicRating, error) {
+	return []entity.ClinicRating{}, nil
+}
+
+func (s mockService) CountPendingReviews(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (s mockService) GetPendingReviews(ctx context.Context, offset int, limit int) ([]entity.ClinicRating, error) {
+	return []entity.ClinicRating{}, nil
+}
+
+func (s mockService) ModerateReview(ctx context.Context, id string, req ModerateReviewRequest) (entity.ClinicRating, error) {
+	return entity.ClinicRating{}, nil
+}
+
+func (s mockService) UpdateMyRating(ctx context.Context, clinicId string, req RateClinicRequest) (entity.ClinicRating, error) {
+	return entity.ClinicRating{}, nil
+}
+
+func (s mockService) DeleteMyRating(ctx context.Context, clinicId string) (entity.ClinicRating, error) {
+	return entity.ClinicRating{}, nil
+}
+
+func (s mockService) GetDimensions() []string {
+	return []string{}
+}
+
+func TestAPI_roles(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), mockService{}, test.MockAuthHandler(), logger)
+
+	const (
+		ok        = http.StatusOK
+		created   = http.StatusCreated
+		forbidden = http.StatusForbidden
+		anonymous = http.StatusUnauthorized
+	)
+	test.Routes(t, router, []test.RouteTestCase{
+		{Method: "GET", URL: "/clinics/reviews/pending", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: forbidden, entity.RolePatient: forbidden}},
+		{Method: "PUT", URL: "/clinics/reviews/1/status", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: forbidden, entity.RolePatient: forbidden}},
+		{Method: "GET", URL: "/clinics/to-rate", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: forbidden, entity.RoleClinicAdmin: forbidden, entity.RolePatient: ok}},
+		{Method: "POST", URL: "/clinics/" + test.ClinicId + "/ratings", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: forbidden, entity.RoleClinicAdmin: forbidden, entity.RolePatient: created}},
+		{Method: "PUT", URL: "/clinics/" + test.ClinicId + "/ratings/mine", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: forbidden, entity.RoleClinicAdmin: forbidden, entity.RolePatient: ok}},
+		{Method: "DELETE", URL: "/clinics/" + test.ClinicId + "/ratings/mine", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: forbidden, entity.RoleClinicAdmin: forbidden, entity.RolePatient: ok}},
+	})
+}
@@ -0,0 +1,153 @@
+package doctor_rating
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/matijapetrovic/clinichub/rating-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/test"
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
+)
+
+// ratingsRepository holds the average ratings, dimension scores and histograms of the rated clinics.
+// Like the database, it gives clinics without ratings a zero rating and leaves them out of the breakdowns.
+type ratingsRepository struct {
+	Repository
+	ratings    map[string]entity.AverageRating
+	dimensions map[string]map[string]entity.DimensionRating
+	histograms map[string]map[int]int
+}
+
+func (r ratingsRepository) GetClinicRating(ctx context.Context, clinicId string) (entity.AverageRating, error) {
+	return r.ratings[clinicId], nil
+}
+
+func (r ratingsRepository) GetClinicRatings(ctx context.Context, clinicIds []string) (map[string]entity.AverageRating, error) {
+	ratings := make(map[string]entity.AverageRating, len(clinicIds))
+	for _, id := range clinicIds {
+		ratings[id] = r.ratings[id]
+	}
+	return ratings, nil
+}
+
+func (r ratingsRepository) GetDimensionRatings(ctx context.Context, clinicIds []string) (map[string]map[string]entity.DimensionRating, error) {
+	dimensions := make(map[string]map[string]entity.DimensionRating)
+	for _, id := range clinicIds {
+		if d, ok := r.dimensions[id]; ok {
+			dimensions[id] = d
+		}
+	}
+	return dimensions, nil
+}
+
+func (r ratingsRepository) GetHistograms(ctx context.Context, clinicIds []string) (map[string]map[int]int, error) {
+	histograms := make(map[string]map[int]int)
+	for _, id := range clinicIds {
+		if h, ok := r.histograms[id]; ok {
+			histograms[id] = h
+		}
+	}
+	return histograms, nil
+}
+
+// ratedRepository holds the ratings of the test clinic only.
+var ratedRepository = ratingsRepository{
+	ratings:    map[string]entity.AverageRating{test.ClinicId: {Rating: 4.5, Count: 2, WeightedRating: 4.2}},
+	dimensions: map[string]map[string]entity.DimensionRating{test.ClinicId: {"staff": {Rating: 4, Count: 1}}},
+	histograms: map[string]map[int]int{test.ClinicId: {4: 1, 5: 1}},
+}
+
+var testDimensions = []string{"wait_time", "staff", "cleanliness"}
+
+func TestService_GetClinicRatings(t *testing.T) {
+	logger, _ := log.NewForTest()
+	s := NewService(ratedRepository, nil, nil, testDimensions, nil, logger)
+	const unknownClinicId = "00000000-0000-0000-0000-000000000002"
+
+	ratings, err := s.GetClinicRatings(context.Background(), GetClinicRatingsRequest{Ids: []string{test.ClinicId, unknownClinicId}})
+	if err != nil {
+		t.Fatalf("GetClinicRatings() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		id   string
+		want entity.AverageRating
+	}{
+		{"rated clinic", test.ClinicId, entity.AverageRating{
+			Rating:         4.5,
+			Count:          2,
+			WeightedRating: 4.2,
+			Dimensions:     map[string]entity.DimensionRating{"wait_time": {}, "staff": {Rating: 4, Count: 1}, "cleanliness": {}},
+			Histogram:      map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 1},
+		}},
+		{"unknown clinic", unknownClinicId, entity.AverageRating{
+			Dimensions: map[string]entity.DimensionRating{"wait_time": {}, "staff": {}, "cleanliness": {}},
+			Histogram:  map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, ok := ratings[tc.id]; !ok || !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ratings[%s] = %+v, want %+v", tc.id, got, tc.want)
+			}
+		})
+	}
+	if len(ratings) != len(tests) {
+		t.Errorf("ratings = %+v, want the requested clinics only", ratings)
+	}
+}
+
+func TestService_GetClinicRating_breakdowns(t *testing.T) {
+	logger, _ := log.NewForTest()
+	s := NewService(ratedRepository, nil, nil, testDimensions, nil, logger)
+
+	rating, err := s.GetClinicRating(context.Background(), test.ClinicId)
+	if err != nil {
+		t.Fatalf("GetClinicRating() error = %v", err)
+	}
+	if want := map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 1}; !reflect.DeepEqual(rating.Histogram, want) {
+		t.Errorf("histogram = %v, want %v", rating.Histogram, want)
+	}
+	if want := map[string]entity.DimensionRating{"wait_time": {}, "staff": {Rating: 4, Count: 1}, "cleanliness": {}}; !reflect.DeepEqual(rating.Dimensions, want) {
+		t.Errorf("dimensions = %v, want %v", rating.Dimensions, want)
+	}
+}
+
+func TestAPI_getRatings(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), NewService(ratedRepository, nil, nil, testDimensions, nil, logger), test.MockAuthHandler(), logger)
+
+	ids := func(n int) string {
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = entity.GenerateID()
+		}
+		return strings.Join(ids, ",")
+	}
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"without ids", "", http.StatusBadRequest},
+		{"empty ids", "?ids=", http.StatusBadRequest},
+		{"blank ids", "?ids=,%20,", http.StatusBadRequest},
+		{"one id", "?ids=" + test.ClinicId, http.StatusOK},
+		{"unknown ids", "?ids=" + ids(2), http.StatusOK},
+		{"most ids allowed", "?ids=" + ids(maxBatchSize), http.StatusOK},
+		{"too many ids", "?ids=" + ids(maxBatchSize+1), http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, test.APITestCase{
+			Name:       tc.name,
+			Method:     "GET",
+			URL:        "/clinics/average-ratings" + tc.query,
+			Header:     test.MockAuthHeader(entity.RolePatient),
+			WantStatus: tc.wantStatus,
+		})
+	}
+}
@@ -2,6 +2,7 @@ package doctor_rating
 
 import (
 	"context"
+	"fmt"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
@@ -14,16 +15,44 @@ type Repository interface {
 	GetById(ctx context.Context, id string) (entity.ClinicRating, error)
 	GetRating(ctx context.Context, patientId string, clinicId string) (entity.ClinicRating, error)
 	GetClinicRating(ctx context.Context, clinicId string) (entity.AverageRating, error)
+	// GetClinicRatings returns the average ratings of the clinics with the given IDs, keyed by clinic ID.
+	// Clinics without ratings get a zero rating.
+	GetClinicRatings(ctx context.Context, clinicIds []string) (map[string]entity.AverageRating, error)
 	RateClinic(ctx context.Context, rating entity.ClinicRating) error
+	Update(ctx context.Context, rating entity.ClinicRating) error
+	Delete(ctx context.Context, rating entity.ClinicRating) error
+	// SetScores replaces the scores of the rating in the rating dimensions.
+	SetScores(ctx context.Context, ratingId string, scores map[string]float32) error
+	// GetScores returns the scores of the ratings with the given IDs keyed by rating ID and dimension name.
+	GetScores(ctx context.Context, ratingIds []string) (map[string]map[string]float32, error)
+	// GetDimensionRatings returns the average scores of the counted ratings of the clinics with the given IDs
+	// keyed by clinic ID and dimension name.
+	GetDimensionRatings(ctx context.Context, clinicIds []string) (map[string]map[string]entity.DimensionRating, error)
+	// GetHistograms returns the number of counted ratings of the clinics with the given IDs for every number
+	// of stars, keyed by clinic ID. Stars without ratings are left out.
+	GetHistograms(ctx context.Context, clinicIds []string) (map[string]map[int]int, error)
+	// AddHistory records a version of a rating that is about to be changed or deleted.
+	AddHistory(ctx context.Context, history entity.ClinicRatingHistory) error
+	// CountReviews returns the number of published ratings of the clinic with a written review.
+	CountReviews(ctx context.Context, clinicId string) (int, error)
+	// GetReviews returns the published ratings of the clinic with a written review, newest first.
+	GetReviews(ctx context.Context, clinicId string, offset int, limit int) ([]entity.ClinicRating, error)
+	// CountPending returns the number of ratings waiting for moderation.
+	CountPending(ctx context.Context) (int, error)
+	// GetPending returns the ratings waiting for moderation, oldest first.
+	GetPending(ctx context.Context, offset int, limit int) ([]entity.ClinicRating, error)
 }
 
 type repository struct {
-	db     *dbcontext.DB
-	logger log.Logger
+	db          *dbcontext.DB
+	priorWeight int
+	logger      log.Logger
 }
 
-func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
-	return repository{db, logger}
+// NewRepository creates a new rating repository. Weighted ratings are computed as if every clinic had
+// priorWeight more ratings at the average of all clinic ratings.
+func NewRepository(db *dbcontext.DB, priorWeight int, logger log.Logger) Repository {
+	return repository{db, priorWeight, logger}
 }
 
 func (r repository) GetRating(ctx context.Context, patientId string, clinicId string) (entity.ClinicRating, error) {
@@ -33,11 +62,65 @@ func (r repository) GetRating(ctx context.Context, patientId string, clinicId st
 }
 
 func (r repository) GetClinicRating(ctx context.Context, clinicId string) (entity.AverageRating, error) {
+	prior, err := r.getPriorRating(ctx)
+	if err != nil {
+		return entity.AverageRating{}, err
+	}
+
 	var count int
 	var rating float32
-	err := r.db.With(ctx).Select("COUNT(rating) AS count", "AVG(rating) AS rating").From("clinic_rating").Where(dbx.HashExp{"clinic_id": clinicId}).Row(&count, &rating)
+	err = r.db.With(ctx).Select("COUNT(rating) AS count", "COALESCE(AVG(rating), 0) AS rating").From("clinic_rating").Where(dbx.HashExp{"clinic_id": clinicId, "counted": true}).Row(&count, &rating)
+	if err != nil {
+		return entity.AverageRating{}, err
+	}
+
+	return entity.AverageRating{
+		Count:          count,
+		Rating:         rating,
+		WeightedRating: entity.BayesianAverage(rating, count, prior, r.priorWeight),
+	}, nil
+}
+
+// getPriorRating returns the average of all counted clinic ratings, towards which weighted ratings are pulled.
+func (r repository) getPriorRating(ctx context.Context) (float32, error) {
+	var rating float32
+	err := r.db.With(ctx).Select("COALESCE(AVG(rating), 0) AS rating").From("clinic_rating").Where(dbx.HashExp{"counted": true}).Row(&rating)
+	return rating, err
+}
+
+func (r repository) GetClinicRatings(ctx context.Context, clinicIds []string) (map[string]entity.AverageRating, error) {
+	prior, err := r.getPriorRating(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	return entity.AverageRating{Count: count, Rating: rating}, err
+	var rows []struct {
+		ClinicId string `db:"clinic_id"`
+		Count    int
+		Rating   float32
+	}
+	b := make([]interface{}, len(clinicIds))
+	for i := range clinicIds {
+		b[i] = clinicIds[i]
+	}
+	err = r.db.With(ctx).Select("clinic_id", "COUNT(rating) AS count", "AVG(rating) AS rating").From("clinic_rating").
+		Where(dbx.And(dbx.In("clinic_id", b...), dbx.HashExp{"counted": true})).GroupBy("clinic_id").All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings := make(map[string]entity.AverageRating, len(clinicIds))
+	for _, id := range clinicIds {
+		ratings[id] = entity.AverageRating{WeightedRating: entity.BayesianAverage(0, 0, prior, r.priorWeight)}
+	}
+	for _, row := range rows {
+		ratings[row.ClinicId] = entity.AverageRating{
+			Count:          row.Count,
+			Rating:         row.Rating,
+			WeightedRating: entity.BayesianAverage(row.Rating, row.Count, prior, r.priorWeight),
+		}
+	}
+	return ratings, nil
 }
 
 func (r repository) RateClinic(ctx context.Context, rating entity.ClinicRating) error {
@@ -49,3 +132,164 @@ func (r repository) GetById(ctx context.Context, id string) (entity.ClinicRating
 	err := r.db.With(ctx).Select().Model(id, &rating)
 	return rating, err
 }
+
+func (r repository) Update(ctx context.Context, rating entity.ClinicRating) error {
+	return r.db.With(ctx).Model(&rating).Update()
+}
+
+func (r repository) Delete(ctx context.Context, rating entity.ClinicRating) error {
+	if _, err := r.db.With(ctx).Delete("clinic_rating_score", dbx.HashExp{"rating_id": rating.ID}).Execute(); err != nil {
+		return err
+	}
+	return r.db.With(ctx).Model(&rating).Delete()
+}
+
+func (r repository) SetScores(ctx context.Context, ratingId string, scores map[string]float32) error {
+	if _, err := r.db.With(ctx).Delete("clinic_rating_score", dbx.HashExp{"rating_id": ratingId}).Execute(); err != nil {
+		return err
+	}
+	for dimension, score := range scores {
+		_, err := r.db.With(ctx).Insert("clinic_rating_score", dbx.Params{
+			"rating_id": ratingId,
+			"dimension": dimension,
+			"score":     score,
+		}).Execute()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r repository) GetScores(ctx context.Context, ratingIds []string) (map[string]map[string]float32, error) {
+	scores := make(map[string]map[string]float32, len(ratingIds))
+	if len(ratingIds) == 0 {
+		return scores, nil
+	}
+	b := make([]interface{}, len(ratingIds))
+	for i := range ratingIds {
+		b[i] = ratingIds[i]
+	}
+	var rows []entity.RatingScore
+	err := r.db.With(ctx).Select("rating_id", "dimension", "score").From("clinic_rating_score").
+		Where(dbx.In("rating_id", b...)).All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if scores[row.RatingId] == nil {
+			scores[row.RatingId] = make(map[string]float32)
+		}
+		scores[row.RatingId][row.Dimension] = row.Score
+	}
+	return scores, nil
+}
+
+func (r repository) GetDimensionRatings(ctx context.Context, clinicIds []string) (map[string]map[string]entity.DimensionRating, error) {
+	var rows []struct {
+		ClinicId  string `db:"clinic_id"`
+		Dimension string
+		Count     int
+		Rating    float32
+	}
+	b := make([]interface{}, len(clinicIds))
+	for i := range clinicIds {
+		b[i] = clinicIds[i]
+	}
+	err := r.db.With(ctx).Select("r.clinic_id", "s.dimension", "COUNT(s.score) AS count", "AVG(s.score) AS rating").
+		From("clinic_rating_score s").
+		InnerJoin("clinic_rating r", dbx.NewExp("r.id=s.rating_id")).
+		Where(dbx.And(dbx.In("r.clinic_id", b...), dbx.HashExp{"r.counted": true})).
+		GroupBy("r.clinic_id", "s.dimension").
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings := make(map[string]map[string]entity.DimensionRating)
+	for _, row := range rows {
+		if ratings[row.ClinicId] == nil {
+			ratings[row.ClinicId] = make(map[string]entity.DimensionRating)
+		}
+		ratings[row.ClinicId][row.Dimension] = entity.DimensionRating{Count: row.Count, Rating: row.Rating}
+	}
+	return ratings, nil
+}
+
+func (r repository) GetHistograms(ctx context.Context, clinicIds []string) (map[string]map[int]int, error) {
+	var rows []struct {
+		ClinicId string `db:"clinic_id"`
+		Stars    int
+		Count    int
+	}
+	b := make([]interface{}, len(clinicIds))
+	for i := range clinicIds {
+		b[i] = clinicIds[i]
+	}
+	stars := fmt.Sprintf("LEAST(GREATEST(ROUND(rating), %d), %d)", entity.MinStars, entity.MaxStars)
+	err := r.db.With(ctx).Select("clinic_id", stars+" AS stars", "COUNT(*) AS count").From("clinic_rating").
+		Where(dbx.And(dbx.In("clinic_id", b...), dbx.HashExp{"counted": true})).
+		GroupBy("clinic_id", "stars").
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	histograms := make(map[string]map[int]int)
+	for _, row := range rows {
+		if histograms[row.ClinicId] == nil {
+			histograms[row.ClinicId] = make(map[int]int)
+		}
+		histograms[row.ClinicId][row.Stars] = row.Count
+	}
+	return histograms, nil
+}
+
+func (r repository) AddHistory(ctx context.Context, history entity.ClinicRatingHistory) error {
+	return r.db.With(ctx).Model(&history).Insert()
+}
+
+func (r repository) CountReviews(ctx context.Context, clinicId string) (int, error) {
+	var count int
+	err := r.db.With(ctx).Select("COUNT(*)").From("clinic_rating").Where(reviewsExp(clinicId)).Row(&count)
+	return count, err
+}
+
+func (r repository) GetReviews(ctx context.Context, clinicId string, offset int, limit int) ([]entity.ClinicRating, error) {
+	var ratings []entity.ClinicRating
+	err := r.db.With(ctx).
+		Select().
+		Where(reviewsExp(clinicId)).
+		OrderBy("created_at DESC", "id").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&ratings)
+	return ratings, err
+}
+
+func (r repository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.With(ctx).Select("COUNT(*)").From("clinic_rating").Where(dbx.HashExp{"status": entity.ReviewPending}).Row(&count)
+	return count, err
+}
+
+func (r repository) GetPending(ctx context.Context, offset int, limit int) ([]entity.ClinicRating, error) {
+	var ratings []entity.ClinicRating
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"status": entity.ReviewPending}).
+		OrderBy("created_at", "id").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&ratings)
+	return ratings, err
+}
+
+// reviewsExp matches the published ratings of the clinic with a written review.
+func reviewsExp(clinicId string) dbx.Expression {
+	return dbx.And(
+		dbx.HashExp{"clinic_id": clinicId, "status": entity.ReviewPublished},
+		dbx.NewExp("(title<>'' OR body<>'')"),
+	)
+}
@@ -4,14 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
-	"github.com/matijapetrovic/clinichub/rating-service/pkg/httpclient"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/client/clinic"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/client/scheduling"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/errors"
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/dbcontext"
 	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -20,64 +20,109 @@ import (
 )
 
 type Service interface {
-	GetAvaialableRatings(request *http.Request) ([]Clinic, error)
+	GetAvaialableRatings(ctx context.Context) ([]clinic.Clinic, error)
 	RateClinic(ctx context.Context, clinicId string, request RateClinicRequest) (entity.ClinicRating, error)
 	GetClinicRating(ctx context.Context, clinicId string) (entity.AverageRating, error)
+	GetClinicRatings(ctx context.Context, req GetClinicRatingsRequest) (map[string]entity.AverageRating, error)
+	CountReviews(ctx context.Context, clinicId string) (int, error)
+	GetReviews(ctx context.Context, clinicId string, offset int, limit int) ([]entity.ClinicRating, error)
+	CountPendingReviews(ctx context.Context) (int, error)
+	GetPendingReviews(ctx context.Context, offset int, limit int) ([]entity.ClinicRating, error)
+	ModerateReview(ctx context.Context, id string, req ModerateReviewRequest) (entity.ClinicRating, error)
+	UpdateMyRating(ctx context.Context, clinicId string, req RateClinicRequest) (entity.ClinicRating, error)
+	DeleteMyRating(ctx context.Context, clinicId string) (entity.ClinicRating, error)
+	// GetDimensions returns the names of the dimensions in which clinics are rated.
+	GetDimensions() []string
 }
 
+// maxBatchSize is the maximum number of clinics whose ratings can be requested at once.
+const maxBatchSize = 100
+
+// GetClinicRatingsRequest represents a request for the average ratings of several clinics.
+type GetClinicRatingsRequest struct {
+	Ids []string
+}
+
+func (m GetClinicRatingsRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Ids, validation.Required, validation.Length(1, maxBatchSize)),
+	)
+}
+
+// RateClinicRequest represents a rating of a clinic with an optional written review and optional scores
+// in the rating dimensions, keyed by dimension name.
 type RateClinicRequest struct {
-	Rating float32 `json:"rating"`
+	Rating float32            `json:"rating"`
+	Title  string             `json:"title"`
+	Body   string             `json:"body"`
+	Scores map[string]float32 `json:"scores"`
 }
 
 func (m RateClinicRequest) Validate() error {
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.Rating, validation.Min(0.0), validation.Max(5.0)),
+		validation.Field(&m.Title, validation.Length(0, 100)),
+		validation.Field(&m.Body, validation.Length(0, 2000)),
+		validation.Field(&m.Scores, validation.By(func(value interface{}) error {
+			for _, score := range value.(map[string]float32) {
+				if score < 0 || score > 5 {
+					return validation.NewError("validation_score", "must be between 0 and 5")
+				}
+			}
+			return nil
+		})),
 	)
 }
 
-type service struct {
-	repo   Repository
-	logger log.Logger
+// ModerateReviewRequest represents a decision of an administrator to publish or reject a review.
+type ModerateReviewRequest struct {
+	Status string `json:"status"`
 }
 
-func NewService(repo Repository, logger log.Logger) Service {
-	return service{repo, logger}
+func (m ModerateReviewRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Status, validation.Required, validation.In(entity.ReviewPublished, entity.ReviewRejected)),
+	)
 }
 
-type Appointment struct {
-	Id                string    `json:"id"`
-	ClinicId          string    `json:"clinicId"`
-	DoctorId          string    `json:"doctorId"`
-	PatientId         string    `json:"patientId"`
-	AppointmentTypeId string    `json:"appointmentTypeId"`
-	Price             uint      `json:"price"`
-	Time              time.Time `json:"time"`
+// errAlreadyRated is returned when the patient has already rated the clinic.
+var errAlreadyRated = errors.Conflict("You have already rated this clinic.")
+
+type service struct {
+	repo             Repository
+	schedulingClient scheduling.Client
+	clinicClient     clinic.Client
+	dimensions       []string
+	transactional    dbcontext.TransactionFunc
+	logger           log.Logger
 }
 
-type Clinic struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
+// NewService creates a new rating service. Ratings can have scores in the given dimensions.
+func NewService(repo Repository, schedulingClient scheduling.Client, clinicClient clinic.Client, dimensions []string, transactional dbcontext.TransactionFunc, logger log.Logger) Service {
+	return service{repo, schedulingClient, clinicClient, dimensions, transactional, logger}
 }
 
-func (s service) GetAvaialableRatings(request *http.Request) ([]Clinic, error) {
-	ctx := request.Context()
-	appointments, err := getPatientAppointments(request.Header.Get("Authorization"))
+func (s service) GetAvaialableRatings(ctx context.Context) ([]clinic.Clinic, error) {
+	appointments, err := s.schedulingClient.GetPatientAppointments(ctx, scheduling.StatusCompleted)
 	if err != nil {
 		return nil, err
 	}
 	clinicsToRate := make(map[string]bool)
 
 	for _, appointment := range appointments {
+		if !appointment.HasTakenPlace() {
+			continue
+		}
 		_, err := s.repo.GetRating(ctx, appointment.PatientId, appointment.ClinicId)
 		if err == sql.ErrNoRows {
 			clinicsToRate[appointment.ClinicId] = true
 		}
 	}
 
-	result := make([]Clinic, 0)
+	result := make([]clinic.Clinic, 0)
 
 	for clinicId := range clinicsToRate {
-		clinic, err := getClinic(request.Header.Get("Authorization"), clinicId)
+		clinic, err := s.clinicClient.GetClinic(ctx, clinicId)
 		if err != nil {
 			return nil, err
 		}
@@ -87,99 +132,343 @@ func (s service) GetAvaialableRatings(request *http.Request) ([]Clinic, error) {
 	return result, nil
 }
 
-func getPatientAppointments(token string) ([]Appointment, error) {
-	url, err := url.Parse("http://localhost:8083/v1/appointments")
+// GetClinicRating returns the average rating of the clinic with the average scores in the rating dimensions
+// and the rating histogram.
+func (s service) GetClinicRating(ctx context.Context, clinicId string) (entity.AverageRating, error) {
+	rating, err := s.repo.GetClinicRating(ctx, clinicId)
 	if err != nil {
-		return nil, err
+		return entity.AverageRating{}, err
 	}
-	endDate := strings.Split(time.Now().String(), " ")[0]
-	fmt.Printf(endDate)
-	queryParamMap := map[string]string{
-		"endDate": endDate,
+	ratings := map[string]entity.AverageRating{clinicId: rating}
+	if err = s.addBreakdowns(ctx, []string{clinicId}, ratings); err != nil {
+		return entity.AverageRating{}, err
 	}
+	return ratings[clinicId], nil
+}
 
-	client := httpclient.NewJsonClient(
-		"GET",
-		url,
-		func(ctx context.Context, r *http.Response) (interface{}, error) {
-			var appointment []Appointment
-			err := json.NewDecoder(r.Body).Decode(&appointment)
-			if err != nil {
-				return nil, err
-			}
-			return appointment, nil
-		},
-		token,
-		httpclient.QueryParamBeforeFunc(queryParamMap),
-	)
-
-	res, err := client.Endpoint()(context.Background(), struct{}{})
+// GetClinicRatings returns the average ratings of the requested clinics keyed by clinic ID.
+// Clinics that have not been rated yet get a zero rating.
+func (s service) GetClinicRatings(ctx context.Context, req GetClinicRatingsRequest) (map[string]entity.AverageRating, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	ratings, err := s.repo.GetClinicRatings(ctx, req.Ids)
 	if err != nil {
 		return nil, err
 	}
-	appointments, ok := res.([]Appointment)
-	if !ok {
-		return nil, errors.New("unexpected error")
+	if err = s.addBreakdowns(ctx, req.Ids, ratings); err != nil {
+		return nil, err
 	}
-
-	return appointments, nil
+	return ratings, nil
 }
 
-func getClinic(token string, clinicId string) (Clinic, error) {
-	url, err := url.Parse("http://localhost:8081/v1/clinics/" + clinicId)
+// addBreakdowns adds the average scores in the rating dimensions and the histograms to the average ratings
+// of the clinics with the given IDs.
+func (s service) addBreakdowns(ctx context.Context, clinicIds []string, ratings map[string]entity.AverageRating) error {
+	dimensions, err := s.repo.GetDimensionRatings(ctx, clinicIds)
 	if err != nil {
-		return Clinic{}, err
+		return err
+	}
+	histograms, err := s.repo.GetHistograms(ctx, clinicIds)
+	if err != nil {
+		return err
 	}
 
-	client := httpclient.NewJsonClient(
-		"GET",
-		url,
-		func(ctx context.Context, r *http.Response) (interface{}, error) {
-			var clinic Clinic
-			err := json.NewDecoder(r.Body).Decode(&clinic)
-			if err != nil {
-				return nil, err
-			}
-			return clinic, nil
-		},
-		token,
-		nil,
-	)
+	for _, id := range clinicIds {
+		rating := ratings[id]
+		rating.Dimensions = make(map[string]entity.DimensionRating, len(s.dimensions))
+		for _, dimension := range s.dimensions {
+			rating.Dimensions[dimension] = dimensions[id][dimension]
+		}
+		rating.Histogram = entity.NewHistogram()
+		for stars, count := range histograms[id] {
+			rating.Histogram[stars] = count
+		}
+		ratings[id] = rating
+	}
+	return nil
+}
+
+// GetDimensions returns the names of the dimensions in which clinics are rated.
+func (s service) GetDimensions() []string {
+	return s.dimensions
+}
 
-	res, err := client.Endpoint()(context.Background(), struct{}{})
+// validateScores checks that the scores are given in the rating dimensions.
+func (s service) validateScores(scores map[string]float32) error {
+	for dimension := range scores {
+		if err := validation.Validate(dimension, validation.In(toInterfaces(s.dimensions)...)); err != nil {
+			return errors.UnprocessableEntity(validation.Errors{
+				"scores": validation.NewError("validation_dimension", fmt.Sprintf("%q is not a rating dimension", dimension)),
+			})
+		}
+	}
+	return nil
+}
+
+// toInterfaces returns the strings as a slice of interface{} values.
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i := range values {
+		result[i] = values[i]
+	}
+	return result
+}
+
+func (s service) RateClinic(ctx context.Context, clinicId string, req RateClinicRequest) (entity.ClinicRating, error) {
+	if err := req.Validate(); err != nil {
+		return entity.ClinicRating{}, err
+	}
+	if err := s.validateScores(req.Scores); err != nil {
+		return entity.ClinicRating{}, err
+	}
+	if err := s.checkVisited(ctx, clinicId); err != nil {
+		return entity.ClinicRating{}, err
+	}
+
+	user := auth.CurrentUser(ctx)
+	if _, err := s.repo.GetRating(ctx, user.GetID(), clinicId); err == nil {
+		return entity.ClinicRating{}, errAlreadyRated
+	} else if err != sql.ErrNoRows {
+		return entity.ClinicRating{}, err
+	}
+
+	id := entity.GenerateID()
+	review := newReview(req.Title, req.Body)
+	err := s.transactional(ctx, func(ctx context.Context) error {
+		err := s.repo.RateClinic(ctx, entity.ClinicRating{
+			ID:        id,
+			ClinicId:  clinicId,
+			PatientId: user.GetID(),
+			Rating:    req.Rating,
+			Review:    review,
+			Counted:   review.Status == entity.ReviewPublished,
+		})
+		if err != nil {
+			return err
+		}
+		return s.repo.SetScores(ctx, id, req.Scores)
+	})
+	if dbcontext.IsUniqueViolation(err) {
+		// another rating by the patient was committed after the check above
+		return entity.ClinicRating{}, errAlreadyRated
+	} else if err != nil {
+		return entity.ClinicRating{}, err
+	}
+	return s.getById(ctx, id)
+}
+
+// getById returns the rating with the given ID together with its scores.
+func (s service) getById(ctx context.Context, id string) (entity.ClinicRating, error) {
+	rating, err := s.repo.GetById(ctx, id)
 	if err != nil {
-		return Clinic{}, err
+		return entity.ClinicRating{}, err
 	}
-	clincic, ok := res.(Clinic)
-	if !ok {
-		return Clinic{}, errors.New("unexpected error")
+	ratings := []entity.ClinicRating{rating}
+	if err = s.addScores(ctx, ratings); err != nil {
+		return entity.ClinicRating{}, err
 	}
+	return ratings[0], nil
+}
 
-	return clincic, nil
+// addScores adds their scores in the rating dimensions to the ratings.
+func (s service) addScores(ctx context.Context, ratings []entity.ClinicRating) error {
+	ids := make([]string, len(ratings))
+	for i, rating := range ratings {
+		ids[i] = rating.ID
+	}
+	scores, err := s.repo.GetScores(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i, rating := range ratings {
+		if ratings[i].Scores = scores[rating.ID]; ratings[i].Scores == nil {
+			ratings[i].Scores = map[string]float32{}
+		}
+	}
+	return nil
 }
 
-func (s service) GetClinicRating(ctx context.Context, clinicId string) (entity.AverageRating, error) {
-	rating, err := s.repo.GetClinicRating(ctx, clinicId)
+// UpdateMyRating changes the rating the current patient gave the clinic, keeping the previous version in its history.
+// A review with a changed title or body has to be published by an administrator again and does not count toward
+// the averages until then. Without scores, the scores of the rating are kept.
+func (s service) UpdateMyRating(ctx context.Context, clinicId string, req RateClinicRequest) (entity.ClinicRating, error) {
+	if err := req.Validate(); err != nil {
+		return entity.ClinicRating{}, err
+	}
+	if err := s.validateScores(req.Scores); err != nil {
+		return entity.ClinicRating{}, err
+	}
+
+	var rating entity.ClinicRating
+	err := s.transactional(ctx, func(ctx context.Context) error {
+		var err error
+		if rating, err = s.getMyRating(ctx, clinicId); err != nil {
+			return err
+		}
+		if err = s.addHistory(ctx, rating, entity.RatingUpdated); err != nil {
+			return err
+		}
+
+		review := newReview(req.Title, req.Body)
+		if review.Title != rating.Title || review.Body != rating.Body {
+			rating.Title, rating.Body, rating.Status = review.Title, review.Body, review.Status
+		}
+		rating.Counted = rating.Status == entity.ReviewPublished
+		rating.Rating = req.Rating
+		if err = s.repo.Update(ctx, rating); err != nil {
+			return err
+		}
+
+		if req.Scores == nil {
+			return nil
+		}
+		rating.Scores = req.Scores
+		return s.repo.SetScores(ctx, rating.ID, req.Scores)
+	})
 	if err != nil {
-		return entity.AverageRating{}, err
+		return entity.ClinicRating{}, err
 	}
 	return rating, nil
 }
 
-func (s service) RateClinic(ctx context.Context, clinicId string, req RateClinicRequest) (entity.ClinicRating, error) {
-	if err := req.Validate(); err != nil {
+// DeleteMyRating withdraws the rating the current patient gave the clinic, keeping it in its history.
+func (s service) DeleteMyRating(ctx context.Context, clinicId string) (entity.ClinicRating, error) {
+	var rating entity.ClinicRating
+	err := s.transactional(ctx, func(ctx context.Context) error {
+		var err error
+		if rating, err = s.getMyRating(ctx, clinicId); err != nil {
+			return err
+		}
+		if err = s.addHistory(ctx, rating, entity.RatingDeleted); err != nil {
+			return err
+		}
+		return s.repo.Delete(ctx, rating)
+	})
+	if err != nil {
 		return entity.ClinicRating{}, err
 	}
+	return rating, nil
+}
+
+// getMyRating returns the rating the current patient gave the clinic.
+func (s service) getMyRating(ctx context.Context, clinicId string) (entity.ClinicRating, error) {
 	user := auth.CurrentUser(ctx)
-	id := entity.GenerateID()
-	err := s.repo.RateClinic(ctx, entity.ClinicRating{
-		ID:        id,
-		ClinicId:  clinicId,
-		PatientId: user.GetID(),
-		Rating:    req.Rating,
+	if user == nil {
+		return entity.ClinicRating{}, errors.Forbidden("")
+	}
+	rating, err := s.repo.GetRating(ctx, user.GetID(), clinicId)
+	if err == sql.ErrNoRows {
+		return entity.ClinicRating{}, errors.NotFound("You have not rated this clinic.")
+	} else if err != nil {
+		return entity.ClinicRating{}, err
+	}
+	return s.getById(ctx, rating.ID)
+}
+
+// addHistory records the current version of the rating before it is changed or deleted.
+func (s service) addHistory(ctx context.Context, rating entity.ClinicRating, action string) error {
+	scores, err := json.Marshal(rating.Scores)
+	if err != nil {
+		return err
+	}
+	return s.repo.AddHistory(ctx, entity.ClinicRatingHistory{
+		ID:        entity.GenerateID(),
+		RatingId:  rating.ID,
+		Rating:    rating.Rating,
+		PatientId: rating.PatientId,
+		ClinicId:  rating.ClinicId,
+		Review:    rating.Review,
+		Scores:    string(scores),
+		Action:    action,
+		ChangedAt: time.Now().UTC(),
 	})
+}
+
+// newReview returns the review of a new rating. A written review has to be published by an administrator.
+func newReview(title string, body string) entity.Review {
+	review := entity.Review{
+		Title:     strings.TrimSpace(title),
+		Body:      strings.TrimSpace(body),
+		Status:    entity.ReviewPublished,
+		CreatedAt: time.Now().UTC(),
+	}
+	if review.HasText() {
+		review.Status = entity.ReviewPending
+	}
+	return review
+}
+
+// CountReviews returns the number of published written reviews of the clinic.
+func (s service) CountReviews(ctx context.Context, clinicId string) (int, error) {
+	return s.repo.CountReviews(ctx, clinicId)
+}
+
+// GetReviews returns a page of the published written reviews of the clinic, newest first.
+func (s service) GetReviews(ctx context.Context, clinicId string, offset int, limit int) ([]entity.ClinicRating, error) {
+	reviews, err := s.repo.GetReviews(ctx, clinicId, offset, limit)
 	if err != nil {
+		return nil, err
+	}
+	if reviews == nil {
+		reviews = []entity.ClinicRating{}
+	}
+	if err = s.addScores(ctx, reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// CountPendingReviews returns the number of reviews waiting for moderation.
+func (s service) CountPendingReviews(ctx context.Context) (int, error) {
+	return s.repo.CountPending(ctx)
+}
+
+// GetPendingReviews returns a page of the moderation queue, oldest reviews first.
+func (s service) GetPendingReviews(ctx context.Context, offset int, limit int) ([]entity.ClinicRating, error) {
+	reviews, err := s.repo.GetPending(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	if reviews == nil {
+		reviews = []entity.ClinicRating{}
+	}
+	if err = s.addScores(ctx, reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// ModerateReview publishes or rejects the review of the rating with the given ID. A decision can be changed later.
+// Only a rating with a published review counts toward the averages.
+func (s service) ModerateReview(ctx context.Context, id string, req ModerateReviewRequest) (entity.ClinicRating, error) {
+	if err := req.Validate(); err != nil {
+		return entity.ClinicRating{}, err
+	}
+
+	rating, err := s.getById(ctx, id)
+	if err != nil {
+		return entity.ClinicRating{}, err
+	}
+	rating.Status = req.Status
+	rating.Counted = rating.Status == entity.ReviewPublished
+	if err = s.repo.Update(ctx, rating); err != nil {
 		return entity.ClinicRating{}, err
 	}
-	return s.repo.GetById(ctx, id)
+	return rating, nil
+}
+
+// checkVisited returns a forbidden error unless the current patient had an appointment at the clinic
+// that the clinic marked as completed.
+func (s service) checkVisited(ctx context.Context, clinicId string) error {
+	appointments, err := s.schedulingClient.GetPatientAppointments(ctx, scheduling.StatusCompleted)
+	if err != nil {
+		return err
+	}
+	for _, appointment := range appointments {
+		if appointment.ClinicId == clinicId && appointment.HasTakenPlace() {
+			return nil
+		}
+	}
+	return errors.Forbidden("Only patients who had an appointment at the clinic can rate it.")
 }
@@ -0,0 +1,155 @@
+package doctor_rating
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/rating-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/client/scheduling"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/errors"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/test"
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
+)
+
+const testPatientId = "00000000-0000-0000-0000-000000000100"
+
+// memoryRepository keeps ratings in memory.
+type memoryRepository struct {
+	Repository
+	ratings map[string]entity.ClinicRating
+}
+
+func (r memoryRepository) GetRating(ctx context.Context, patientId string, clinicId string) (entity.ClinicRating, error) {
+	for _, rating := range r.ratings {
+		if rating.PatientId == patientId && rating.ClinicId == clinicId {
+			return rating, nil
+		}
+	}
+	return entity.ClinicRating{}, sql.ErrNoRows
+}
+
+func (r memoryRepository) GetById(ctx context.Context, id string) (entity.ClinicRating, error) {
+	if rating, ok := r.ratings[id]; ok {
+		return rating, nil
+	}
+	return entity.ClinicRating{}, sql.ErrNoRows
+}
+
+func (r memoryRepository) RateClinic(ctx context.Context, rating entity.ClinicRating) error {
+	r.ratings[rating.ID] = rating
+	return nil
+}
+
+func (r memoryRepository) Update(ctx context.Context, rating entity.ClinicRating) error {
+	r.ratings[rating.ID] = rating
+	return nil
+}
+
+func (r memoryRepository) SetScores(ctx context.Context, ratingId string, scores map[string]float32) error {
+	return nil
+}
+
+func (r memoryRepository) GetScores(ctx context.Context, ratingIds []string) (map[string]map[string]float32, error) {
+	return map[string]map[string]float32{}, nil
+}
+
+func (r memoryRepository) AddHistory(ctx context.Context, history entity.ClinicRatingHistory) error {
+	return nil
+}
+
+// appointmentClient returns a past appointment of the patient at the test clinic having the given status,
+// filtering by the requested status the way the scheduling-service does.
+type appointmentClient struct {
+	status string
+}
+
+func (c appointmentClient) GetPatientAppointments(ctx context.Context, status string) ([]scheduling.Appointment, error) {
+	if status != "" && status != c.status {
+		return nil, nil
+	}
+	return []scheduling.Appointment{{
+		ClinicId:  test.ClinicId,
+		PatientId: testPatientId,
+		Time:      time.Now().Add(-time.Hour),
+		Status:    c.status,
+	}}, nil
+}
+
+// visitedClient returns an appointment of the patient at the test clinic that has taken place.
+var visitedClient = appointmentClient{status: scheduling.StatusCompleted}
+
+func TestService_RateClinic_approvedAppointmentNotEligible(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := memoryRepository{ratings: make(map[string]entity.ClinicRating)}
+	transactional := func(ctx context.Context, f func(ctx context.Context) error) error { return f(ctx) }
+	s := NewService(repo, appointmentClient{status: "approved"}, nil, nil, transactional, logger)
+	ctx := auth.WithUser(context.Background(), testPatientId, "patient", entity.RolePatient, nil)
+
+	_, err := s.RateClinic(ctx, test.ClinicId, RateClinicRequest{Rating: 4})
+	if res, ok := err.(errors.ErrorResponse); !ok || res.Status != http.StatusForbidden {
+		t.Fatalf("RateClinic() error = %v, want 403 for an approved appointment that was not completed", err)
+	}
+	if len(repo.ratings) != 0 {
+		t.Errorf("ratings = %v, want none stored", repo.ratings)
+	}
+}
+
+func TestService_onlyPublishedRatingsCount(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := memoryRepository{ratings: make(map[string]entity.ClinicRating)}
+	transactional := func(ctx context.Context, f func(ctx context.Context) error) error { return f(ctx) }
+	s := NewService(repo, visitedClient, nil, nil, transactional, logger)
+	ctx := auth.WithUser(context.Background(), testPatientId, "patient", entity.RolePatient, nil)
+
+	check := func(step string, rating entity.ClinicRating, err error, wantStatus string, wantCounted bool) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: error = %v", step, err)
+		}
+		stored := repo.ratings[rating.ID]
+		if stored.Status != wantStatus || stored.Counted != wantCounted {
+			t.Fatalf("%s: status = %q, counted = %v, want %q, %v", step, stored.Status, stored.Counted, wantStatus, wantCounted)
+		}
+	}
+
+	rating, err := s.RateClinic(ctx, test.ClinicId, RateClinicRequest{Rating: 4, Body: "Friendly staff."})
+	check("rating with a review", rating, err, entity.ReviewPending, false)
+
+	rating, err = s.ModerateReview(ctx, rating.ID, ModerateReviewRequest{Status: entity.ReviewPublished})
+	check("publishing the review", rating, err, entity.ReviewPublished, true)
+
+	rating, err = s.UpdateMyRating(ctx, test.ClinicId, RateClinicRequest{Rating: 2, Body: "Long waiting times."})
+	check("editing the review", rating, err, entity.ReviewPending, false)
+	if stored := repo.ratings[rating.ID]; stored.Rating != 2 {
+		t.Fatalf("editing the review: rating = %v, want 2", stored.Rating)
+	}
+
+	rating, err = s.ModerateReview(ctx, rating.ID, ModerateReviewRequest{Status: entity.ReviewRejected})
+	check("rejecting the edited review", rating, err, entity.ReviewRejected, false)
+
+	rating, err = s.ModerateReview(ctx, rating.ID, ModerateReviewRequest{Status: entity.ReviewPublished})
+	check("publishing the edited review", rating, err, entity.ReviewPublished, true)
+}
+
+func TestService_UpdateMyRating_removingPendingReview(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := memoryRepository{ratings: make(map[string]entity.ClinicRating)}
+	transactional := func(ctx context.Context, f func(ctx context.Context) error) error { return f(ctx) }
+	s := NewService(repo, visitedClient, nil, nil, transactional, logger)
+	ctx := auth.WithUser(context.Background(), testPatientId, "patient", entity.RolePatient, nil)
+
+	rating, err := s.RateClinic(ctx, test.ClinicId, RateClinicRequest{Rating: 4, Title: "Great"})
+	if err != nil {
+		t.Fatalf("RateClinic() error = %v", err)
+	}
+	if _, err = s.UpdateMyRating(ctx, test.ClinicId, RateClinicRequest{Rating: 5}); err != nil {
+		t.Fatalf("UpdateMyRating() error = %v", err)
+	}
+	if stored := repo.ratings[rating.ID]; stored.Status != entity.ReviewPublished || !stored.Counted {
+		t.Errorf("rating = %+v, want a published rating counting toward the averages", stored)
+	}
+}
@@ -11,6 +11,13 @@ import (
 const (
 	defaultServerPort         = 8082
 	defaultJWTExpirationHours = 72
+	defaultJWKSRefreshMinutes = 15
+	defaultPeerTimeoutSeconds = 5
+	defaultPeerRetries        = 2
+	defaultPeerRetryBackoffMs = 100
+	defaultBreakerThreshold   = 5
+	defaultBreakerCooldownSec = 30
+	defaultRatingPriorWeight  = 10
 )
 
 // Config represents an application configuration.
@@ -19,17 +26,61 @@ type Config struct {
 	ServerPort int `yaml:"server_port" env:"SERVER_PORT"`
 	// the data source name (DSN) for connecting to the database. required.
 	DSN string `yaml:"dsn" env:"DSN,secret"`
-	// JWT signing key. required.
+	// JWT signing key used to verify HS256-signed tokens without a key ID.
+	// required unless JWTVerificationKeyFile or JWKSLocation is set.
 	JWTSigningKey string `yaml:"jwt_signing_key" env:"JWT_SIGNING_KEY,secret"`
+	// path to the PEM file holding the RSA public key used to verify RS256-signed tokens.
+	JWTVerificationKeyFile string `yaml:"jwt_verification_key_file" env:"JWT_VERIFICATION_KEY_FILE"`
+	// the expected issuer of JWTs. The issuer is not checked if empty.
+	JWTIssuer string `yaml:"jwt_issuer" env:"JWT_ISSUER"`
+	// URL or file path of the JWKS document holding the keys used to verify tokens with a key ID.
+	JWKSLocation string `yaml:"jwks_location" env:"JWKS_LOCATION"`
+	// JWKS refresh interval in minutes. Defaults to 15 minutes
+	JWKSRefreshInterval int `yaml:"jwks_refresh_interval" env:"JWKS_REFRESH_INTERVAL"`
 	// JWT expiration in hours. Defaults to 72 hours (3 days)
 	JWTExpiration int `yaml:"jwt_expiration" env:"JWT_EXPIRATION"`
+	// the base URL of the clinic-service API. required.
+	ClinicServiceURL string `yaml:"clinic_service_url" env:"CLINIC_SERVICE_URL"`
+	// timeout of requests to the clinic-service API in seconds. Defaults to 5 seconds
+	ClinicServiceTimeout int `yaml:"clinic_service_timeout" env:"CLINIC_SERVICE_TIMEOUT"`
+	// the base URL of the scheduling-service API. required.
+	SchedulingServiceURL string `yaml:"scheduling_service_url" env:"SCHEDULING_SERVICE_URL"`
+	// timeout of requests to the scheduling-service API in seconds. Defaults to 5 seconds
+	SchedulingServiceTimeout int `yaml:"scheduling_service_timeout" env:"SCHEDULING_SERVICE_TIMEOUT"`
+	// number of times failed GET requests to other services are retried. Defaults to 2
+	PeerRetries int `yaml:"peer_retries" env:"PEER_RETRIES"`
+	// delay before the first retry in milliseconds, doubled for every further retry. Defaults to 100 milliseconds
+	PeerRetryBackoff int `yaml:"peer_retry_backoff" env:"PEER_RETRY_BACKOFF"`
+	// number of consecutive failed requests after which requests to a service are stopped. Defaults to 5
+	CircuitBreakerThreshold int `yaml:"circuit_breaker_threshold" env:"CIRCUIT_BREAKER_THRESHOLD"`
+	// time in seconds after which a stopped service is tried again. Defaults to 30 seconds
+	CircuitBreakerCooldown int `yaml:"circuit_breaker_cooldown" env:"CIRCUIT_BREAKER_COOLDOWN"`
+	// names of the dimensions in which clinics are rated. Defaults to wait_time, staff and cleanliness
+	ClinicRatingDimensions []string `yaml:"clinic_rating_dimensions" env:"CLINIC_RATING_DIMENSIONS"`
+	// names of the dimensions in which doctors are rated. Defaults to wait_time and communication
+	DoctorRatingDimensions []string `yaml:"doctor_rating_dimensions" env:"DOCTOR_RATING_DIMENSIONS"`
+	// number of ratings at the average of all ratings added to every clinic and doctor when computing
+	// weighted ratings. 0 turns weighting off. Defaults to 10
+	RatingPriorWeight int `yaml:"rating_prior_weight" env:"RATING_PRIOR_WEIGHT"`
 }
 
 // Validate validates the application configuration.
 func (c Config) Validate() error {
 	return validation.ValidateStruct(&c,
 		validation.Field(&c.DSN, validation.Required),
-		validation.Field(&c.JWTSigningKey, validation.Required),
+		validation.Field(&c.JWTSigningKey, validation.Required.When(c.JWTVerificationKeyFile == "" && c.JWKSLocation == "")),
+		validation.Field(&c.JWKSRefreshInterval, validation.Min(1)),
+		validation.Field(&c.ClinicServiceURL, validation.Required),
+		validation.Field(&c.ClinicServiceTimeout, validation.Min(1)),
+		validation.Field(&c.SchedulingServiceURL, validation.Required),
+		validation.Field(&c.SchedulingServiceTimeout, validation.Min(1)),
+		validation.Field(&c.PeerRetries, validation.Min(0)),
+		validation.Field(&c.PeerRetryBackoff, validation.Min(1)),
+		validation.Field(&c.CircuitBreakerThreshold, validation.Min(1)),
+		validation.Field(&c.CircuitBreakerCooldown, validation.Min(1)),
+		validation.Field(&c.ClinicRatingDimensions, validation.Each(validation.Required, validation.Length(1, 50))),
+		validation.Field(&c.DoctorRatingDimensions, validation.Each(validation.Required, validation.Length(1, 50))),
+		validation.Field(&c.RatingPriorWeight, validation.Min(0)),
 	)
 }
 
@@ -37,8 +88,18 @@ func (c Config) Validate() error {
 func Load(file string, logger log.Logger) (*Config, error) {
 	// default config
 	c := Config{
-		ServerPort:    defaultServerPort,
-		JWTExpiration: defaultJWTExpirationHours,
+		ServerPort:               defaultServerPort,
+		JWTExpiration:            defaultJWTExpirationHours,
+		JWKSRefreshInterval:      defaultJWKSRefreshMinutes,
+		SchedulingServiceTimeout: defaultPeerTimeoutSeconds,
+		ClinicServiceTimeout:     defaultPeerTimeoutSeconds,
+		PeerRetries:              defaultPeerRetries,
+		PeerRetryBackoff:         defaultPeerRetryBackoffMs,
+		CircuitBreakerThreshold:  defaultBreakerThreshold,
+		CircuitBreakerCooldown:   defaultBreakerCooldownSec,
+		ClinicRatingDimensions:   []string{"wait_time", "staff", "cleanliness"},
+		DoctorRatingDimensions:   []string{"wait_time", "communication"},
+		RatingPriorWeight:        defaultRatingPriorWeight,
 	}
 
 	// load from YAML config file
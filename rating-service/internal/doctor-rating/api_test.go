@@ -0,0 +1,83 @@
+package doctor_rating
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/matijapetrovic/clinichub/rating-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/test"
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
+)
+
+// mockService answers every call with empty results, so that the tests can check which requests reach it.
+type mockService struct{}
+
+func (s mockService) GetAvaialableRatings(ctx context.Context) ([]Doctor, error) {
+	return []Doctor{}, nil
+}
+
+func (s mockService) RateDoctor(ctx context.Context, doctorId string, request RateDoctorRequest) (entity.DoctorRating, error) {
+	return entity.DoctorRating{}, nil
+}
+
+func (s mockService) GetDoctorRating(ctx context.Context, doctorID string) (entity.AverageRating, error) {
+	return entity.AverageRating{}, nil
+}
+
+func (s mockService) GetDoctorRatings(ctx context.Context, req GetDoctorRatingsRequest) (map[string]entity.AverageRating, error) {
+	return map[string]entity.AverageRating{}, nil
+}
+
+func (s mockService) CountReviews(ctx context.Context, doctorId string) (int, error) {
+	return 0, nil
+}
+
+func (s mockService) GetReviews(ctx context.Context, doctorId string, offset int, limit int) ([]entity.DoctorRating, error) {
+	return []entity.DoctorRating{}, nil
+}
+
+func (s mockService) CountPendingReviews(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (s mockService) GetPendingReviews(ctx context.Context, offset int, limit int) ([]entity.DoctorRating, error) {
+	return []entity.DoctorRating{}, nil
+}
+
+func (s mockService) ModerateReview(ctx context.Context, id string, req ModerateReviewRequest) (entity.DoctorRating, error) {
+	return entity.DoctorRating{}, nil
+}
+
+func (s mockService) UpdateMyRating(ctx context.Context, doctorId string, req RateDoctorRequest) (entity.DoctorRating, error) {
+	return entity.DoctorRating{}, nil
+}
+
+func (s mockService) DeleteMyRating(ctx context.Context, doctorId string) (entity.DoctorRating, error) {
+	return entity.DoctorRating{}, nil
+}
+
+func (s mockService) GetDimensions() []string {
+	return []string{}
+}
+
+func TestAPI_roles(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), mockService{}, test.MockAuthHandler(), logger)
+
+	const (
+		ok        = http.StatusOK
+		created   = http.StatusCreated
+		forbidden = http.StatusForbidden
+		anonymous = http.StatusUnauthorized
+	)
+	test.Routes(t, router, []test.RouteTestCase{
+		{Method: "GET", URL: "/doctors/reviews/pending", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: forbidden, entity.RolePatient: forbidden}},
+		{Method: "PUT", URL: "/doctors/reviews/1/status", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: forbidden, entity.RolePatient: forbidden}},
+		{Method: "GET", URL: "/doctors/to-rate", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: forbidden, entity.RoleClinicAdmin: forbidden, entity.RolePatient: ok}},
+		{Method: "POST", URL: "/doctors/1/ratings", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: forbidden, entity.RoleClinicAdmin: forbidden, entity.RolePatient: created}},
+		{Method: "PUT", URL: "/doctors/1/ratings/mine", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: forbidden, entity.RoleClinicAdmin: forbidden, entity.RolePatient: ok}},
+		{Method: "DELETE", URL: "/doctors/1/ratings/mine", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: forbidden, entity.RoleClinicAdmin: forbidden, entity.RolePatient: ok}},
+	})
+}
@@ -0,0 +1,153 @@
+package doctor_rating
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/matijapetrovic/clinichub/rating-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/test"
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
+)
+
+// ratingsRepository holds the average ratings, dimension scores and histograms of the rated doctors.
+// Like the database, it gives doctors without ratings a zero rating and leaves them out of the breakdowns.
+type ratingsRepository struct {
+	Repository
+	ratings    map[string]entity.AverageRating
+	dimensions map[string]map[string]entity.DimensionRating
+	histograms map[string]map[int]int
+}
+
+func (r ratingsRepository) GetDoctorRating(ctx context.Context, doctorId string) (entity.AverageRating, error) {
+	return r.ratings[doctorId], nil
+}
+
+func (r ratingsRepository) GetDoctorRatings(ctx context.Context, doctorIds []string) (map[string]entity.AverageRating, error) {
+	ratings := make(map[string]entity.AverageRating, len(doctorIds))
+	for _, id := range doctorIds {
+		ratings[id] = r.ratings[id]
+	}
+	return ratings, nil
+}
+
+func (r ratingsRepository) GetDimensionRatings(ctx context.Context, doctorIds []string) (map[string]map[string]entity.DimensionRating, error) {
+	dimensions := make(map[string]map[string]entity.DimensionRating)
+	for _, id := range doctorIds {
+		if d, ok := r.dimensions[id]; ok {
+			dimensions[id] = d
+		}
+	}
+	return dimensions, nil
+}
+
+func (r ratingsRepository) GetHistograms(ctx context.Context, doctorIds []string) (map[string]map[int]int, error) {
+	histograms := make(map[string]map[int]int)
+	for _, id := range doctorIds {
+		if h, ok := r.histograms[id]; ok {
+			histograms[id] = h
+		}
+	}
+	return histograms, nil
+}
+
+// ratedRepository holds the ratings of testDoctorId only.
+var ratedRepository = ratingsRepository{
+	ratings:    map[string]entity.AverageRating{testDoctorId: {Rating: 4.5, Count: 2, WeightedRating: 4.2}},
+	dimensions: map[string]map[string]entity.DimensionRating{testDoctorId: {"communication": {Rating: 4, Count: 1}}},
+	histograms: map[string]map[int]int{testDoctorId: {4: 1, 5: 1}},
+}
+
+var testDimensions = []string{"wait_time", "communication"}
+
+func TestService_GetDoctorRatings(t *testing.T) {
+	logger, _ := log.NewForTest()
+	s := NewService(ratedRepository, nil, nil, testDimensions, nil, logger)
+	const unknownDoctorId = "00000000-0000-0000-0000-000000000011"
+
+	ratings, err := s.GetDoctorRatings(context.Background(), GetDoctorRatingsRequest{Ids: []string{testDoctorId, unknownDoctorId}})
+	if err != nil {
+		t.Fatalf("GetDoctorRatings() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		id   string
+		want entity.AverageRating
+	}{
+		{"rated doctor", testDoctorId, entity.AverageRating{
+			Rating:         4.5,
+			Count:          2,
+			WeightedRating: 4.2,
+			Dimensions:     map[string]entity.DimensionRating{"wait_time": {}, "communication": {Rating: 4, Count: 1}},
+			Histogram:      map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 1},
+		}},
+		{"unknown doctor", unknownDoctorId, entity.AverageRating{
+			Dimensions: map[string]entity.DimensionRating{"wait_time": {}, "communication": {}},
+			Histogram:  map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, ok := ratings[tc.id]; !ok || !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ratings[%s] = %+v, want %+v", tc.id, got, tc.want)
+			}
+		})
+	}
+	if len(ratings) != len(tests) {
+		t.Errorf("ratings = %+v, want the requested doctors only", ratings)
+	}
+}
+
+func TestService_GetDoctorRating_breakdowns(t *testing.T) {
+	logger, _ := log.NewForTest()
+	s := NewService(ratedRepository, nil, nil, testDimensions, nil, logger)
+
+	rating, err := s.GetDoctorRating(context.Background(), testDoctorId)
+	if err != nil {
+		t.Fatalf("GetDoctorRating() error = %v", err)
+	}
+	if want := map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 1}; !reflect.DeepEqual(rating.Histogram, want) {
+		t.Errorf("histogram = %v, want %v", rating.Histogram, want)
+	}
+	if want := map[string]entity.DimensionRating{"wait_time": {}, "communication": {Rating: 4, Count: 1}}; !reflect.DeepEqual(rating.Dimensions, want) {
+		t.Errorf("dimensions = %v, want %v", rating.Dimensions, want)
+	}
+}
+
+func TestAPI_getRatings(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), NewService(ratedRepository, nil, nil, testDimensions, nil, logger), test.MockAuthHandler(), logger)
+
+	ids := func(n int) string {
+		ids := make([]string, n)
+		for i := range ids {
+			ids[i] = entity.GenerateID()
+		}
+		return strings.Join(ids, ",")
+	}
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+	}{
+		{"without ids", "", http.StatusBadRequest},
+		{"empty ids", "?ids=", http.StatusBadRequest},
+		{"blank ids", "?ids=,%20,", http.StatusBadRequest},
+		{"one id", "?ids=" + testDoctorId, http.StatusOK},
+		{"unknown ids", "?ids=" + ids(2), http.StatusOK},
+		{"most ids allowed", "?ids=" + ids(maxBatchSize), http.StatusOK},
+		{"too many ids", "?ids=" + ids(maxBatchSize+1), http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, router, test.APITestCase{
+			Name:       tc.name,
+			Method:     "GET",
+			URL:        "/doctors/average-ratings" + tc.query,
+			Header:     test.MockAuthHeader(entity.RolePatient),
+			WantStatus: tc.wantStatus,
+		})
+	}
+}
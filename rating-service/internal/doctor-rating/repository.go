@@ -2,6 +2,7 @@ package doctor_rating
 
 import (
 	"context"
+	"fmt"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
@@ -14,16 +15,44 @@ type Repository interface {
 	GetById(ctx context.Context, id string) (entity.DoctorRating, error)
 	GetRating(ctx context.Context, patientId string, doctorId string) (entity.DoctorRating, error)
 	GetDoctorRating(ctx context.Context, doctorId string) (entity.AverageRating, error)
+	// GetDoctorRatings returns the average ratings of the doctors with the given IDs, keyed by doctor ID.
+	// Doctors without ratings get a zero rating.
+	GetDoctorRatings(ctx context.Context, doctorIds []string) (map[string]entity.AverageRating, error)
 	RateDoctor(ctx context.Context, rating entity.DoctorRating) error
+	Update(ctx context.Context, rating entity.DoctorRating) error
+	Delete(ctx context.Context, rating entity.DoctorRating) error
+	// SetScores replaces the scores of the rating in the rating dimensions.
+	SetScores(ctx context.Context, ratingId string, scores map[string]float32) error
+	// GetScores returns the scores of the ratings with the given IDs keyed by rating ID and dimension name.
+	GetScores(ctx context.Context, ratingIds []string) (map[string]map[string]float32, error)
+	// GetDimensionRatings returns the average scores of the counted ratings of the doctors with the given IDs
+	// keyed by doctor ID and dimension name.
+	GetDimensionRatings(ctx context.Context, doctorIds []string) (map[string]map[string]entity.DimensionRating, error)
+	// GetHistograms returns the number of counted ratings of the doctors with the given IDs for every number
+	// of stars, keyed by doctor ID. Stars without ratings are left out.
+	GetHistograms(ctx context.Context, doctorIds []string) (map[string]map[int]int, error)
+	// AddHistory records a version of a rating that is about to be changed or deleted.
+	AddHistory(ctx context.Context, history entity.DoctorRatingHistory) error
+	// CountReviews returns the number of published ratings of the doctor with a written review.
+	CountReviews(ctx context.Context, doctorId string) (int, error)
+	// GetReviews returns the published ratings of the doctor with a written review, newest first.
+	GetReviews(ctx context.Context, doctorId string, offset int, limit int) ([]entity.DoctorRating, error)
+	// CountPending returns the number of ratings waiting for moderation.
+	CountPending(ctx context.Context) (int, error)
+	// GetPending returns the ratings waiting for moderation, oldest first.
+	GetPending(ctx context.Context, offset int, limit int) ([]entity.DoctorRating, error)
 }
 
 type repository struct {
-	db     *dbcontext.DB
-	logger log.Logger
+	db          *dbcontext.DB
+	priorWeight int
+	logger      log.Logger
 }
 
-func NewRepository(db *dbcontext.DB, logger log.Logger) Repository {
-	return repository{db, logger}
+// NewRepository creates a new rating repository. Weighted ratings are computed as if every doctor had
+// priorWeight more ratings at the average of all doctor ratings.
+func NewRepository(db *dbcontext.DB, priorWeight int, logger log.Logger) Repository {
+	return repository{db, priorWeight, logger}
 }
 
 func (r repository) GetRating(ctx context.Context, patientId string, doctorID string) (entity.DoctorRating, error) {
@@ -33,11 +62,65 @@ func (r repository) GetRating(ctx context.Context, patientId string, doctorID st
 }
 
 func (r repository) GetDoctorRating(ctx context.Context, doctorId string) (entity.AverageRating, error) {
+	prior, err := r.getPriorRating(ctx)
+	if err != nil {
+		return entity.AverageRating{}, err
+	}
+
 	var count int
 	var rating float32
-	err := r.db.With(ctx).Select("COUNT(rating) AS count", "AVG(rating) AS rating").From("doctor_rating").Where(dbx.HashExp{"doctor_id": doctorId}).Row(&count, &rating)
+	err = r.db.With(ctx).Select("COUNT(rating) AS count", "COALESCE(AVG(rating), 0) AS rating").From("doctor_rating").Where(dbx.HashExp{"doctor_id": doctorId, "counted": true}).Row(&count, &rating)
+	if err != nil {
+		return entity.AverageRating{}, err
+	}
+
+	return entity.AverageRating{
+		Count:          count,
+		Rating:         rating,
+		WeightedRating: entity.BayesianAverage(rating, count, prior, r.priorWeight),
+	}, nil
+}
+
+// getPriorRating returns the average of all counted doctor ratings, towards which weighted ratings are pulled.
+func (r repository) getPriorRating(ctx context.Context) (float32, error) {
+	var rating float32
+	err := r.db.With(ctx).Select("COALESCE(AVG(rating), 0) AS rating").From("doctor_rating").Where(dbx.HashExp{"counted": true}).Row(&rating)
+	return rating, err
+}
+
+func (r repository) GetDoctorRatings(ctx context.Context, doctorIds []string) (map[string]entity.AverageRating, error) {
+	prior, err := r.getPriorRating(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	return entity.AverageRating{Count: count, Rating: rating}, err
+	var rows []struct {
+		DoctorId string `db:"doctor_id"`
+		Count    int
+		Rating   float32
+	}
+	b := make([]interface{}, len(doctorIds))
+	for i := range doctorIds {
+		b[i] = doctorIds[i]
+	}
+	err = r.db.With(ctx).Select("doctor_id", "COUNT(rating) AS count", "AVG(rating) AS rating").From("doctor_rating").
+		Where(dbx.And(dbx.In("doctor_id", b...), dbx.HashExp{"counted": true})).GroupBy("doctor_id").All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings := make(map[string]entity.AverageRating, len(doctorIds))
+	for _, id := range doctorIds {
+		ratings[id] = entity.AverageRating{WeightedRating: entity.BayesianAverage(0, 0, prior, r.priorWeight)}
+	}
+	for _, row := range rows {
+		ratings[row.DoctorId] = entity.AverageRating{
+			Count:          row.Count,
+			Rating:         row.Rating,
+			WeightedRating: entity.BayesianAverage(row.Rating, row.Count, prior, r.priorWeight),
+		}
+	}
+	return ratings, nil
 }
 
 func (r repository) RateDoctor(ctx context.Context, rating entity.DoctorRating) error {
@@ -49,3 +132,164 @@ func (r repository) GetById(ctx context.Context, id string) (entity.DoctorRating
 	err := r.db.With(ctx).Select().Model(id, &rating)
 	return rating, err
 }
+
+func (r repository) Update(ctx context.Context, rating entity.DoctorRating) error {
+	return r.db.With(ctx).Model(&rating).Update()
+}
+
+func (r repository) Delete(ctx context.Context, rating entity.DoctorRating) error {
+	if _, err := r.db.With(ctx).Delete("doctor_rating_score", dbx.HashExp{"rating_id": rating.ID}).Execute(); err != nil {
+		return err
+	}
+	return r.db.With(ctx).Model(&rating).Delete()
+}
+
+func (r repository) SetScores(ctx context.Context, ratingId string, scores map[string]float32) error {
+	if _, err := r.db.With(ctx).Delete("doctor_rating_score", dbx.HashExp{"rating_id": ratingId}).Execute(); err != nil {
+		return err
+	}
+	for dimension, score := range scores {
+		_, err := r.db.With(ctx).Insert("doctor_rating_score", dbx.Params{
+			"rating_id": ratingId,
+			"dimension": dimension,
+			"score":     score,
+		}).Execute()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r repository) GetScores(ctx context.Context, ratingIds []string) (map[string]map[string]float32, error) {
+	scores := make(map[string]map[string]float32, len(ratingIds))
+	if len(ratingIds) == 0 {
+		return scores, nil
+	}
+	b := make([]interface{}, len(ratingIds))
+	for i := range ratingIds {
+		b[i] = ratingIds[i]
+	}
+	var rows []entity.RatingScore
+	err := r.db.With(ctx).Select("rating_id", "dimension", "score").From("doctor_rating_score").
+		Where(dbx.In("rating_id", b...)).All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if scores[row.RatingId] == nil {
+			scores[row.RatingId] = make(map[string]float32)
+		}
+		scores[row.RatingId][row.Dimension] = row.Score
+	}
+	return scores, nil
+}
+
+func (r repository) GetDimensionRatings(ctx context.Context, doctorIds []string) (map[string]map[string]entity.DimensionRating, error) {
+	var rows []struct {
+		DoctorId  string `db:"doctor_id"`
+		Dimension string
+		Count     int
+		Rating    float32
+	}
+	b := make([]interface{}, len(doctorIds))
+	for i := range doctorIds {
+		b[i] = doctorIds[i]
+	}
+	err := r.db.With(ctx).Select("r.doctor_id", "s.dimension", "COUNT(s.score) AS count", "AVG(s.score) AS rating").
+		From("doctor_rating_score s").
+		InnerJoin("doctor_rating r", dbx.NewExp("r.id=s.rating_id")).
+		Where(dbx.And(dbx.In("r.doctor_id", b...), dbx.HashExp{"r.counted": true})).
+		GroupBy("r.doctor_id", "s.dimension").
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	ratings := make(map[string]map[string]entity.DimensionRating)
+	for _, row := range rows {
+		if ratings[row.DoctorId] == nil {
+			ratings[row.DoctorId] = make(map[string]entity.DimensionRating)
+		}
+		ratings[row.DoctorId][row.Dimension] = entity.DimensionRating{Count: row.Count, Rating: row.Rating}
+	}
+	return ratings, nil
+}
+
+func (r repository) GetHistograms(ctx context.Context, doctorIds []string) (map[string]map[int]int, error) {
+	var rows []struct {
+		DoctorId string `db:"doctor_id"`
+		Stars    int
+		Count    int
+	}
+	b := make([]interface{}, len(doctorIds))
+	for i := range doctorIds {
+		b[i] = doctorIds[i]
+	}
+	stars := fmt.Sprintf("LEAST(GREATEST(ROUND(rating), %d), %d)", entity.MinStars, entity.MaxStars)
+	err := r.db.With(ctx).Select("doctor_id", stars+" AS stars", "COUNT(*) AS count").From("doctor_rating").
+		Where(dbx.And(dbx.In("doctor_id", b...), dbx.HashExp{"counted": true})).
+		GroupBy("doctor_id", "stars").
+		All(&rows)
+	if err != nil {
+		return nil, err
+	}
+
+	histograms := make(map[string]map[int]int)
+	for _, row := range rows {
+		if histograms[row.DoctorId] == nil {
+			histograms[row.DoctorId] = make(map[int]int)
+		}
+		histograms[row.DoctorId][row.Stars] = row.Count
+	}
+	return histograms, nil
+}
+
+func (r repository) AddHistory(ctx context.Context, history entity.DoctorRatingHistory) error {
+	return r.db.With(ctx).Model(&history).Insert()
+}
+
+func (r repository) CountReviews(ctx context.Context, doctorId string) (int, error) {
+	var count int
+	err := r.db.With(ctx).Select("COUNT(*)").From("doctor_rating").Where(reviewsExp(doctorId)).Row(&count)
+	return count, err
+}
+
+func (r repository) GetReviews(ctx context.Context, doctorId string, offset int, limit int) ([]entity.DoctorRating, error) {
+	var ratings []entity.DoctorRating
+	err := r.db.With(ctx).
+		Select().
+		Where(reviewsExp(doctorId)).
+		OrderBy("created_at DESC", "id").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&ratings)
+	return ratings, err
+}
+
+func (r repository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	err := r.db.With(ctx).Select("COUNT(*)").From("doctor_rating").Where(dbx.HashExp{"status": entity.ReviewPending}).Row(&count)
+	return count, err
+}
+
+func (r repository) GetPending(ctx context.Context, offset int, limit int) ([]entity.DoctorRating, error) {
+	var ratings []entity.DoctorRating
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.HashExp{"status": entity.ReviewPending}).
+		OrderBy("created_at", "id").
+		Offset(int64(offset)).
+		Limit(int64(limit)).
+		All(&ratings)
+	return ratings, err
+}
+
+// reviewsExp matches the published ratings of the doctor with a written review.
+func reviewsExp(doctorId string) dbx.Expression {
+	return dbx.And(
+		dbx.HashExp{"doctor_id": doctorId, "status": entity.ReviewPublished},
+		dbx.NewExp("(title<>'' OR body<>'')"),
+	)
+}
@@ -4,14 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
-	"github.com/matijapetrovic/clinichub/rating-service/pkg/httpclient"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/client/clinic"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/client/scheduling"
+	"github.com/matijapetrovic/clinichub/rating-service/internal/errors"
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/dbcontext"
 	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -20,38 +20,86 @@ import (
 )
 
 type Service interface {
-	GetAvaialableRatings(request *http.Request) ([]Doctor, error)
+	GetAvaialableRatings(ctx context.Context) ([]Doctor, error)
 	RateDoctor(ctx context.Context, doctorId string, request RateDoctorRequest) (entity.DoctorRating, error)
 	GetDoctorRating(ctx context.Context, doctorID string) (entity.AverageRating, error)
+	GetDoctorRatings(ctx context.Context, req GetDoctorRatingsRequest) (map[string]entity.AverageRating, error)
+	CountReviews(ctx context.Context, doctorId string) (int, error)
+	GetReviews(ctx context.Context, doctorId string, offset int, limit int) ([]entity.DoctorRating, error)
+	CountPendingReviews(ctx context.Context) (int, error)
+	GetPendingReviews(ctx context.Context, offset int, limit int) ([]entity.DoctorRating, error)
+	ModerateReview(ctx context.Context, id string, req ModerateReviewRequest) (entity.DoctorRating, error)
+	UpdateMyRating(ctx context.Context, doctorId string, req RateDoctorRequest) (entity.DoctorRating, error)
+	DeleteMyRating(ctx context.Context, doctorId string) (entity.DoctorRating, error)
+	// GetDimensions returns the names of the dimensions in which doctors are rated.
+	GetDimensions() []string
 }
 
+// maxBatchSize is the maximum number of doctors whose ratings can be requested at once.
+const maxBatchSize = 100
+
+// GetDoctorRatingsRequest represents a request for the average ratings of several doctors.
+type GetDoctorRatingsRequest struct {
+	Ids []string
+}
+
+func (m GetDoctorRatingsRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Ids, validation.Required, validation.Length(1, maxBatchSize)),
+	)
+}
+
+// RateDoctorRequest represents a rating of a doctor with an optional written review and optional scores
+// in the rating dimensions, keyed by dimension name.
 type RateDoctorRequest struct {
-	Rating float32 `json:"rating"`
+	Rating float32            `json:"rating"`
+	Title  string             `json:"title"`
+	Body   string             `json:"body"`
+	Scores map[string]float32 `json:"scores"`
 }
 
 func (m RateDoctorRequest) Validate() error {
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.Rating, validation.Min(0.0), validation.Max(5.0)),
+		validation.Field(&m.Title, validation.Length(0, 100)),
+		validation.Field(&m.Body, validation.Length(0, 2000)),
+		validation.Field(&m.Scores, validation.By(func(value interface{}) error {
+			for _, score := range value.(map[string]float32) {
+				if score < 0 || score > 5 {
+					return validation.NewError("validation_score", "must be between 0 and 5")
+				}
+			}
+			return nil
+		})),
 	)
 }
 
-type service struct {
-	repo   Repository
-	logger log.Logger
+// ModerateReviewRequest represents a decision of an administrator to publish or reject a review.
+type ModerateReviewRequest struct {
+	Status string `json:"status"`
 }
 
-func NewService(repo Repository, logger log.Logger) Service {
-	return service{repo, logger}
+func (m ModerateReviewRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Status, validation.Required, validation.In(entity.ReviewPublished, entity.ReviewRejected)),
+	)
+}
+
+// errAlreadyRated is returned when the patient has already rated the doctor.
+var errAlreadyRated = errors.Conflict("You have already rated this doctor.")
+
+type service struct {
+	repo             Repository
+	schedulingClient scheduling.Client
+	clinicClient     clinic.Client
+	dimensions       []string
+	transactional    dbcontext.TransactionFunc
+	logger           log.Logger
 }
 
-type Appointment struct {
-	Id                string    `json:"id"`
-	ClinicId          string    `json:"clinicId"`
-	DoctorId          string    `json:"doctorId"`
-	PatientId         string    `json:"patientId"`
-	AppointmentTypeId string    `json:"appointmentTypeId"`
-	Price             uint      `json:"price"`
-	Time              time.Time `json:"time"`
+// NewService creates a new rating service. Ratings can have scores in the given dimensions.
+func NewService(repo Repository, schedulingClient scheduling.Client, clinicClient clinic.Client, dimensions []string, transactional dbcontext.TransactionFunc, logger log.Logger) Service {
+	return service{repo, schedulingClient, clinicClient, dimensions, transactional, logger}
 }
 
 type Doctor struct {
@@ -61,15 +109,17 @@ type Doctor struct {
 	Name      string `json:"name"`
 }
 
-func (s service) GetAvaialableRatings(request *http.Request) ([]Doctor, error) {
-	ctx := request.Context()
-	appointments, err := getPatientAppointments(request.Header.Get("Authorization"))
+func (s service) GetAvaialableRatings(ctx context.Context) ([]Doctor, error) {
+	appointments, err := s.schedulingClient.GetPatientAppointments(ctx, scheduling.StatusCompleted)
 	if err != nil {
 		return nil, err
 	}
 	doctorsToRate := make(map[string]bool)
 
 	for _, appointment := range appointments {
+		if !appointment.HasTakenPlace() {
+			continue
+		}
 		_, err := s.repo.GetRating(ctx, appointment.PatientId, appointment.DoctorId)
 		if err == sql.ErrNoRows {
 			doctorsToRate[appointment.DoctorId] = true
@@ -79,110 +129,358 @@ func (s service) GetAvaialableRatings(request *http.Request) ([]Doctor, error) {
 	result := make([]Doctor, 0)
 
 	for doctorId := range doctorsToRate {
-		doctor, err := getDoctor(request.Header.Get("Authorization"), doctorId)
+		doctor, err := s.clinicClient.GetDoctor(ctx, doctorId)
 		if err != nil {
 			return nil, err
 		}
-		doctor.Name = fmt.Sprintf("%s %s", doctor.FirstName, doctor.LastName)
-		result = append(result, doctor)
+		result = append(result, Doctor{
+			Id:        doctor.Id,
+			FirstName: doctor.FirstName,
+			LastName:  doctor.LastName,
+			Name:      fmt.Sprintf("%s %s", doctor.FirstName, doctor.LastName),
+		})
 	}
 
 	return result, nil
 }
 
-func getPatientAppointments(token string) ([]Appointment, error) {
-	url, err := url.Parse("http://localhost:8083/v1/appointments")
+// GetDoctorRating returns the average rating of the doctor with the average scores in the rating dimensions
+// and the rating histogram.
+func (s service) GetDoctorRating(ctx context.Context, doctorID string) (entity.AverageRating, error) {
+	rating, err := s.repo.GetDoctorRating(ctx, doctorID)
 	if err != nil {
-		return nil, err
+		return entity.AverageRating{}, err
 	}
-	endDate := strings.Split(time.Now().String(), " ")[0]
-	fmt.Printf(endDate)
-	queryParamMap := map[string]string{
-		"endDate": endDate,
+	ratings := map[string]entity.AverageRating{doctorID: rating}
+	if err = s.addBreakdowns(ctx, []string{doctorID}, ratings); err != nil {
+		return entity.AverageRating{}, err
 	}
+	return ratings[doctorID], nil
+}
 
-	client := httpclient.NewJsonClient(
-		"GET",
-		url,
-		func(ctx context.Context, r *http.Response) (interface{}, error) {
-			var appointment []Appointment
-			err := json.NewDecoder(r.Body).Decode(&appointment)
-			if err != nil {
-				return nil, err
-			}
-			return appointment, nil
-		},
-		token,
-		httpclient.QueryParamBeforeFunc(queryParamMap),
-	)
-
-	res, err := client.Endpoint()(context.Background(), struct{}{})
+// GetDoctorRatings returns the average ratings of the requested doctors keyed by doctor ID.
+// Doctors that have not been rated yet get a zero rating.
+func (s service) GetDoctorRatings(ctx context.Context, req GetDoctorRatingsRequest) (map[string]entity.AverageRating, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	ratings, err := s.repo.GetDoctorRatings(ctx, req.Ids)
 	if err != nil {
 		return nil, err
 	}
-	appointments, ok := res.([]Appointment)
-	if !ok {
-		return nil, errors.New("unexpected error")
+	if err = s.addBreakdowns(ctx, req.Ids, ratings); err != nil {
+		return nil, err
 	}
-
-	return appointments, nil
+	return ratings, nil
 }
 
-func getDoctor(token string, doctorId string) (Doctor, error) {
-	url, err := url.Parse("http://localhost:8081/v1/doctors/" + doctorId)
+// addBreakdowns adds the average scores in the rating dimensions and the histograms to the average ratings
+// of the doctors with the given IDs.
+func (s service) addBreakdowns(ctx context.Context, doctorIds []string, ratings map[string]entity.AverageRating) error {
+	dimensions, err := s.repo.GetDimensionRatings(ctx, doctorIds)
+	if err != nil {
+		return err
+	}
+	histograms, err := s.repo.GetHistograms(ctx, doctorIds)
 	if err != nil {
-		return Doctor{}, err
+		return err
 	}
 
-	client := httpclient.NewJsonClient(
-		"GET",
-		url,
-		func(ctx context.Context, r *http.Response) (interface{}, error) {
-			var doctor Doctor
-			err := json.NewDecoder(r.Body).Decode(&doctor)
-			if err != nil {
-				return nil, err
-			}
-			return doctor, nil
-		},
-		token,
-		nil,
-	)
+	for _, id := range doctorIds {
+		rating := ratings[id]
+		rating.Dimensions = make(map[string]entity.DimensionRating, len(s.dimensions))
+		for _, dimension := range s.dimensions {
+			rating.Dimensions[dimension] = dimensions[id][dimension]
+		}
+		rating.Histogram = entity.NewHistogram()
+		for stars, count := range histograms[id] {
+			rating.Histogram[stars] = count
+		}
+		ratings[id] = rating
+	}
+	return nil
+}
 
-	res, err := client.Endpoint()(context.Background(), struct{}{})
+// GetDimensions returns the names of the dimensions in which doctors are rated.
+func (s service) GetDimensions() []string {
+	return s.dimensions
+}
+
+// validateScores checks that the scores are given in the rating dimensions.
+func (s service) validateScores(scores map[string]float32) error {
+	for dimension := range scores {
+		if err := validation.Validate(dimension, validation.In(toInterfaces(s.dimensions)...)); err != nil {
+			return errors.UnprocessableEntity(validation.Errors{
+				"scores": validation.NewError("validation_dimension", fmt.Sprintf("%q is not a rating dimension", dimension)),
+			})
+		}
+	}
+	return nil
+}
+
+// toInterfaces returns the strings as a slice of interface{} values.
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i := range values {
+		result[i] = values[i]
+	}
+	return result
+}
+
+func (s service) RateDoctor(ctx context.Context, doctorId string, req RateDoctorRequest) (entity.DoctorRating, error) {
+	if err := req.Validate(); err != nil {
+		return entity.DoctorRating{}, err
+	}
+	if err := s.validateScores(req.Scores); err != nil {
+		return entity.DoctorRating{}, err
+	}
+	if err := s.checkVisited(ctx, doctorId); err != nil {
+		return entity.DoctorRating{}, err
+	}
+
+	user := auth.CurrentUser(ctx)
+	if _, err := s.repo.GetRating(ctx, user.GetID(), doctorId); err == nil {
+		return entity.DoctorRating{}, errAlreadyRated
+	} else if err != sql.ErrNoRows {
+		return entity.DoctorRating{}, err
+	}
+
+	id := entity.GenerateID()
+	review := newReview(req.Title, req.Body)
+	err := s.transactional(ctx, func(ctx context.Context) error {
+		err := s.repo.RateDoctor(ctx, entity.DoctorRating{
+			ID:        id,
+			DoctorId:  doctorId,
+			PatientId: user.GetID(),
+			Rating:    req.Rating,
+			Review:    review,
+			Counted:   review.Status == entity.ReviewPublished,
+		})
+		if err != nil {
+			return err
+		}
+		return s.repo.SetScores(ctx, id, req.Scores)
+	})
+	if dbcontext.IsUniqueViolation(err) {
+		// another rating by the patient was committed after the check above
+		return entity.DoctorRating{}, errAlreadyRated
+	} else if err != nil {
+		return entity.DoctorRating{}, err
+	}
+	return s.getById(ctx, id)
+}
+
+// getById returns the rating with the given ID together with its scores.
+func (s service) getById(ctx context.Context, id string) (entity.DoctorRating, error) {
+	rating, err := s.repo.GetById(ctx, id)
 	if err != nil {
-		return Doctor{}, err
+		return entity.DoctorRating{}, err
 	}
-	doctor, ok := res.(Doctor)
-	if !ok {
-		return Doctor{}, errors.New("unexpected error")
+	ratings := []entity.DoctorRating{rating}
+	if err = s.addScores(ctx, ratings); err != nil {
+		return entity.DoctorRating{}, err
 	}
+	return ratings[0], nil
+}
 
-	return doctor, nil
+// addScores adds their scores in the rating dimensions to the ratings.
+func (s service) addScores(ctx context.Context, ratings []entity.DoctorRating) error {
+	ids := make([]string, len(ratings))
+	for i, rating := range ratings {
+		ids[i] = rating.ID
+	}
+	scores, err := s.repo.GetScores(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i, rating := range ratings {
+		if ratings[i].Scores = scores[rating.ID]; ratings[i].Scores == nil {
+			ratings[i].Scores = map[string]float32{}
+		}
+	}
+	return nil
 }
 
-func (s service) GetDoctorRating(ctx context.Context, doctorID string) (entity.AverageRating, error) {
-	rating, err := s.repo.GetDoctorRating(ctx, doctorID)
+// UpdateMyRating changes the rating the current patient gave the doctor, keeping the previous version in its history.
+// A review with a changed title or body has to be published by an administrator again and does not count toward
+// the averages until then. Without scores, the scores of the rating are kept.
+func (s service) UpdateMyRating(ctx context.Context, doctorId string, req RateDoctorRequest) (entity.DoctorRating, error) {
+	if err := req.Validate(); err != nil {
+		return entity.DoctorRating{}, err
+	}
+	if err := s.validateScores(req.Scores); err != nil {
+		return entity.DoctorRating{}, err
+	}
+
+	var rating entity.DoctorRating
+	err := s.transactional(ctx, func(ctx context.Context) error {
+		var err error
+		if rating, err = s.getMyRating(ctx, doctorId); err != nil {
+			return err
+		}
+		if err = s.addHistory(ctx, rating, entity.RatingUpdated); err != nil {
+			return err
+		}
+
+		review := newReview(req.Title, req.Body)
+		if review.Title != rating.Title || review.Body != rating.Body {
+			rating.Title, rating.Body, rating.Status = review.Title, review.Body, review.Status
+		}
+		rating.Counted = rating.Status == entity.ReviewPublished
+		rating.Rating = req.Rating
+		if err = s.repo.Update(ctx, rating); err != nil {
+			return err
+		}
+
+		if req.Scores == nil {
+			return nil
+		}
+		rating.Scores = req.Scores
+		return s.repo.SetScores(ctx, rating.ID, req.Scores)
+	})
 	if err != nil {
-		return entity.AverageRating{}, err
+		return entity.DoctorRating{}, err
 	}
 	return rating, nil
 }
 
-func (s service) RateDoctor(ctx context.Context, doctorId string, req RateDoctorRequest) (entity.DoctorRating, error) {
-	if err := req.Validate(); err != nil {
+// DeleteMyRating withdraws the rating the current patient gave the doctor, keeping it in its history.
+func (s service) DeleteMyRating(ctx context.Context, doctorId string) (entity.DoctorRating, error) {
+	var rating entity.DoctorRating
+	err := s.transactional(ctx, func(ctx context.Context) error {
+		var err error
+		if rating, err = s.getMyRating(ctx, doctorId); err != nil {
+			return err
+		}
+		if err = s.addHistory(ctx, rating, entity.RatingDeleted); err != nil {
+			return err
+		}
+		return s.repo.Delete(ctx, rating)
+	})
+	if err != nil {
 		return entity.DoctorRating{}, err
 	}
+	return rating, nil
+}
+
+// getMyRating returns the rating the current patient gave the doctor.
+func (s service) getMyRating(ctx context.Context, doctorId string) (entity.DoctorRating, error) {
 	user := auth.CurrentUser(ctx)
-	id := entity.GenerateID()
-	err := s.repo.RateDoctor(ctx, entity.DoctorRating{
-		ID:        id,
-		DoctorId:  doctorId,
-		PatientId: user.GetID(),
-		Rating:    req.Rating,
+	if user == nil {
+		return entity.DoctorRating{}, errors.Forbidden("")
+	}
+	rating, err := s.repo.GetRating(ctx, user.GetID(), doctorId)
+	if err == sql.ErrNoRows {
+		return entity.DoctorRating{}, errors.NotFound("You have not rated this doctor.")
+	} else if err != nil {
+		return entity.DoctorRating{}, err
+	}
+	return s.getById(ctx, rating.ID)
+}
+
+// addHistory records the current version of the rating before it is changed or deleted.
+func (s service) addHistory(ctx context.Context, rating entity.DoctorRating, action string) error {
+	scores, err := json.Marshal(rating.Scores)
+	if err != nil {
+		return err
+	}
+	return s.repo.AddHistory(ctx, entity.DoctorRatingHistory{
+		ID:        entity.GenerateID(),
+		RatingId:  rating.ID,
+		Rating:    rating.Rating,
+		PatientId: rating.PatientId,
+		DoctorId:  rating.DoctorId,
+		Review:    rating.Review,
+		Scores:    string(scores),
+		Action:    action,
+		ChangedAt: time.Now().UTC(),
 	})
+}
+
+// newReview returns the review of a new rating. A written review has to be published by an administrator.
+func newReview(title string, body string) entity.Review {
+	review := entity.Review{
+		Title:     strings.TrimSpace(title),
+		Body:      strings.TrimSpace(body),
+		Status:    entity.ReviewPublished,
+		CreatedAt: time.Now().UTC(),
+	}
+	if review.HasText() {
+		review.Status = entity.ReviewPending
+	}
+	return review
+}
+
+// CountReviews returns the number of published written reviews of the doctor.
+func (s service) CountReviews(ctx context.Context, doctorId string) (int, error) {
+	return s.repo.CountReviews(ctx, doctorId)
+}
+
+// GetReviews returns a page of the published written reviews of the doctor, newest first.
+func (s service) GetReviews(ctx context.Context, doctorId string, offset int, limit int) ([]entity.DoctorRating, error) {
+	reviews, err := s.repo.GetReviews(ctx, doctorId, offset, limit)
 	if err != nil {
+		return nil, err
+	}
+	if reviews == nil {
+		reviews = []entity.DoctorRating{}
+	}
+	if err = s.addScores(ctx, reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// CountPendingReviews returns the number of reviews waiting for moderation.
+func (s service) CountPendingReviews(ctx context.Context) (int, error) {
+	return s.repo.CountPending(ctx)
+}
+
+// GetPendingReviews returns a page of the moderation queue, oldest reviews first.
+func (s service) GetPendingReviews(ctx context.Context, offset int, limit int) ([]entity.DoctorRating, error) {
+	reviews, err := s.repo.GetPending(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	if reviews == nil {
+		reviews = []entity.DoctorRating{}
+	}
+	if err = s.addScores(ctx, reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// ModerateReview publishes or rejects the review of the rating with the given ID. A decision can be changed later.
+// Only a rating with a published review counts toward the averages.
+func (s service) ModerateReview(ctx context.Context, id string, req ModerateReviewRequest) (entity.DoctorRating, error) {
+	if err := req.Validate(); err != nil {
+		return entity.DoctorRating{}, err
+	}
+
+	rating, err := s.getById(ctx, id)
+	if err != nil {
+		return entity.DoctorRating{}, err
+	}
+	rating.Status = req.Status
+	rating.Counted = rating.Status == entity.ReviewPublished
+	if err = s.repo.Update(ctx, rating); err != nil {
 		return entity.DoctorRating{}, err
 	}
-	return s.repo.GetById(ctx, id)
+	return rating, nil
+}
+
+// checkVisited returns a forbidden error unless the current patient had an appointment with the doctor
+// that the clinic marked as completed.
+func (s service) checkVisited(ctx context.Context, doctorId string) error {
+	appointments, err := s.schedulingClient.GetPatientAppointments(ctx, scheduling.StatusCompleted)
+	if err != nil {
+		return err
+	}
+	for _, appointment := range appointments {
+		if appointment.DoctorId == doctorId && appointment.HasTakenPlace() {
+			return nil
+		}
+	}
+	return errors.Forbidden("Only patients who had an appointment with the doctor can rate it.")
 }
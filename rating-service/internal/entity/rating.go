@@ -1,10 +1,26 @@
 package entity
 
+import "time"
+
+// Moderation statuses of ratings. Ratings with a written review wait for an administrator to publish them,
+// while ratings without one are published right away. A rating counts toward the average once it has been
+// published; an edited review waiting for moderation again only holds back its text, not the rating.
+const (
+	ReviewPending   = "pending"
+	ReviewPublished = "published"
+	ReviewRejected  = "rejected"
+)
+
 type DoctorRating struct {
 	ID        string  `json:"id"`
 	Rating    float32 `json:"rating"`
 	PatientId string  `json:"patientId"`
 	DoctorId  string  `json:"clinicId"`
+	Review
+	// Counted tells whether the rating counts toward the averages, which it does while its review is published.
+	Counted bool `json:"-"`
+	// Scores holds the scores of the rating in the rating dimensions, keyed by dimension name.
+	Scores map[string]float32 `json:"scores" db:"-"`
 }
 
 type ClinicRating struct {
@@ -12,9 +28,107 @@ type ClinicRating struct {
 	Rating    float32 `json:"rating"`
 	PatientId string  `json:"patientId"`
 	ClinicId  string  `json:"clinicId"`
+	Review
+	// Counted tells whether the rating counts toward the averages, which it does while its review is published.
+	Counted bool `json:"-"`
+	// Scores holds the scores of the rating in the rating dimensions, keyed by dimension name.
+	Scores map[string]float32 `json:"scores" db:"-"`
+}
+
+// Review is the optional written part of a rating together with its moderation status.
+type Review struct {
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
+// HasText returns whether the review has a title or a body.
+func (r Review) HasText() bool {
+	return r.Title != "" || r.Body != ""
+}
+
+// Changes of ratings recorded in their history.
+const (
+	RatingUpdated = "updated"
+	RatingDeleted = "deleted"
+)
+
+// ClinicRatingHistory is a version of a clinic rating that its author changed or withdrew, kept for audit.
+type ClinicRatingHistory struct {
+	ID        string  `json:"id"`
+	RatingId  string  `json:"ratingId"`
+	Rating    float32 `json:"rating"`
+	PatientId string  `json:"patientId"`
+	ClinicId  string  `json:"clinicId"`
+	Review
+	// Scores holds the JSON-encoded scores of the version in the rating dimensions.
+	Scores string `json:"scores"`
+	// Action tells whether the version was replaced by an update or deleted.
+	Action    string    `json:"action"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// DoctorRatingHistory is a version of a doctor rating that its author changed or withdrew, kept for audit.
+type DoctorRatingHistory struct {
+	ID        string  `json:"id"`
+	RatingId  string  `json:"ratingId"`
+	Rating    float32 `json:"rating"`
+	PatientId string  `json:"patientId"`
+	DoctorId  string  `json:"doctorId"`
+	Review
+	// Scores holds the JSON-encoded scores of the version in the rating dimensions.
+	Scores string `json:"scores"`
+	// Action tells whether the version was replaced by an update or deleted.
+	Action    string    `json:"action"`
+	ChangedAt time.Time `json:"changedAt"`
+}
+
+// RatingScore is the score of a rating in one of the rating dimensions, such as cleanliness.
+type RatingScore struct {
+	RatingId  string  `json:"ratingId"`
+	Dimension string  `json:"dimension"`
+	Score     float32 `json:"score"`
+}
+
+// Stars are the whole numbers of stars ratings are rounded to in rating histograms.
+const (
+	MinStars = 1
+	MaxStars = 5
+)
+
 type AverageRating struct {
 	Rating float32 `json:"rating"`
 	Count  int     `json:"count"`
+	// WeightedRating is the Bayesian average of the ratings, which ranks a few high ratings below many slightly lower ones.
+	WeightedRating float32 `json:"weightedRating"`
+	// Dimensions holds the average scores in the rating dimensions, keyed by dimension name.
+	Dimensions map[string]DimensionRating `json:"dimensions"`
+	// Histogram holds the number of ratings for every number of stars from MinStars to MaxStars,
+	// with ratings rounded to whole stars.
+	Histogram map[int]int `json:"histogram"`
+}
+
+// BayesianAverage returns the average of count ratings averaging rating together with priorWeight ratings
+// averaging priorRating, which pulls the averages of rarely rated clinics and doctors towards priorRating.
+func BayesianAverage(rating float32, count int, priorRating float32, priorWeight int) float32 {
+	if count+priorWeight == 0 {
+		return 0
+	}
+	return (rating*float32(count) + priorRating*float32(priorWeight)) / float32(count+priorWeight)
+}
+
+// DimensionRating is the average score in a rating dimension.
+type DimensionRating struct {
+	Rating float32 `json:"rating"`
+	Count  int     `json:"count"`
+}
+
+// NewHistogram returns a rating histogram without ratings.
+func NewHistogram() map[int]int {
+	histogram := make(map[int]int, MaxStars-MinStars+1)
+	for stars := MinStars; stars <= MaxStars; stars++ {
+		histogram[stars] = 0
+	}
+	return histogram
 }
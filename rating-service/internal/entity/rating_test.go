@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestNewHistogram(t *testing.T) {
+	want := map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}
+	if got := NewHistogram(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewHistogram() = %v, want %v", got, want)
+	}
+}
+
+func TestBayesianAverage(t *testing.T) {
+	tests := []struct {
+		name        string
+		rating      float32
+		count       int
+		priorRating float32
+		priorWeight int
+		want        float32
+	}{
+		{"no ratings gives the prior", 0, 0, 4, 10, 4},
+		{"no ratings and no prior weight", 0, 0, 4, 0, 0},
+		{"no prior weight gives the average", 4.5, 3, 4, 0, 4.5},
+		{"prior weight as many ratings at the prior", 5, 10, 4, 10, 4.5},
+		{"a few ratings are pulled towards the prior", 5, 1, 3, 10, 35.0 / 11},
+		{"many ratings stay close to their average", 4.8, 400, 3, 10, (4.8*400 + 30) / 410},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := BayesianAverage(tc.rating, tc.count, tc.priorRating, tc.priorWeight); math.Abs(float64(got-tc.want)) > 1e-5 {
+				t.Errorf("BayesianAverage() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBayesianAverage_ranking(t *testing.T) {
+	// a single 5.0 rating ranks below 400 ratings averaging 4.8
+	if one, many := BayesianAverage(5, 1, 4, 10), BayesianAverage(4.8, 400, 4, 10); one >= many {
+		t.Errorf("BayesianAverage() = %v for one 5.0 rating, want it below %v for 400 ratings averaging 4.8", one, many)
+	}
+}
@@ -1,11 +1,13 @@
 package errors
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/matijapetrovic/clinichub/rating-service/pkg/httpclient"
 	"github.com/matijapetrovic/clinichub/rating-service/pkg/log"
 	"net/http"
 	"runtime/debug"
@@ -64,5 +66,29 @@ func buildErrorResponse(err error) ErrorResponse {
 	if errors.Is(err, sql.ErrNoRows) {
 		return NotFound("")
 	}
+	if errors.Is(err, httpclient.ErrCircuitOpen) {
+		return ServiceUnavailable("")
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return GatewayTimeout("")
+	}
+	var responseErr *httpclient.ResponseError
+	if errors.As(err, &responseErr) {
+		return buildPeerErrorResponse(responseErr)
+	}
+	return InternalServerError("")
+}
+
+// buildPeerErrorResponse builds an error response from an error response returned by another service.
+// Not-found and authentication errors are passed through, anything else is treated as an internal error.
+func buildPeerErrorResponse(err *httpclient.ResponseError) ErrorResponse {
+	switch err.StatusCode {
+	case http.StatusNotFound:
+		return NotFound("")
+	case http.StatusUnauthorized:
+		return Unauthorized(err.Message)
+	case http.StatusForbidden:
+		return Forbidden(err.Message)
+	}
 	return InternalServerError("")
 }
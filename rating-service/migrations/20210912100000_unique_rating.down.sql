@@ -0,0 +1,10 @@
+DROP INDEX doctor_rating_patient_id_doctor_id ON doctor_rating;
+DROP INDEX clinic_rating_patient_id_clinic_id ON clinic_rating;
+
+INSERT INTO doctor_rating (id, rating, patient_id, doctor_id)
+SELECT id, rating, patient_id, doctor_id FROM doctor_rating_duplicate;
+INSERT INTO clinic_rating (id, rating, patient_id, clinic_id)
+SELECT id, rating, patient_id, clinic_id FROM clinic_rating_duplicate;
+
+DROP TABLE doctor_rating_duplicate;
+DROP TABLE clinic_rating_duplicate;
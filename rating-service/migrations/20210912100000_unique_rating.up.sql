@@ -0,0 +1,35 @@
+-- Keep a single rating of a patient for the same clinic or doctor. Ratings carry no timestamp at this point,
+-- so the rating with the smallest ID is kept. The other ratings no longer count towards the averages:
+-- they are moved to the *_rating_duplicate tables instead of being dropped, and the down migration restores them.
+CREATE TABLE clinic_rating_duplicate
+(
+    id          VARCHAR(36)  NOT NULL PRIMARY KEY,
+    rating      DECIMAL(3,2) NOT NULL,
+    patient_id  VARCHAR(36)  NOT NULL,
+    clinic_id   VARCHAR(36)  NOT NULL,
+    archived_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE doctor_rating_duplicate
+(
+    id          VARCHAR(36)  NOT NULL PRIMARY KEY,
+    rating      DECIMAL(3,2) NOT NULL,
+    patient_id  VARCHAR(36)  NOT NULL,
+    doctor_id   VARCHAR(36)  NOT NULL,
+    archived_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO clinic_rating_duplicate (id, rating, patient_id, clinic_id)
+SELECT r1.id, r1.rating, r1.patient_id, r1.clinic_id
+FROM clinic_rating r1
+WHERE EXISTS (SELECT 1 FROM clinic_rating r2 WHERE r2.patient_id = r1.patient_id AND r2.clinic_id = r1.clinic_id AND r2.id < r1.id);
+INSERT INTO doctor_rating_duplicate (id, rating, patient_id, doctor_id)
+SELECT r1.id, r1.rating, r1.patient_id, r1.doctor_id
+FROM doctor_rating r1
+WHERE EXISTS (SELECT 1 FROM doctor_rating r2 WHERE r2.patient_id = r1.patient_id AND r2.doctor_id = r1.doctor_id AND r2.id < r1.id);
+
+DELETE r FROM clinic_rating r JOIN clinic_rating_duplicate d ON d.id = r.id;
+DELETE r FROM doctor_rating r JOIN doctor_rating_duplicate d ON d.id = r.id;
+
+CREATE UNIQUE INDEX clinic_rating_patient_id_clinic_id ON clinic_rating (patient_id, clinic_id);
+CREATE UNIQUE INDEX doctor_rating_patient_id_doctor_id ON doctor_rating (patient_id, doctor_id);
@@ -0,0 +1,5 @@
+DROP INDEX doctor_rating_status_created_at ON doctor_rating;
+DROP INDEX clinic_rating_status_created_at ON clinic_rating;
+
+ALTER TABLE doctor_rating DROP COLUMN created_at, DROP COLUMN status, DROP COLUMN body, DROP COLUMN title;
+ALTER TABLE clinic_rating DROP COLUMN created_at, DROP COLUMN status, DROP COLUMN body, DROP COLUMN title;
@@ -0,0 +1,13 @@
+ALTER TABLE clinic_rating
+    ADD COLUMN title      VARCHAR(255)  NOT NULL DEFAULT '',
+    ADD COLUMN body       VARCHAR(4000) NOT NULL DEFAULT '',
+    ADD COLUMN status     VARCHAR(16)   NOT NULL DEFAULT 'published',
+    ADD COLUMN created_at DATETIME      NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE doctor_rating
+    ADD COLUMN title      VARCHAR(255)  NOT NULL DEFAULT '',
+    ADD COLUMN body       VARCHAR(4000) NOT NULL DEFAULT '',
+    ADD COLUMN status     VARCHAR(16)   NOT NULL DEFAULT 'published',
+    ADD COLUMN created_at DATETIME      NOT NULL DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX clinic_rating_status_created_at ON clinic_rating (status, created_at);
+CREATE INDEX doctor_rating_status_created_at ON doctor_rating (status, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE doctor_rating_history;
+DROP TABLE clinic_rating_history;
@@ -0,0 +1,33 @@
+CREATE TABLE clinic_rating_history
+(
+    id         VARCHAR(36)   NOT NULL PRIMARY KEY,
+    rating_id  VARCHAR(36)   NOT NULL,
+    rating     DECIMAL(3,2)  NOT NULL,
+    patient_id VARCHAR(36)   NOT NULL,
+    clinic_id  VARCHAR(36)   NOT NULL,
+    title      VARCHAR(255)  NOT NULL,
+    body       VARCHAR(4000) NOT NULL,
+    status     VARCHAR(16)   NOT NULL,
+    created_at DATETIME      NOT NULL,
+    action     VARCHAR(16)   NOT NULL,
+    changed_at DATETIME      NOT NULL,
+
+    INDEX clinic_rating_history_rating_id (rating_id)
+);
+
+CREATE TABLE doctor_rating_history
+(
+    id         VARCHAR(36)   NOT NULL PRIMARY KEY,
+    rating_id  VARCHAR(36)   NOT NULL,
+    rating     DECIMAL(3,2)  NOT NULL,
+    patient_id VARCHAR(36)   NOT NULL,
+    doctor_id  VARCHAR(36)   NOT NULL,
+    title      VARCHAR(255)  NOT NULL,
+    body       VARCHAR(4000) NOT NULL,
+    status     VARCHAR(16)   NOT NULL,
+    created_at DATETIME      NOT NULL,
+    action     VARCHAR(16)   NOT NULL,
+    changed_at DATETIME      NOT NULL,
+
+    INDEX doctor_rating_history_rating_id (rating_id)
+);
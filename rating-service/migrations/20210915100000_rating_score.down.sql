@@ -0,0 +1,4 @@
+ALTER TABLE doctor_rating_history DROP COLUMN scores;
+ALTER TABLE clinic_rating_history DROP COLUMN scores;
+DROP TABLE doctor_rating_score;
+DROP TABLE clinic_rating_score;
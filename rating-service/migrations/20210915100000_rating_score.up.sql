@@ -0,0 +1,20 @@
+CREATE TABLE clinic_rating_score
+(
+    rating_id VARCHAR(36)  NOT NULL,
+    dimension VARCHAR(50)  NOT NULL,
+    score     DECIMAL(3,2) NOT NULL,
+
+    PRIMARY KEY (rating_id, dimension)
+);
+
+CREATE TABLE doctor_rating_score
+(
+    rating_id VARCHAR(36)  NOT NULL,
+    dimension VARCHAR(50)  NOT NULL,
+    score     DECIMAL(3,2) NOT NULL,
+
+    PRIMARY KEY (rating_id, dimension)
+);
+
+ALTER TABLE clinic_rating_history ADD COLUMN scores VARCHAR(1000) NOT NULL DEFAULT '';
+ALTER TABLE doctor_rating_history ADD COLUMN scores VARCHAR(1000) NOT NULL DEFAULT '';
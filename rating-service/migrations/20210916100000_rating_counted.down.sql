@@ -0,0 +1,2 @@
+ALTER TABLE doctor_rating DROP COLUMN counted;
+ALTER TABLE clinic_rating DROP COLUMN counted;
@@ -0,0 +1,5 @@
+-- only ratings with a published review count toward the averages
+ALTER TABLE clinic_rating ADD COLUMN counted BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE doctor_rating ADD COLUMN counted BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE clinic_rating SET counted = TRUE WHERE status = 'published';
+UPDATE doctor_rating SET counted = TRUE WHERE status = 'published';
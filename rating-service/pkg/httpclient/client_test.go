@@ -0,0 +1,206 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakePeer is a service answering the first failures requests with the given status and the others with a JSON body.
+func fakePeer(status int, failures int32) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= failures {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"message":"peer failed"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"peer"}`))
+	}))
+	return server, &calls
+}
+
+// hangingPeer is a service that does not answer until the request is abandoned or the returned stop function is called.
+func hangingPeer() (*httptest.Server, *int32, func()) {
+	var calls int32
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	return server, &calls, func() {
+		close(done)
+		server.Close()
+	}
+}
+
+type result struct {
+	Name string `json:"name"`
+}
+
+func TestClient_Get(t *testing.T) {
+	server, calls := fakePeer(http.StatusOK, 0)
+	defer server.Close()
+
+	var res result
+	err := New(server.URL).Get(context.Background(), "/peer", nil, &res)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if res.Name != "peer" || atomic.LoadInt32(calls) != 1 {
+		t.Errorf("Get() = %+v after %d calls, want the peer result after 1 call", res, atomic.LoadInt32(calls))
+	}
+}
+
+func TestClient_Get_retries(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		failures  int32
+		retries   int
+		wantCalls int32
+		wantErr   int
+	}{
+		{"5xx recovered by a retry", http.StatusServiceUnavailable, 2, 2, 3, 0},
+		{"5xx until retries run out", http.StatusInternalServerError, 5, 2, 3, http.StatusInternalServerError},
+		{"4xx not retried", http.StatusNotFound, 5, 2, 1, http.StatusNotFound},
+		{"without retries", http.StatusBadGateway, 5, 0, 1, http.StatusBadGateway},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server, calls := fakePeer(tc.status, tc.failures)
+			defer server.Close()
+
+			var res result
+			err := New(server.URL, WithRetries(tc.retries, time.Millisecond)).Get(context.Background(), "/peer", nil, &res)
+			if atomic.LoadInt32(calls) != tc.wantCalls {
+				t.Errorf("calls = %d, want %d", atomic.LoadInt32(calls), tc.wantCalls)
+			}
+			if tc.wantErr == 0 {
+				if err != nil || res.Name != "peer" {
+					t.Errorf("Get() = %+v, %v, want the peer result", res, err)
+				}
+				return
+			}
+			var responseErr *ResponseError
+			if !errors.As(err, &responseErr) || responseErr.StatusCode != tc.wantErr || responseErr.Message != "peer failed" {
+				t.Errorf("Get() error = %v, want a ResponseError with status %d", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_Do_doesNotRetryPost(t *testing.T) {
+	server, calls := fakePeer(http.StatusServiceUnavailable, 5)
+	defer server.Close()
+
+	err := New(server.URL, WithRetries(2, time.Millisecond)).Do(context.Background(), http.MethodPost, "/peer", nil, result{}, nil)
+	if err == nil || atomic.LoadInt32(calls) != 1 {
+		t.Errorf("Do() error = %v after %d calls, want an error after 1 call", err, atomic.LoadInt32(calls))
+	}
+}
+
+func TestClient_Get_timeout(t *testing.T) {
+	server, calls, stop := hangingPeer()
+	defer stop()
+
+	start := time.Now()
+	err := New(server.URL, WithTimeout(50*time.Millisecond), WithRetries(1, time.Millisecond)).
+		Get(context.Background(), "/peer", nil, nil)
+	if !isServerFailure(err) {
+		t.Errorf("Get() error = %v, want a timeout", err)
+	}
+	if atomic.LoadInt32(calls) != 2 {
+		t.Errorf("calls = %d, want every attempt to time out on its own", atomic.LoadInt32(calls))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Get() took %v, want the attempts to be cut off", elapsed)
+	}
+}
+
+func TestClient_Get_contextCancelled(t *testing.T) {
+	server, calls, stop := hangingPeer()
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := New(server.URL, WithRetries(3, time.Millisecond)).Get(ctx, "/peer", nil, nil)
+	if err == nil || atomic.LoadInt32(calls) != 1 {
+		t.Errorf("Get() error = %v after %d calls, want an error without retries", err, atomic.LoadInt32(calls))
+	}
+}
+
+func TestClient_Get_unreachable(t *testing.T) {
+	server, _ := fakePeer(http.StatusOK, 0)
+	server.Close()
+
+	err := New(server.URL, WithRetries(1, time.Millisecond)).Get(context.Background(), "/peer", nil, nil)
+	if !isServerFailure(err) {
+		t.Errorf("Get() error = %v, want a network error", err)
+	}
+}
+
+func TestClient_circuitBreaker(t *testing.T) {
+	server, calls := fakePeer(http.StatusServiceUnavailable, 3)
+	defer server.Close()
+
+	c := New(server.URL, WithCircuitBreaker(2, time.Minute))
+	now := time.Now()
+	c.breaker.now = func() time.Time { return now }
+	get := func() error {
+		return c.Get(context.Background(), "/peer", nil, nil)
+	}
+
+	// two failures open the circuit
+	for i := 0; i < 2; i++ {
+		if err := get(); !isServerFailure(err) {
+			t.Fatalf("Get() error = %v, want a server failure", err)
+		}
+	}
+	if err := get(); err != ErrCircuitOpen || atomic.LoadInt32(calls) != 2 {
+		t.Fatalf("Get() error = %v after %d calls, want ErrCircuitOpen without calling the peer", err, atomic.LoadInt32(calls))
+	}
+
+	// the failing trial request keeps the circuit open for another cooldown
+	now = now.Add(time.Minute)
+	if err := get(); !isServerFailure(err) || atomic.LoadInt32(calls) != 3 {
+		t.Fatalf("Get() error = %v after %d calls, want the trial request to reach the peer", err, atomic.LoadInt32(calls))
+	}
+	if err := get(); err != ErrCircuitOpen {
+		t.Fatalf("Get() error = %v, want ErrCircuitOpen", err)
+	}
+
+	// the successful trial request closes the circuit
+	now = now.Add(time.Minute)
+	for i := 0; i < 2; i++ {
+		if err := get(); err != nil {
+			t.Fatalf("Get() error = %v, want the peer result", err)
+		}
+	}
+	if atomic.LoadInt32(calls) != 5 {
+		t.Errorf("calls = %d, want 5", atomic.LoadInt32(calls))
+	}
+}
+
+func TestClient_circuitBreaker_ignoresClientErrors(t *testing.T) {
+	server, calls := fakePeer(http.StatusNotFound, 5)
+	defer server.Close()
+
+	c := New(server.URL, WithCircuitBreaker(1, time.Minute))
+	for i := 0; i < 3; i++ {
+		var responseErr *ResponseError
+		if err := c.Get(context.Background(), "/peer", nil, nil); !errors.As(err, &responseErr) {
+			t.Fatalf("Get() error = %v, want a ResponseError", err)
+		}
+	}
+	if atomic.LoadInt32(calls) != 3 {
+		t.Errorf("calls = %d, want every request to reach the peer", atomic.LoadInt32(calls))
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/go-ozzo/ozzo-dbx"
 	"github.com/go-ozzo/ozzo-routing/v2"
 	"github.com/go-ozzo/ozzo-routing/v2/content"
@@ -16,11 +17,14 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/matijapetrovic/clinichub/scheduling-service/internal/appointment"
 	"github.com/matijapetrovic/clinichub/scheduling-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/client/clinic"
 	"github.com/matijapetrovic/clinichub/scheduling-service/internal/config"
 	"github.com/matijapetrovic/clinichub/scheduling-service/internal/errors"
 	"github.com/matijapetrovic/clinichub/scheduling-service/internal/healthcheck"
 	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/accesslog"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/cron"
 	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/dbcontext"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/httpclient"
 	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
 )
 
@@ -41,6 +45,21 @@ func main() {
 		os.Exit(-1)
 	}
 
+	// load the keys used to verify JWTs
+	var keySet *auth.KeySet
+	if cfg.JWKSLocation != "" {
+		if keySet, err = auth.NewKeySet(cfg.JWKSLocation, logger); err != nil {
+			logger.Errorf("failed to load JWKS: %s", err)
+			os.Exit(-1)
+		}
+		go keySet.RefreshEvery(time.Duration(cfg.JWKSRefreshInterval) * time.Minute)
+	}
+	keyFunc, err := auth.NewKeyFunc(keySet, cfg.JWTSigningKey, cfg.JWTVerificationKeyFile)
+	if err != nil {
+		logger.Errorf("failed to load JWT verification keys: %s", err)
+		os.Exit(-1)
+	}
+
 	// connect to the database
 	db, err := dbx.MustOpen("mysql", cfg.DSN)
 	if err != nil {
@@ -55,11 +74,30 @@ func main() {
 		}
 	}()
 
+	dbContext := dbcontext.New(db)
+	clinicClient := clinic.NewClient(newPeerClient(cfg, cfg.ClinicServiceURL, cfg.ClinicServiceTimeout))
+	appointmentService := appointment.NewService(appointment.NewRepository(dbContext, logger), dbContext.Transactional,
+		clinicClient, time.Duration(cfg.MinNoticeHours)*time.Hour, logger)
+
+	// run the room assignment job
+	schedule, err := cron.Parse(cfg.RoomAssignmentSchedule)
+	if err != nil {
+		logger.Error(err)
+		os.Exit(-1)
+	}
+	go cron.Run(context.Background(), schedule, "room assignment", func(ctx context.Context) error {
+		result, err := appointmentService.AssignRooms(ctx, false)
+		if err == nil {
+			logger.Infof("assigned rooms to %d appointments, %d appointments left without a room", len(result.Assignments), len(result.Unassigned))
+		}
+		return err
+	}, logger)
+
 	// build HTTP server
 	address := fmt.Sprintf(":%v", cfg.ServerPort)
 	hs := &http.Server{
 		Addr:    address,
-		Handler: buildHandler(logger, dbcontext.New(db), cfg),
+		Handler: buildHandler(logger, cfg, keyFunc, appointmentService),
 	}
 
 	// start the HTTP server with graceful shutdown
@@ -72,7 +110,7 @@ func main() {
 }
 
 // buildHandler sets up the HTTP routing and builds an HTTP handler.
-func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.Handler {
+func buildHandler(logger log.Logger, cfg *config.Config, keyFunc jwt.Keyfunc, appointmentService appointment.Service) http.Handler {
 	router := routing.New()
 
 	router.Use(
@@ -86,12 +124,9 @@ func buildHandler(logger log.Logger, db *dbcontext.DB, cfg *config.Config) http.
 
 	rg := router.Group("/v1")
 
-	authHandler := auth.Handler(cfg.JWTSigningKey)
+	authHandler := auth.Handler(keyFunc, cfg.JWTIssuer)
 
-	appointment.RegisterHandlers(rg.Group(""),
-		appointment.NewService(appointment.NewRepository(db, logger), logger),
-		authHandler, logger,
-	)
+	appointment.RegisterHandlers(rg.Group(""), appointmentService, authHandler, logger)
 
 	return router
 }
@@ -117,3 +152,14 @@ func logDBExec(logger log.Logger) dbx.ExecLogFunc {
 		}
 	}
 }
+
+// newPeerClient creates a client for the API of another service with the given base URL and timeout in seconds.
+// Every peer gets its own circuit breaker.
+func newPeerClient(cfg *config.Config, baseURL string, timeout int) *httpclient.Client {
+	return httpclient.New(baseURL,
+		httpclient.WithRequestFunc(auth.ForwardToken),
+		httpclient.WithTimeout(time.Duration(timeout)*time.Second),
+		httpclient.WithRetries(cfg.PeerRetries, time.Duration(cfg.PeerRetryBackoff)*time.Millisecond),
+		httpclient.WithCircuitBreaker(cfg.CircuitBreakerThreshold, time.Duration(cfg.CircuitBreakerCooldown)*time.Second),
+	)
+}
@@ -2,22 +2,29 @@ package appointment
 
 import (
 	"net/http"
+	"strings"
 
 	routing "github.com/go-ozzo/ozzo-routing/v2"
 	"github.com/matijapetrovic/clinichub/scheduling-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/entity"
 	"github.com/matijapetrovic/clinichub/scheduling-service/internal/errors"
 	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
 )
 
 func RegisterHandlers(r *routing.RouteGroup, service Service, authHandler routing.Handler, logger log.Logger) {
 	res := resource{service, logger}
-	r.Get("/clinics/<id>/profit", res.getProfit)
 
 	r.Use(authHandler)
 
-	r.Get("/appointments", res.query)
+	r.Get("/clinics/<id>/profit", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.getProfit)
+	r.Get("/appointments", auth.RequireRole(entity.RolePatient), res.query)
 	r.Get("/doctors/<id>/appointments", res.getDoctorAppointments)
-	r.Post("/appointments", res.schedule)
+	r.Get("/doctors/busy-times", res.getBusyTimes)
+	r.Post("/appointments", auth.RequireRole(entity.RolePatient), res.schedule)
+	r.Put("/appointments/<id>", auth.RequireRole(entity.RolePatient, entity.RoleAdmin, entity.RoleClinicAdmin), res.reschedule)
+	r.Delete("/appointments/<id>", auth.RequireRole(entity.RolePatient, entity.RoleAdmin, entity.RoleClinicAdmin), res.cancel)
+	r.Put("/appointments/<id>/status", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.changeStatus)
+	r.Get("/room-assignments/preview", auth.RequireRole(entity.RoleAdmin, entity.RoleClinicAdmin), res.previewRoomAssignments)
 }
 
 type resource struct {
@@ -26,6 +33,9 @@ type resource struct {
 }
 
 func (r resource) getProfit(c *routing.Context) error {
+	if !auth.CanManageClinic(c.Request.Context(), c.Param("id")) {
+		return errors.Forbidden("")
+	}
 	startDate := c.Request.URL.Query().Get("startDate")
 	endDate := c.Request.URL.Query().Get("endDate")
 
@@ -46,10 +56,12 @@ func (r resource) query(c *routing.Context) error {
 	user := auth.CurrentUser(c.Request.Context())
 	startDate := c.Request.URL.Query().Get("startDate")
 	endDate := c.Request.URL.Query().Get("endDate")
-	appointments, err := r.service.GetPatientAppointments(c.Request, GetPatientAppointmentsRequest{
+	appointments, err := r.service.GetPatientAppointments(c.Request.Context(), GetPatientAppointmentsRequest{
 		PatientId: user.GetID(),
 		StartDate: startDate,
 		EndDate:   endDate,
+		Status:    c.Query("status"),
+		TimeZone:  c.Query("timeZone"),
 	})
 	if err != nil {
 		return err
@@ -84,15 +96,80 @@ func (r resource) schedule(c *routing.Context) error {
 		r.logger.With(c.Request.Context()).Info(err)
 		return errors.BadRequest("")
 	}
-	appointment, err := r.service.ScheduleAppointment(c.Request, request)
+	appointment, err := r.service.ScheduleAppointment(c.Request.Context(), request)
 	if err != nil {
-		if err.Error() == "conflict" {
-			return c.WriteWithStatus(err.Error(), http.StatusConflict)
-		} else if err.Error() == "unexpected" {
-			return c.WriteWithStatus(err.Error(), http.StatusInternalServerError)
-		}
 		return err
 	}
 
 	return c.WriteWithStatus(appointment, http.StatusCreated)
 }
+
+func (r resource) reschedule(c *routing.Context) error {
+	var request RescheduleAppointmentRequest
+	if err := c.Read(&request); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest("")
+	}
+	appointment, err := r.service.RescheduleAppointment(c.Request.Context(), c.Param("id"), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(appointment)
+}
+
+func (r resource) cancel(c *routing.Context) error {
+	appointment, err := r.service.CancelAppointment(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return err
+	}
+
+	return c.Write(appointment)
+}
+
+func (r resource) changeStatus(c *routing.Context) error {
+	var request ChangeStatusRequest
+	if err := c.Read(&request); err != nil {
+		r.logger.With(c.Request.Context()).Info(err)
+		return errors.BadRequest("")
+	}
+	appointment, err := r.service.ChangeStatus(c.Request.Context(), c.Param("id"), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(appointment)
+}
+
+func (r resource) previewRoomAssignments(c *routing.Context) error {
+	result, err := r.service.AssignRooms(c.Request.Context(), true)
+	if err != nil {
+		return err
+	}
+
+	return c.Write(result)
+}
+
+func (r resource) getBusyTimes(c *routing.Context) error {
+	busyTimes, err := r.service.GetBusyTimes(c.Request.Context(), GetBusyTimesRequest{
+		DoctorIds: splitIds(c.Query("doctorIds")),
+		Start:     c.Query("start"),
+		End:       c.Query("end"),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Write(busyTimes)
+}
+
+// splitIds returns the non-empty IDs in the given comma-separated list.
+func splitIds(ids string) []string {
+	result := []string{}
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			result = append(result, id)
+		}
+	}
+	return result
+}
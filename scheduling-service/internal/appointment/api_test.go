@@ -0,0 +1,191 @@
+package appointment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/test"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
+)
+
+// mockService answers every call with empty results, so that the tests can check which requests reach it.
+type mockService struct{}
+
+func (s mockService) ScheduleAppointment(ctx context.Context, req ScheduleAppointmentRequest) (entity.Appointment, error) {
+	return entity.Appointment{}, nil
+}
+
+func (s mockService) CancelAppointment(ctx context.Context, id string) (entity.Appointment, error) {
+	return entity.Appointment{}, nil
+}
+
+func (s mockService) ChangeStatus(ctx context.Context, id string, req ChangeStatusRequest) (entity.Appointment, error) {
+	return entity.Appointment{}, nil
+}
+
+func (s mockService) RescheduleAppointment(ctx context.Context, id string, req RescheduleAppointmentRequest) (entity.Appointment, error) {
+	return entity.Appointment{}, nil
+}
+
+func (s mockService) GetDoctorAppointments(ctx context.Context, req GetDoctorAppointmentsRequest) ([]entity.Appointment, error) {
+	return []entity.Appointment{}, nil
+}
+
+func (s mockService) GetBusyTimes(ctx context.Context, req GetBusyTimesRequest) (map[string][]BusyTime, error) {
+	return map[string][]BusyTime{}, nil
+}
+
+func (s mockService) GetPatientAppointments(ctx context.Context, req GetPatientAppointmentsRequest) ([]entity.Appointment, error) {
+	return []entity.Appointment{}, nil
+}
+
+func (s mockService) GetClinicProfit(ctx context.Context, req GetClinicReportRequest) (int, error) {
+	return 0, nil
+}
+
+func (s mockService) AssignRooms(ctx context.Context, dryRun bool) (RoomAssignmentResult, error) {
+	return RoomAssignmentResult{}, nil
+}
+
+func TestAPI_roles(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), mockService{}, test.MockAuthHandler(), logger)
+
+	const (
+		ok        = http.StatusOK
+		created   = http.StatusCreated
+		forbidden = http.StatusForbidden
+		anonymous = http.StatusUnauthorized
+	)
+	test.Routes(t, router, []test.RouteTestCase{
+		{Method: "GET", URL: "/clinics/" + test.ClinicId + "/profit", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+		{Method: "GET", URL: "/clinics/" + otherClinicId + "/profit", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: forbidden, entity.RolePatient: forbidden}},
+		{Method: "GET", URL: "/appointments", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: forbidden, entity.RoleClinicAdmin: forbidden, entity.RolePatient: ok}},
+		{Method: "GET", URL: "/doctors/1/appointments", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: ok}},
+		{Method: "GET", URL: "/doctors/busy-times", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: ok}},
+		{Method: "POST", URL: "/appointments", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: forbidden, entity.RoleClinicAdmin: forbidden, entity.RolePatient: created}},
+		{Method: "PUT", URL: "/appointments/1", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: ok}},
+		{Method: "DELETE", URL: "/appointments/1", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: ok}},
+		{Method: "PUT", URL: "/appointments/1/status", Body: "{}", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+		{Method: "GET", URL: "/room-assignments/preview", WantStatus: map[string]int{"": anonymous, entity.RoleAdmin: ok, entity.RoleClinicAdmin: ok, entity.RolePatient: forbidden}},
+	})
+}
+
+func TestAPI_query_invalidInput(t *testing.T) {
+	logger, _ := log.NewForTest()
+	router := test.MockRouter(logger)
+	service := NewService(fakeRepository{}, nil, fakeClinicClient{}, 0, logger)
+	RegisterHandlers(router.Group(""), service, test.MockAuthHandler(), logger)
+
+	for _, query := range []string{
+		"startDate=2021",
+		"startDate=2021-09",
+		"endDate=2021-13-01",
+		"startDate=2021-09-10&endDate=2021-09-09",
+		"status=unknown",
+		"timeZone=Mars/Olympus_Mons",
+	} {
+		test.Endpoint(t, router, test.APITestCase{
+			Name:       query,
+			Method:     "GET",
+			URL:        "/appointments?" + query,
+			Header:     test.MockAuthHeader(entity.RolePatient),
+			WantStatus: http.StatusBadRequest,
+		})
+	}
+}
+
+// lifecycleRouter serves the appointment API backed by a fakeDB holding the appointments:
+//   - own: an approved appointment of the patient of test.MockAuthHeader at the test clinic, at start
+//   - foreign: an approved appointment of another patient at another clinic, at start
+//   - soon: an approved appointment of the patient starting within the 24 hour notice period
+//   - completed: a completed appointment of the patient
+//   - blocking: an approved appointment of another patient with the same doctor, two hours after start
+func lifecycleRouter(start time.Time) *routing.Router {
+	logger, _ := log.NewForTest()
+	db := newFakeDB(0)
+	appointment := func(id, patientId, clinicId, doctorId string, t time.Time, status string) {
+		db.committed[id] = entity.Appointment{
+			Id:                id,
+			ClinicId:          clinicId,
+			DoctorId:          doctorId,
+			PatientId:         patientId,
+			AppointmentTypeId: "type",
+			Time:              t,
+			Status:            status,
+			Duration:          30,
+		}
+	}
+	const otherPatientId = "00000000-0000-0000-0000-000000000200"
+	appointment("own", testPatientId, test.ClinicId, testDoctorId, start, entity.StatusApproved)
+	appointment("foreign", otherPatientId, otherClinicId, "other-doctor", start, entity.StatusApproved)
+	appointment("soon", testPatientId, test.ClinicId, testDoctorId, time.Now().UTC().Add(2*time.Hour).Truncate(time.Hour), entity.StatusApproved)
+	appointment("completed", testPatientId, test.ClinicId, testDoctorId, start.AddDate(0, 0, -7), entity.StatusCompleted)
+	appointment("blocking", otherPatientId, test.ClinicId, testDoctorId, start.Add(2*time.Hour), entity.StatusApproved)
+
+	router := test.MockRouter(logger)
+	service := NewService(fakeRepository{db: db}, db.Transactional, fakeClinicClient{}, 24*time.Hour, logger)
+	RegisterHandlers(router.Group(""), service, test.MockAuthHandler(), logger)
+	return router
+}
+
+func TestAPI_appointmentLifecycle(t *testing.T) {
+	start := time.Now().UTC().Add(72 * time.Hour).Truncate(time.Hour)
+	moveTo := func(t time.Time) string {
+		body, _ := json.Marshal(RescheduleAppointmentRequest{Time: t})
+		return string(body)
+	}
+	changeTo := func(status string) string {
+		body, _ := json.Marshal(ChangeStatusRequest{Status: status})
+		return string(body)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		body       string
+		role       string
+		wantStatus int
+	}{
+		{"patient cancels own appointment", "DELETE", "/appointments/own", "", entity.RolePatient, http.StatusOK},
+		{"patient cancels appointment of another patient", "DELETE", "/appointments/foreign", "", entity.RolePatient, http.StatusForbidden},
+		{"clinic admin cancels appointment of another clinic", "DELETE", "/appointments/foreign", "", entity.RoleClinicAdmin, http.StatusForbidden},
+		{"admin cancels appointment of any clinic", "DELETE", "/appointments/foreign", "", entity.RoleAdmin, http.StatusOK},
+		{"patient cancels within the notice period", "DELETE", "/appointments/soon", "", entity.RolePatient, http.StatusUnprocessableEntity},
+		{"clinic admin cancels within the notice period", "DELETE", "/appointments/soon", "", entity.RoleClinicAdmin, http.StatusOK},
+		{"admin cancels completed appointment", "DELETE", "/appointments/completed", "", entity.RoleAdmin, http.StatusConflict},
+
+		{"patient moves own appointment", "PUT", "/appointments/own", moveTo(start.Add(4 * time.Hour)), entity.RolePatient, http.StatusOK},
+		{"patient moves appointment of another patient", "PUT", "/appointments/foreign", moveTo(start.Add(4 * time.Hour)), entity.RolePatient, http.StatusForbidden},
+		{"clinic admin moves appointment of another clinic", "PUT", "/appointments/foreign", moveTo(start.Add(4 * time.Hour)), entity.RoleClinicAdmin, http.StatusForbidden},
+		{"patient moves appointment within the notice period", "PUT", "/appointments/soon", moveTo(start.Add(4 * time.Hour)), entity.RolePatient, http.StatusUnprocessableEntity},
+		{"patient moves appointment into the notice period", "PUT", "/appointments/own", moveTo(time.Now().UTC().Add(3 * time.Hour).Truncate(time.Hour)), entity.RolePatient, http.StatusUnprocessableEntity},
+		{"clinic admin moves appointment into the notice period", "PUT", "/appointments/own", moveTo(time.Now().UTC().Add(3 * time.Hour).Truncate(time.Hour)), entity.RoleClinicAdmin, http.StatusOK},
+		{"patient moves appointment off the slots", "PUT", "/appointments/own", moveTo(start.Add(4*time.Hour + 10*time.Minute)), entity.RolePatient, http.StatusUnprocessableEntity},
+		{"patient moves appointment to a taken slot", "PUT", "/appointments/own", moveTo(start.Add(2 * time.Hour)), entity.RolePatient, http.StatusConflict},
+		{"admin moves completed appointment", "PUT", "/appointments/completed", moveTo(start.Add(4 * time.Hour)), entity.RoleAdmin, http.StatusConflict},
+
+		{"clinic admin cancels appointment by status", "PUT", "/appointments/own/status", changeTo(entity.StatusCancelled), entity.RoleClinicAdmin, http.StatusOK},
+		{"clinic admin changes status of appointment of another clinic", "PUT", "/appointments/foreign/status", changeTo(entity.StatusCancelled), entity.RoleClinicAdmin, http.StatusForbidden},
+		{"admin reopens completed appointment", "PUT", "/appointments/completed/status", changeTo(entity.StatusApproved), entity.RoleAdmin, http.StatusConflict},
+		{"admin completes appointment before it starts", "PUT", "/appointments/own/status", changeTo(entity.StatusCompleted), entity.RoleAdmin, http.StatusConflict},
+		{"admin sets unknown status", "PUT", "/appointments/own/status", changeTo("done"), entity.RoleAdmin, http.StatusBadRequest},
+	}
+	for _, tc := range tests {
+		test.Endpoint(t, lifecycleRouter(start), test.APITestCase{
+			Name:       tc.name,
+			Method:     tc.method,
+			URL:        tc.url,
+			Body:       tc.body,
+			Header:     test.MockAuthHeader(tc.role),
+			WantStatus: tc.wantStatus,
+		})
+	}
+}
@@ -0,0 +1,54 @@
+//go:build integration
+// +build integration
+
+package appointment
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/go-ozzo/ozzo-dbx"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/dbcontext"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
+)
+
+// TestAPI_schedule_concurrentBookingsMySQL books the same slot concurrently against the migrated database
+// found at APP_DSN, e.g. with `make test-integration`.
+func TestAPI_schedule_concurrentBookingsMySQL(t *testing.T) {
+	const bookings = 20
+	dsn := os.Getenv("APP_DSN")
+	if dsn == "" {
+		t.Skip("APP_DSN is not set")
+	}
+	db, err := dbx.MustOpen("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	doctorId := entity.GenerateID()
+	defer func() {
+		if _, err := db.Delete("appointment", dbx.HashExp{"doctor_id": doctorId}).Execute(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	logger, _ := log.NewForTest()
+	dbContext := dbcontext.New(db)
+	service := NewService(NewRepository(dbContext, logger), dbContext.Transactional, fakeClinicClient{}, 0, logger)
+
+	counts := bookConcurrently(service, logger, doctorId, bookings)
+	if counts[http.StatusCreated] != 1 || counts[http.StatusConflict] != bookings-1 {
+		t.Errorf("statuses = %v, want one %d and %d times %d", counts, http.StatusCreated, bookings-1, http.StatusConflict)
+	}
+	var booked int
+	if err := db.Select("COUNT(*)").From("appointment").Where(dbx.HashExp{"doctor_id": doctorId}).Row(&booked); err != nil {
+		t.Fatal(err)
+	}
+	if booked != 1 {
+		t.Errorf("%d appointments booked, want 1", booked)
+	}
+}
@@ -0,0 +1,260 @@
+package appointment
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/client/clinic"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/test"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
+)
+
+const testDoctorId = "00000000-0000-0000-0000-000000000010"
+
+type txKey struct{}
+
+// fakeDB keeps appointments in memory and emulates the InnoDB locking the booking relies on.
+// LockDoctorAppointments takes a gap lock on the doctor's appointments, which does not block the gap locks
+// of other transactions but blocks their inserts. An insert waits for the gap locks of other transactions
+// to be released, unless one of them is waiting itself: the transaction is then rolled back as a deadlock victim,
+// as MySQL does when two transactions lock an empty gap and both insert. Rows inserted by a transaction are
+// locked until it finishes, so locking reads of other transactions wait for it.
+type fakeDB struct {
+	mu       sync.Mutex
+	finished *sync.Cond
+	// committed holds the committed appointments keyed by ID.
+	committed map[string]entity.Appointment
+	// pending holds the appointments inserted by unfinished transactions, keyed by transaction.
+	pending map[int][]entity.Appointment
+	// gapLocks holds the doctors whose appointments are locked, keyed by transaction.
+	gapLocks map[int]string
+	// waiting holds the transactions whose inserts wait for gap locks.
+	waiting map[int]bool
+	// lockGate is the number of gap locks the first locking reads wait for, so that the bookings race for the gap.
+	lockGate int
+	locks    int
+	nextTx   int
+}
+
+func newFakeDB(lockGate int) *fakeDB {
+	db := &fakeDB{
+		committed: make(map[string]entity.Appointment),
+		pending:   make(map[int][]entity.Appointment),
+		gapLocks:  make(map[int]string),
+		waiting:   make(map[int]bool),
+		lockGate:  lockGate,
+	}
+	db.finished = sync.NewCond(&db.mu)
+	return db
+}
+
+func (db *fakeDB) Transactional(ctx context.Context, f func(ctx context.Context) error) error {
+	db.mu.Lock()
+	db.nextTx++
+	tx := db.nextTx
+	db.mu.Unlock()
+
+	err := f(context.WithValue(ctx, txKey{}, tx))
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if err == nil {
+		for _, appointment := range db.pending[tx] {
+			db.committed[appointment.Id] = appointment
+		}
+	}
+	delete(db.pending, tx)
+	delete(db.gapLocks, tx)
+	delete(db.waiting, tx)
+	db.finished.Broadcast()
+	return err
+}
+
+// fakeRepository is the appointment repository backed by a fakeDB.
+type fakeRepository struct {
+	Repository
+	db *fakeDB
+}
+
+func (r fakeRepository) LockDoctorAppointments(ctx context.Context, doctorId string, start time.Time, end time.Time) ([]entity.Appointment, error) {
+	tx := ctx.Value(txKey{}).(int)
+	db := r.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for r.insertedByOther(tx, doctorId) {
+		db.finished.Wait()
+	}
+	db.gapLocks[tx] = doctorId
+	db.locks++
+	db.finished.Broadcast()
+	for db.locks < db.lockGate {
+		db.finished.Wait()
+	}
+
+	var appointments []entity.Appointment
+	for _, appointment := range db.committed {
+		if appointment.DoctorId == doctorId && appointment.Time.Before(end) && start.Before(appointment.OccupiedUntil()) {
+			appointments = append(appointments, appointment)
+		}
+	}
+	return appointments, nil
+}
+
+// insertedByOther returns whether another transaction inserted an appointment of the doctor.
+func (r fakeRepository) insertedByOther(tx int, doctorId string) bool {
+	for other, appointments := range r.db.pending {
+		for _, appointment := range appointments {
+			if other != tx && appointment.DoctorId == doctorId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lockedByOther returns whether another transaction holds a gap lock on the doctor's appointments.
+func (r fakeRepository) lockedByOther(tx int, doctorId string) bool {
+	for other, locked := range r.db.gapLocks {
+		if other != tx && locked == doctorId {
+			return true
+		}
+	}
+	return false
+}
+
+func (r fakeRepository) Create(ctx context.Context, appointment entity.Appointment) error {
+	tx := ctx.Value(txKey{}).(int)
+	db := r.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for r.lockedByOther(tx, appointment.DoctorId) {
+		for other, doctorId := range db.gapLocks {
+			if other != tx && doctorId == appointment.DoctorId && db.waiting[other] {
+				return &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"}
+			}
+		}
+		db.waiting[tx] = true
+		db.finished.Wait()
+	}
+	delete(db.waiting, tx)
+	for _, other := range db.committed {
+		if other.DoctorId == appointment.DoctorId && other.Time.Equal(appointment.Time) {
+			return &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+		}
+	}
+	db.pending[tx] = append(db.pending[tx], appointment)
+	return nil
+}
+
+func (r fakeRepository) Update(ctx context.Context, appointment entity.Appointment) error {
+	tx := ctx.Value(txKey{}).(int)
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.pending[tx] = append(r.db.pending[tx], appointment)
+	return nil
+}
+
+func (r fakeRepository) GetById(ctx context.Context, id string) (entity.Appointment, error) {
+	tx := ctx.Value(txKey{}).(int)
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for _, appointment := range r.db.pending[tx] {
+		if appointment.Id == id {
+			return appointment, nil
+		}
+	}
+	return r.db.committed[id], nil
+}
+
+// fakeClinicClient returns a doctor working around the clock with 30 minute appointments.
+type fakeClinicClient struct {
+	clinic.Client
+}
+
+func (c fakeClinicClient) GetDoctor(ctx context.Context, doctorId string) (clinic.Doctor, error) {
+	return clinic.Doctor{
+		Id:              doctorId,
+		ClinicId:        test.ClinicId,
+		AppointmentType: clinic.AppointmentType{Id: "type", Duration: 30},
+		TimeZone:        entity.DefaultTimeZone,
+	}, nil
+}
+
+func (c fakeClinicClient) GetDoctorSchedule(ctx context.Context, doctorId string, startDate string, endDate string) (entity.Schedule, error) {
+	return entity.Schedule{Shifts: entity.DefaultShifts(doctorId, "00:00", "00:00")}, nil
+}
+
+// bookConcurrently sends the given number of simultaneous requests booking the same slot of the doctor
+// and returns how many times each status was answered.
+func bookConcurrently(service Service, logger log.Logger, doctorId string, bookings int) map[int]int {
+	router := test.MockRouter(logger)
+	RegisterHandlers(router.Group(""), service, test.MockAuthHandler(), logger)
+
+	slot := time.Now().UTC().AddDate(0, 0, 1).Truncate(time.Hour)
+	body, _ := json.Marshal(ScheduleAppointmentRequest{DoctorId: doctorId, Time: slot})
+
+	statuses := make(chan int, bookings)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < bookings; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			req, _ := http.NewRequest(http.MethodPost, "/appointments", strings.NewReader(string(body)))
+			req.Header = test.MockAuthHeader(entity.RolePatient)
+			req.Header.Set("Content-Type", "application/json")
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			statuses <- res.Code
+		}()
+	}
+	close(start)
+	wg.Wait()
+	close(statuses)
+
+	counts := make(map[int]int)
+	for status := range statuses {
+		counts[status]++
+	}
+	return counts
+}
+
+func TestAPI_schedule_concurrentBookings(t *testing.T) {
+	const bookings = 20
+	logger, _ := log.NewForTest()
+	db := newFakeDB(bookings)
+	service := NewService(fakeRepository{db: db}, db.Transactional, fakeClinicClient{}, 0, logger)
+
+	counts := bookConcurrently(service, logger, testDoctorId, bookings)
+	if counts[http.StatusCreated] != 1 || counts[http.StatusConflict] != bookings-1 {
+		t.Errorf("statuses = %v, want one %d and %d times %d", counts, http.StatusCreated, bookings-1, http.StatusConflict)
+	}
+	if len(db.committed) != 1 {
+		t.Errorf("%d appointments booked, want 1", len(db.committed))
+	}
+}
+
+// lockConflictTransactional runs no transaction and fails as if it always lost a lock conflict.
+func lockConflictTransactional(ctx context.Context, f func(ctx context.Context) error) error {
+	return &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"}
+}
+
+func TestAPI_schedule_lockConflictsExhausted(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := newFakeDB(0)
+	service := NewService(fakeRepository{db: db}, lockConflictTransactional, fakeClinicClient{}, 0, logger)
+
+	counts := bookConcurrently(service, logger, testDoctorId, 1)
+	if counts[http.StatusServiceUnavailable] != 1 {
+		t.Errorf("statuses = %v, want %d so that the booking can be retried", counts, http.StatusServiceUnavailable)
+	}
+}
@@ -12,10 +12,30 @@ import (
 
 type Repository interface {
 	Create(ctx context.Context, appointment entity.Appointment) error
+	Update(ctx context.Context, appointment entity.Appointment) error
 	GetById(ctx context.Context, id string) (entity.Appointment, error)
-	GetDoctorAppointments(ctx context.Context, doctorId string, dateStart time.Time, dateEnd time.Time) ([]entity.Appointment, error)
-	GetByDoctorIdAndTime(ctx context.Context, doctorId string, time time.Time) (entity.Appointment, error)
-	GetByPatientIdAndDate(ctx context.Context, patientId string, startDate time.Time, endDate time.Time) ([]entity.Appointment, error)
+	// GetDoctorAppointments returns the doctor's appointments keeping the doctor busy at some point between start and end.
+	GetDoctorAppointments(ctx context.Context, doctorId string, start time.Time, end time.Time) ([]entity.Appointment, error)
+	// GetByDoctorIds returns the appointments of the doctors keeping them busy at some point between start and end.
+	GetByDoctorIds(ctx context.Context, doctorIds []string, start time.Time, end time.Time) ([]entity.Appointment, error)
+	// LockDoctorAppointments works like GetDoctorAppointments but also locks the doctor's appointments starting
+	// before end until the transaction finishes, so that overlapping appointments cannot be booked concurrently.
+	// It must be called within a transaction.
+	LockDoctorAppointments(ctx context.Context, doctorId string, start time.Time, end time.Time) ([]entity.Appointment, error)
+	// LockRoomAppointments returns the active appointments occupying the room at some point between start and end
+	// and locks the room's appointments starting before end until the transaction finishes, so that the room cannot
+	// be given to overlapping appointments concurrently. It must be called within a transaction.
+	LockRoomAppointments(ctx context.Context, roomId string, start time.Time, end time.Time) ([]entity.Appointment, error)
+	// GetByPatientIdAndDate returns the patient's appointments starting at or after startDate and before endDate
+	// having the given status. Zero dates and an empty status are not used for filtering.
+	GetByPatientIdAndDate(ctx context.Context, patientId string, startDate time.Time, endDate time.Time, status string) ([]entity.Appointment, error)
+	// GetRequestedWithoutRoom returns the requested appointments starting at or after the given time
+	// that have no room assigned yet, earliest first.
+	GetRequestedWithoutRoom(ctx context.Context, from time.Time) ([]entity.Appointment, error)
+	// GetWithRoom returns the appointments occupying a room at or after the given time.
+	GetWithRoom(ctx context.Context, from time.Time) ([]entity.Appointment, error)
+	// GetClinicProfit returns the sum of the prices of the clinic's appointments starting at or after startDate and before endDate.
+	// Rejected and cancelled appointments are not counted.
 	GetClinicProfit(ctx context.Context, clinicId string, startDate time.Time, endDate time.Time) (int, error)
 }
 
@@ -32,33 +52,46 @@ func (r repository) GetClinicProfit(ctx context.Context, clinicId string, startD
 	var profit int
 	dbExp := dbx.NewExp("clinic_id={:clinicId}", dbx.Params{"clinicId": clinicId})
 	dbExp = dbx.And(dbExp, dbx.NewExp("time>={:startDate}", dbx.Params{"startDate": startDate}))
-	dbExp = dbx.And(dbExp, dbx.NewExp("time<={:endDate}", dbx.Params{"endDate": endDate}))
+	dbExp = dbx.And(dbExp, dbx.NewExp("time<{:endDate}", dbx.Params{"endDate": endDate}), activeExp())
 
-	err := r.db.With(ctx).Select("SUM(price) AS profit").From("appointment").Where(dbExp).Row(&profit)
+	err := r.db.With(ctx).Select("COALESCE(SUM(price), 0) AS profit").From("appointment").Where(dbExp).Row(&profit)
 	return profit, err
 }
 
+func (r repository) GetRequestedWithoutRoom(ctx context.Context, from time.Time) ([]entity.Appointment, error) {
+	var appointments []entity.Appointment
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.And(dbx.HashExp{"status": entity.StatusRequested, "room_id": ""}, dbx.NewExp("time>={:from}", dbx.Params{"from": from}))).
+		OrderBy("time", "id").
+		All(&appointments)
+	return appointments, err
+}
+
+func (r repository) GetWithRoom(ctx context.Context, from time.Time) ([]entity.Appointment, error) {
+	var appointments []entity.Appointment
+	err := r.db.With(ctx).
+		Select().
+		Where(dbx.And(dbx.NewExp("room_id<>''"), occupiedAfterExp(from), activeExp())).
+		All(&appointments)
+	return appointments, err
+}
+
 func (r repository) Create(ctx context.Context, appointment entity.Appointment) error {
 	return r.db.With(ctx).Model(&appointment).Insert()
 }
 
-func (r repository) GetById(ctx context.Context, id string) (entity.Appointment, error) {
-	var appointment entity.Appointment
-	err := r.db.With(ctx).Select().Model(id, &appointment)
-	return appointment, err
+func (r repository) Update(ctx context.Context, appointment entity.Appointment) error {
+	return r.db.With(ctx).Model(&appointment).Update()
 }
 
-func (r repository) GetByDoctorIdAndTime(ctx context.Context, doctorId string, time time.Time) (entity.Appointment, error) {
+func (r repository) GetById(ctx context.Context, id string) (entity.Appointment, error) {
 	var appointment entity.Appointment
-	err := r.db.With(ctx).
-		Select().
-		Where(dbx.HashExp{"doctor_id": doctorId, "time": time}).
-		One(&appointment)
-
+	err := r.db.With(ctx).Select().Model(id, &appointment)
 	return appointment, err
 }
 
-func (r repository) GetByPatientIdAndDate(ctx context.Context, patientId string, startDate time.Time, endDate time.Time) ([]entity.Appointment, error) {
+func (r repository) GetByPatientIdAndDate(ctx context.Context, patientId string, startDate time.Time, endDate time.Time, status string) ([]entity.Appointment, error) {
 	var appointments []entity.Appointment
 
 	dbExp := dbx.NewExp("patient_id={:patientId}", dbx.Params{"patientId": patientId})
@@ -66,7 +99,10 @@ func (r repository) GetByPatientIdAndDate(ctx context.Context, patientId string,
 		dbExp = dbx.And(dbExp, dbx.NewExp("time>={:startDate}", dbx.Params{"startDate": startDate}))
 	}
 	if !endDate.IsZero() {
-		dbExp = dbx.And(dbExp, dbx.NewExp("time<={:endDate}", dbx.Params{"endDate": endDate}))
+		dbExp = dbx.And(dbExp, dbx.NewExp("time<{:endDate}", dbx.Params{"endDate": endDate}))
+	}
+	if status != "" {
+		dbExp = dbx.And(dbExp, dbx.HashExp{"status": status})
 	}
 
 	err := r.db.With(ctx).
@@ -77,11 +113,73 @@ func (r repository) GetByPatientIdAndDate(ctx context.Context, patientId string,
 	return appointments, err
 }
 
-func (r repository) GetDoctorAppointments(ctx context.Context, doctorId string, dateStart time.Time, dateEnd time.Time) ([]entity.Appointment, error) {
+func (r repository) GetDoctorAppointments(ctx context.Context, doctorId string, start time.Time, end time.Time) ([]entity.Appointment, error) {
+	var appointments []entity.Appointment
+	err := r.db.With(ctx).
+		Select().
+		Where(doctorAppointmentsExp(doctorId, start, end)).
+		OrderBy("time").
+		All(&appointments)
+	return appointments, err
+}
+
+func (r repository) GetByDoctorIds(ctx context.Context, doctorIds []string, start time.Time, end time.Time) ([]entity.Appointment, error) {
+	ids := make([]interface{}, len(doctorIds))
+	for i, id := range doctorIds {
+		ids[i] = id
+	}
 	var appointments []entity.Appointment
 	err := r.db.With(ctx).
 		Select().
-		Where(dbx.And(dbx.HashExp{"doctor_id": doctorId}, dbx.Between("time", dateStart, dateEnd))).
+		Where(dbx.And(dbx.In("doctor_id", ids...), busyBetweenExp(start, end))).
+		OrderBy("time").
 		All(&appointments)
 	return appointments, err
 }
+
+func (r repository) LockDoctorAppointments(ctx context.Context, doctorId string, start time.Time, end time.Time) ([]entity.Appointment, error) {
+	return r.lockAppointments(ctx, doctorAppointmentsExp(doctorId, start, end))
+}
+
+func (r repository) LockRoomAppointments(ctx context.Context, roomId string, start time.Time, end time.Time) ([]entity.Appointment, error) {
+	return r.lockAppointments(ctx, dbx.And(dbx.HashExp{"room_id": roomId}, busyBetweenExp(start, end)))
+}
+
+// lockAppointments returns the appointments matching the expression, locking the scanned index range
+// until the transaction finishes.
+func (r repository) lockAppointments(ctx context.Context, exp dbx.Expression) ([]entity.Appointment, error) {
+	var appointments []entity.Appointment
+	q := r.db.With(ctx).
+		Select().
+		From("appointment").
+		Where(exp).
+		OrderBy("time").
+		Build()
+	// ozzo-dbx cannot add a locking clause to a select query
+	err := r.db.With(ctx).NewQuery(q.SQL() + " FOR UPDATE").Bind(q.Params()).All(&appointments)
+	return appointments, err
+}
+
+// doctorAppointmentsExp matches the doctor's active appointments keeping the doctor busy at some point between start and end.
+func doctorAppointmentsExp(doctorId string, start time.Time, end time.Time) dbx.Expression {
+	return dbx.And(dbx.HashExp{"doctor_id": doctorId}, busyBetweenExp(start, end))
+}
+
+// busyBetweenExp matches the active appointments keeping the doctor busy at some point between start and end.
+func busyBetweenExp(start time.Time, end time.Time) dbx.Expression {
+	return dbx.And(dbx.NewExp("time<{:end}", dbx.Params{"end": end}), occupiedAfterExp(start), activeExp())
+}
+
+// occupiedAfterExp matches the appointments that, including the buffer, end after the given time.
+func occupiedAfterExp(t time.Time) dbx.Expression {
+	return dbx.NewExp("time + INTERVAL (duration + buffer) MINUTE>{:occupiedAfter}", dbx.Params{"occupiedAfter": t})
+}
+
+// activeExp matches the appointments that occupy their slot.
+func activeExp() dbx.Expression {
+	statuses := make([]interface{}, len(entity.InactiveStatuses))
+	for i, status := range entity.InactiveStatuses {
+		statuses[i] = status
+	}
+	return dbx.NotIn("status", statuses...)
+}
@@ -0,0 +1,204 @@
+package appointment
+
+import (
+	"context"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/client/clinic"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/dbcontext"
+)
+
+// RoomAssignment describes a room assigned to a requested appointment.
+type RoomAssignment struct {
+	AppointmentId string    `json:"appointmentId"`
+	ClinicId      string    `json:"clinicId"`
+	DoctorId      string    `json:"doctorId"`
+	Time          time.Time `json:"time"`
+	RoomId        string    `json:"roomId"`
+	RoomName      string    `json:"roomName"`
+}
+
+// RoomAssignmentResult lists the rooms assigned to requested appointments and the IDs of
+// the requested appointments for which no free room was found.
+type RoomAssignmentResult struct {
+	Assignments []RoomAssignment `json:"assignments"`
+	Unassigned  []string         `json:"unassigned"`
+}
+
+// AssignRooms assigns free rooms supporting the appointment type to the upcoming requested appointments
+// and approves them. Appointments are handled earliest first and a room is never given to two appointments
+// at overlapping times. With dryRun set, the assignments are only computed and nothing is changed; such a preview
+// only covers the clinics the current user manages. As rooms belong to a single clinic, the previewed assignments
+// are the same as in a run covering every clinic.
+func (s service) AssignRooms(ctx context.Context, dryRun bool) (RoomAssignmentResult, error) {
+	result := RoomAssignmentResult{Assignments: []RoomAssignment{}, Unassigned: []string{}}
+	now := time.Now()
+	requested, err := s.repo.GetRequestedWithoutRoom(ctx, now)
+	if err != nil {
+		return result, err
+	}
+	if dryRun {
+		requested = managedBy(ctx, requested)
+	}
+	if len(requested) == 0 {
+		return result, nil
+	}
+
+	assigned, err := s.repo.GetWithRoom(ctx, now)
+	if err != nil {
+		return result, err
+	}
+	occupied := make(map[string][]entity.Appointment)
+	for _, appointment := range assigned {
+		occupied[appointment.RoomId] = append(occupied[appointment.RoomId], appointment)
+	}
+
+	rooms := make(map[string][]clinic.Room)
+	for _, appointment := range requested {
+		clinicRooms, ok := rooms[appointment.ClinicId]
+		if !ok {
+			if clinicRooms, err = s.clinicClient.GetRooms(ctx, appointment.ClinicId); err != nil {
+				return result, err
+			}
+			rooms[appointment.ClinicId] = clinicRooms
+		}
+
+		room, ok := findFreeRoom(clinicRooms, appointment, occupied)
+		if !ok {
+			result.Unassigned = append(result.Unassigned, appointment.Id)
+			continue
+		}
+		occupied[room.Id] = append(occupied[room.Id], appointment)
+		result.Assignments = append(result.Assignments, RoomAssignment{
+			AppointmentId: appointment.Id,
+			ClinicId:      appointment.ClinicId,
+			DoctorId:      appointment.DoctorId,
+			Time:          appointment.Time,
+			RoomId:        room.Id,
+			RoomName:      room.Name,
+		})
+	}
+
+	if dryRun {
+		return result, nil
+	}
+
+	applied := make([]RoomAssignment, 0, len(result.Assignments))
+	for _, assignment := range result.Assignments {
+		ok, err := s.applyRoomAssignment(ctx, assignment)
+		if err != nil {
+			return result, err
+		}
+		if ok {
+			applied = append(applied, assignment)
+		} else {
+			result.Unassigned = append(result.Unassigned, assignment.AppointmentId)
+		}
+	}
+	result.Assignments = applied
+	return result, nil
+}
+
+// applyRoomAssignment assigns the room and approves the appointment if it is still waiting for a room.
+// False is returned if the appointment changed in the meantime or the room has been taken.
+func (s service) applyRoomAssignment(ctx context.Context, assignment RoomAssignment) (bool, error) {
+	applied := false
+	err := s.transactional(ctx, func(ctx context.Context) error {
+		appointment, err := s.repo.GetById(ctx, assignment.AppointmentId)
+		if err != nil {
+			return err
+		}
+		if appointment.Status != entity.StatusRequested || appointment.RoomId != "" || !appointment.Time.Equal(assignment.Time) {
+			return nil
+		}
+		if free, err := s.lockRoom(ctx, assignment.RoomId, appointment); err != nil {
+			return err
+		} else if !free {
+			s.logger.With(ctx).Infof("room %s was taken before it could be assigned to appointment %s", assignment.RoomId, appointment.Id)
+			return nil
+		}
+
+		appointment.RoomId = assignment.RoomId
+		appointment.Status = entity.StatusApproved
+		err = s.repo.Update(ctx, appointment)
+		if dbcontext.IsUniqueViolation(err) {
+			s.logger.With(ctx).Infof("room %s was taken before it could be assigned to appointment %s", assignment.RoomId, appointment.Id)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied, err
+}
+
+// lockFreeRoom returns the ID of the first room of the clinic supporting the appointment type that is free during
+// the appointment, keeping the room's appointments locked until the transaction finishes.
+// errNoFreeRoom is returned if no such room is free.
+func (s service) lockFreeRoom(ctx context.Context, appointment entity.Appointment) (string, error) {
+	rooms, err := s.clinicClient.GetRooms(ctx, appointment.ClinicId)
+	if err != nil {
+		return "", err
+	}
+	for _, room := range rooms {
+		if !room.Supports(appointment.AppointmentTypeId) {
+			continue
+		}
+		if free, err := s.lockRoom(ctx, room.Id, appointment); err != nil {
+			return "", err
+		} else if free {
+			return room.Id, nil
+		}
+	}
+	return "", errNoFreeRoom
+}
+
+// lockRoom locks the appointments of the room until the transaction finishes and returns whether
+// no other appointment occupies the room during the given one.
+func (s service) lockRoom(ctx context.Context, roomId string, appointment entity.Appointment) (bool, error) {
+	appointments, err := s.repo.LockRoomAppointments(ctx, roomId, appointment.Time, appointment.OccupiedUntil())
+	if err != nil {
+		return false, err
+	}
+	for _, other := range appointments {
+		if other.Id != appointment.Id {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// managedBy returns the appointments taking place in the clinics the current user manages.
+func managedBy(ctx context.Context, appointments []entity.Appointment) []entity.Appointment {
+	managed := make([]entity.Appointment, 0, len(appointments))
+	for _, appointment := range appointments {
+		if auth.CanManageClinic(ctx, appointment.ClinicId) {
+			managed = append(managed, appointment)
+		}
+	}
+	return managed
+}
+
+// findFreeRoom returns the first room supporting the appointment type that is free during the appointment.
+// occupied holds the appointments taking place in each room.
+func findFreeRoom(rooms []clinic.Room, appointment entity.Appointment, occupied map[string][]entity.Appointment) (clinic.Room, bool) {
+	for _, room := range rooms {
+		if room.Supports(appointment.AppointmentTypeId) && !overlapsAny(occupied[room.Id], appointment) {
+			return room, true
+		}
+	}
+	return clinic.Room{}, false
+}
+
+// overlapsAny returns whether any of the appointments overlaps the given one.
+func overlapsAny(appointments []entity.Appointment, appointment entity.Appointment) bool {
+	for _, other := range appointments {
+		if other.Overlaps(appointment.Time, appointment.OccupiedUntil()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package appointment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/client/clinic"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/test"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
+)
+
+const otherClinicId = "00000000-0000-0000-0000-000000000002"
+
+// roomRepository returns the given appointments as waiting for a room, while no room is occupied yet.
+type roomRepository struct {
+	Repository
+	requested []entity.Appointment
+}
+
+func (r roomRepository) GetRequestedWithoutRoom(ctx context.Context, from time.Time) ([]entity.Appointment, error) {
+	return r.requested, nil
+}
+
+func (r roomRepository) GetWithRoom(ctx context.Context, from time.Time) ([]entity.Appointment, error) {
+	return nil, nil
+}
+
+// roomClinicClient returns a single room for every clinic.
+type roomClinicClient struct {
+	clinic.Client
+}
+
+func (c roomClinicClient) GetRooms(ctx context.Context, clinicId string) ([]clinic.Room, error) {
+	return []clinic.Room{{Id: "room-" + clinicId, ClinicId: clinicId, AppointmentTypeIds: []string{"type"}}}, nil
+}
+
+func TestService_AssignRooms_previewOfManagedClinics(t *testing.T) {
+	logger, _ := log.NewForTest()
+	slot := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	repo := roomRepository{requested: []entity.Appointment{
+		{Id: "a1", ClinicId: test.ClinicId, AppointmentTypeId: "type", Time: slot, Status: entity.StatusRequested, Duration: 30},
+		{Id: "a2", ClinicId: otherClinicId, AppointmentTypeId: "type", Time: slot, Status: entity.StatusRequested, Duration: 30},
+	}}
+	s := NewService(repo, nil, roomClinicClient{}, 0, logger)
+
+	tests := []struct {
+		name string
+		role string
+		want []string
+	}{
+		{"admin", entity.RoleAdmin, []string{"a1", "a2"}},
+		{"clinic admin", entity.RoleClinicAdmin, []string{"a1"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := auth.WithUser(context.Background(), "user", tc.role, tc.role, []string{test.ClinicId})
+			result, err := s.AssignRooms(ctx, true)
+			if err != nil {
+				t.Fatalf("AssignRooms() error = %v", err)
+			}
+			var got []string
+			for _, assignment := range result.Assignments {
+				got = append(got, assignment.AppointmentId)
+			}
+			if len(got) != len(tc.want) || len(result.Unassigned) != 0 {
+				t.Fatalf("AssignRooms() = %+v, want assignments of %v", result, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("AssignRooms() assigned %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestService_RescheduleAppointment_releasesRoom(t *testing.T) {
+	logger, _ := log.NewForTest()
+	db := newFakeDB(0)
+	slot := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Hour)
+	db.committed["a1"] = entity.Appointment{
+		Id:                "a1",
+		ClinicId:          test.ClinicId,
+		DoctorId:          testDoctorId,
+		PatientId:         "patient",
+		AppointmentTypeId: "type",
+		Time:              slot,
+		Status:            entity.StatusApproved,
+		RoomId:            "room",
+		Duration:          30,
+	}
+	s := NewService(fakeRepository{db: db}, db.Transactional, fakeClinicClient{}, 0, logger)
+
+	ctx := auth.WithUser(context.Background(), "patient", "patient", entity.RolePatient, nil)
+	appointment, err := s.RescheduleAppointment(ctx, "a1", RescheduleAppointmentRequest{Time: slot.Add(2 * time.Hour)})
+	if err != nil {
+		t.Fatalf("RescheduleAppointment() error = %v", err)
+	}
+	if appointment.RoomId != "" || appointment.Status != entity.StatusRequested {
+		t.Errorf("RescheduleAppointment() = %+v, want a requested appointment without a room", appointment)
+	}
+	if stored := db.committed["a1"]; stored.RoomId != "" || !stored.Time.Equal(slot.Add(2*time.Hour)) {
+		t.Errorf("stored appointment = %+v, want it moved and without a room", stored)
+	}
+}
+
+// lockingRoomRepository keeps appointments in memory. Appointments occupying rooms are only found
+// when the rooms are locked, as if the rooms were given to them after the assignments were planned.
+type lockingRoomRepository struct {
+	roomRepository
+	appointments map[string]entity.Appointment
+}
+
+func (r lockingRoomRepository) GetById(ctx context.Context, id string) (entity.Appointment, error) {
+	return r.appointments[id], nil
+}
+
+func (r lockingRoomRepository) Update(ctx context.Context, appointment entity.Appointment) error {
+	r.appointments[appointment.Id] = appointment
+	return nil
+}
+
+func (r lockingRoomRepository) LockRoomAppointments(ctx context.Context, roomId string, start time.Time, end time.Time) ([]entity.Appointment, error) {
+	var appointments []entity.Appointment
+	for _, appointment := range r.appointments {
+		if appointment.RoomId == roomId && appointment.IsUpcoming() && appointment.Overlaps(start, end) {
+			appointments = append(appointments, appointment)
+		}
+	}
+	return appointments, nil
+}
+
+// newLockingRoomRepository returns a repository with a requested appointment a1 and, if the room is taken,
+// an approved appointment b1 at the same time in the only room of the test clinic.
+func newLockingRoomRepository(slot time.Time, roomTaken bool) lockingRoomRepository {
+	requested := entity.Appointment{Id: "a1", ClinicId: test.ClinicId, AppointmentTypeId: "type", Time: slot, Status: entity.StatusRequested, Duration: 30}
+	repo := lockingRoomRepository{
+		roomRepository: roomRepository{requested: []entity.Appointment{requested}},
+		appointments:   map[string]entity.Appointment{"a1": requested},
+	}
+	if roomTaken {
+		repo.appointments["b1"] = entity.Appointment{Id: "b1", ClinicId: test.ClinicId, AppointmentTypeId: "type", Time: slot, Status: entity.StatusApproved, RoomId: "room-" + test.ClinicId, Duration: 30}
+	}
+	return repo
+}
+
+func TestService_AssignRooms_roomTakenMeanwhile(t *testing.T) {
+	logger, _ := log.NewForTest()
+	repo := newLockingRoomRepository(time.Now().Add(24*time.Hour).Truncate(time.Hour), true)
+	transactional := func(ctx context.Context, f func(ctx context.Context) error) error { return f(ctx) }
+	s := NewService(repo, transactional, roomClinicClient{}, 0, logger)
+
+	result, err := s.AssignRooms(context.Background(), false)
+	if err != nil {
+		t.Fatalf("AssignRooms() error = %v", err)
+	}
+	if len(result.Assignments) != 0 || len(result.Unassigned) != 1 || result.Unassigned[0] != "a1" {
+		t.Errorf("AssignRooms() = %+v, want a1 unassigned", result)
+	}
+	if stored := repo.appointments["a1"]; stored.RoomId != "" || stored.Status != entity.StatusRequested {
+		t.Errorf("stored appointment = %+v, want it still requested without a room", stored)
+	}
+}
+
+func TestService_ChangeStatus_approvalAssignsRoom(t *testing.T) {
+	logger, _ := log.NewForTest()
+	slot := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	transactional := func(ctx context.Context, f func(ctx context.Context) error) error { return f(ctx) }
+	ctx := auth.WithUser(context.Background(), "admin", "admin", entity.RoleClinicAdmin, []string{test.ClinicId})
+
+	tests := []struct {
+		name       string
+		roomTaken  bool
+		wantErr    error
+		wantStatus string
+		wantRoomId string
+	}{
+		{"free room", false, nil, entity.StatusApproved, "room-" + test.ClinicId},
+		{"room taken", true, errNoFreeRoom, entity.StatusRequested, ""},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newLockingRoomRepository(slot, tc.roomTaken)
+			s := NewService(repo, transactional, roomClinicClient{}, 0, logger)
+
+			_, err := s.ChangeStatus(ctx, "a1", ChangeStatusRequest{Status: entity.StatusApproved})
+			if err != tc.wantErr {
+				t.Fatalf("ChangeStatus() error = %v, want %v", err, tc.wantErr)
+			}
+			if stored := repo.appointments["a1"]; stored.Status != tc.wantStatus || stored.RoomId != tc.wantRoomId {
+				t.Errorf("stored appointment = %+v, want status %q and room %q", stored, tc.wantStatus, tc.wantRoomId)
+			}
+		})
+	}
+}
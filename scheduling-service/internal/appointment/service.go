@@ -2,29 +2,31 @@ package appointment
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
-	"errors"
-	"net/http"
-	"net/url"
-	"strconv"
-	"strings"
+	"fmt"
 	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/matijapetrovic/clinichub/scheduling-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/client/clinic"
 	"github.com/matijapetrovic/clinichub/scheduling-service/internal/entity"
-	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/httpclient"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/errors"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/dbcontext"
 	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
 )
 
 type Service interface {
-	ScheduleAppointment(request *http.Request, req ScheduleAppointmentRequest) (entity.Appointment, error)
+	ScheduleAppointment(ctx context.Context, req ScheduleAppointmentRequest) (entity.Appointment, error)
+	CancelAppointment(ctx context.Context, id string) (entity.Appointment, error)
+	ChangeStatus(ctx context.Context, id string, req ChangeStatusRequest) (entity.Appointment, error)
+	RescheduleAppointment(ctx context.Context, id string, req RescheduleAppointmentRequest) (entity.Appointment, error)
 	GetDoctorAppointments(ctx context.Context, req GetDoctorAppointmentsRequest) ([]entity.Appointment, error)
-	GetPatientAppointments(request *http.Request, req GetPatientAppointmentsRequest) ([]entity.Appointment, error)
+	GetBusyTimes(ctx context.Context, req GetBusyTimesRequest) (map[string][]BusyTime, error)
+	GetPatientAppointments(ctx context.Context, req GetPatientAppointmentsRequest) ([]entity.Appointment, error)
 	GetClinicProfit(ctx context.Context, req GetClinicReportRequest) (int, error)
+	AssignRooms(ctx context.Context, dryRun bool) (RoomAssignmentResult, error)
 }
 
+// GetClinicReportRequest represents a clinic report request covering the days from StartDate to EndDate inclusive.
 type GetClinicReportRequest struct {
 	ClinicId  string `json:"clinicId"`
 	StartDate string `json:"startDate"`
@@ -32,8 +34,11 @@ type GetClinicReportRequest struct {
 }
 
 func (m GetClinicReportRequest) Validate() error {
+	startDate, _ := time.Parse(entity.DateLayout, m.StartDate)
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.ClinicId, validation.Required, validation.Length(36, 36)),
+		validation.Field(&m.StartDate, validation.Required, validation.Date(entity.DateLayout)),
+		validation.Field(&m.EndDate, validation.Required, validation.Date(entity.DateLayout).Min(startDate).RangeError("must not be before the start date")),
 	)
 }
 
@@ -49,6 +54,36 @@ func (m ScheduleAppointmentRequest) Validate() error {
 	)
 }
 
+// RescheduleAppointmentRequest represents a request to move an appointment to another time.
+type RescheduleAppointmentRequest struct {
+	Time time.Time `json:"time"`
+}
+
+func (m RescheduleAppointmentRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Time, validation.Required, validation.Min(time.Now())),
+	)
+}
+
+// ChangeStatusRequest represents a request to move an appointment to another status.
+type ChangeStatusRequest struct {
+	Status string `json:"status"`
+}
+
+func (m ChangeStatusRequest) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Status, validation.Required, validation.By(validateStatus)),
+	)
+}
+
+// validateStatus checks that the value is one of the appointment statuses.
+func validateStatus(value interface{}) error {
+	if status, _ := value.(string); status != "" && !entity.IsValidStatus(status) {
+		return validation.NewError("validation_status", "must be a valid appointment status")
+	}
+	return nil
+}
+
 type GetDoctorAppointmentsRequest struct {
 	DoctorId string `json:"doctorId"`
 	Date     string `json:"date"`
@@ -57,66 +92,110 @@ type GetDoctorAppointmentsRequest struct {
 func (m GetDoctorAppointmentsRequest) Validate() error {
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.DoctorId, validation.Required, validation.Length(36, 36)),
-		//		validation.Field(&m.Date, validation.Required, validation.Date("2021-09-04"), validation.Min(time.Now())),
+		validation.Field(&m.Date, validation.Date(entity.DateLayout)),
+	)
+}
+
+// maxBatchSize is the maximum number of doctors whose busy times can be requested at once.
+const maxBatchSize = 100
+
+// GetBusyTimesRequest represents a request for the times at which several doctors are busy between
+// Start and End, given in the RFC 3339 format.
+type GetBusyTimesRequest struct {
+	DoctorIds []string `json:"doctorIds"`
+	Start     string   `json:"start"`
+	End       string   `json:"end"`
+}
+
+func (m GetBusyTimesRequest) Validate() error {
+	start, _ := time.Parse(time.RFC3339, m.Start)
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.DoctorIds, validation.Required, validation.Length(1, maxBatchSize), validation.Each(validation.Length(36, 36))),
+		validation.Field(&m.Start, validation.Required, validation.Date(time.RFC3339)),
+		validation.Field(&m.End, validation.Required, validation.Date(time.RFC3339).Min(start).RangeError("must not be before the start")),
 	)
 }
 
+// BusyTime is a period in which a doctor is busy with an appointment, including the buffer after it.
+type BusyTime struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// GetPatientAppointmentsRequest represents a request for the appointments of a patient, optionally limited
+// to those from StartDate to EndDate and to those having the given status.
 type GetPatientAppointmentsRequest struct {
 	PatientId string `json:"patientId"`
 	StartDate string `json:"startDate"`
 	EndDate   string `json:"endDate"`
+	Status    string `json:"status"`
+	// TimeZone is the IANA time zone in which the dates are given. It defaults to UTC.
+	TimeZone string `json:"timeZone"`
 }
 
 func (m GetPatientAppointmentsRequest) Validate() error {
+	startDate, _ := time.Parse(entity.DateLayout, m.StartDate)
 	return validation.ValidateStruct(&m,
 		validation.Field(&m.PatientId, validation.Required, validation.Length(36, 36)),
-		validation.Field(&m.StartDate, validation.Required, validation.Date("2021-09-04")),
-		validation.Field(&m.EndDate, validation.Required, validation.Date("2021-09-04")),
+		validation.Field(&m.StartDate, validation.Date(entity.DateLayout)),
+		validation.Field(&m.EndDate, validation.Date(entity.DateLayout).Min(startDate).RangeError("must not be before the start date")),
+		validation.Field(&m.Status, validation.By(validateStatus)),
+		validation.Field(&m.TimeZone, validation.By(validateTimeZone)),
 	)
 }
 
-type service struct {
-	repo   Repository
-	logger log.Logger
-}
+// errSlotTaken is returned when the doctor already has an appointment at the requested time.
+var errSlotTaken = errors.Conflict("The doctor already has an appointment at the requested time.")
 
-func NewService(repo Repository, logger log.Logger) Service {
-	return service{repo, logger}
-}
+// errNoFreeRoom is returned when an appointment is approved while no room for it is free.
+var errNoFreeRoom = errors.Conflict("No room of the clinic supporting the appointment type is free during the appointment.")
 
-type Doctor struct {
-	Id                   string `json:"id"`
-	ClinicId             string `json:"clinicId"`
-	FirstName            string `json:"firstName"`
-	LastName             string `json:"lastName"`
-	WorkStart            string `json:"workStart"`
-	WorkEnd              string `json:"workEnd"`
-	AppointmentType      `json:"specialization"`
-	AppointmentTypePrice uint     `json:"specializationPrice"`
-	AvailableHours       []string `json:"availableHours"`
-}
+// errBookingContended is returned when a booking keeps losing lock conflicts with concurrent bookings of the doctor.
+// The slot may still be free, so the booking can be retried.
+var errBookingContended = errors.ServiceUnavailable("The doctor's schedule is being changed by other bookings. Please try again.")
+
+// maxBookingAttempts is the number of times a booking transaction is run when it loses a lock conflict
+// with a concurrent booking of the same doctor.
+const maxBookingAttempts = 3
+
+// bookingRetryDelay is how long a booking transaction waits before running again after losing a lock conflict.
+// The delay grows with every attempt.
+const bookingRetryDelay = 20 * time.Millisecond
 
-type Clinic struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
+type service struct {
+	repo          Repository
+	transactional dbcontext.TransactionFunc
+	clinicClient  clinic.Client
+	minNotice     time.Duration
+	logger        log.Logger
 }
 
-type AppointmentType struct {
-	Id   string `json:"id"`
-	Name string `json:"name"`
+// NewService creates a new appointment service. Patients cannot cancel or move appointments
+// starting within minNotice.
+func NewService(repo Repository, transactional dbcontext.TransactionFunc, clinicClient clinic.Client, minNotice time.Duration, logger log.Logger) Service {
+	return service{repo, transactional, clinicClient, minNotice, logger}
 }
 
 func (s service) GetClinicProfit(ctx context.Context, req GetClinicReportRequest) (int, error) {
-	startDate, err := parseDate(req.StartDate)
+	if err := req.Validate(); err != nil {
+		return -1, err
+	}
+
+	clinic, err := s.clinicClient.GetClinic(ctx, req.ClinicId)
 	if err != nil {
 		return -1, err
 	}
-	endDate, err := parseDate(req.EndDate)
+	location := entity.LoadLocation(clinic.TimeZone)
+	startDate, err := parseDate("startDate", req.StartDate, location)
+	if err != nil {
+		return -1, err
+	}
+	endDate, err := parseDate("endDate", req.EndDate, location)
 	if err != nil {
 		return -1, err
 	}
 
-	profit, err := s.repo.GetClinicProfit(ctx, req.ClinicId, startDate, endDate)
+	profit, err := s.repo.GetClinicProfit(ctx, req.ClinicId, startDate, endDate.AddDate(0, 0, 1))
 	if err != nil {
 		return -1, err
 	}
@@ -124,72 +203,254 @@ func (s service) GetClinicProfit(ctx context.Context, req GetClinicReportRequest
 	return profit, nil
 }
 
-func (s service) ScheduleAppointment(request *http.Request, req ScheduleAppointmentRequest) (entity.Appointment, error) {
-	ctx := request.Context()
+func (s service) ScheduleAppointment(ctx context.Context, req ScheduleAppointmentRequest) (entity.Appointment, error) {
 	if err := req.Validate(); err != nil {
 		return entity.Appointment{}, err
 	}
 
-	doctor, err := getDoctor(request.Header.Get("Authorization"), req.DoctorId)
+	doctor, err := s.clinicClient.GetDoctor(ctx, req.DoctorId)
 	if err != nil {
 		return entity.Appointment{}, err
 	}
-	_, err = s.repo.GetByDoctorIdAndTime(ctx, req.DoctorId, req.Time)
-	if err == nil {
-		return entity.Appointment{}, errors.New("conflict")
-	} else if err != sql.ErrNoRows {
+	if err := s.validateSlot(ctx, doctor, req.Time); err != nil {
 		return entity.Appointment{}, err
 	}
+
 	user := auth.CurrentUser(ctx)
-	id := entity.GenerateID()
-	err = s.repo.Create(ctx, entity.Appointment{
-		Id:                id,
+	appointment := entity.Appointment{
+		Id:                entity.GenerateID(),
 		DoctorId:          req.DoctorId,
 		ClinicId:          doctor.ClinicId,
 		AppointmentTypeId: doctor.AppointmentType.Id,
 		PatientId:         user.GetID(),
 		Price:             int(doctor.AppointmentTypePrice),
-		Time:              req.Time,
-	})
+		Time:              req.Time.UTC(),
+		Status:            entity.StatusRequested,
+		Duration:          doctor.AppointmentType.Duration,
+		Buffer:            doctor.AppointmentType.Buffer,
+	}
+	err = s.book(ctx, func(ctx context.Context) error {
+		if err := s.checkOverlap(ctx, appointment); err != nil {
+			return err
+		}
+		err := s.repo.Create(ctx, appointment)
+		if dbcontext.IsUniqueViolation(err) {
+			// another booking of the same slot was committed after the check above
+			return errSlotTaken
+		} else if err != nil {
+			return err
+		}
 
+		appointment, err = s.repo.GetById(ctx, appointment.Id)
+		return err
+	})
 	if err != nil {
 		return entity.Appointment{}, err
 	}
 
-	return s.repo.GetById(ctx, id)
+	return appointment, nil
 }
 
-func getDoctor(token string, doctorId string) (Doctor, error) {
-	url, err := url.Parse("http://localhost:8081/v1/doctors/" + doctorId)
+// CancelAppointment cancels the appointment, freeing its slot. Patients can only cancel their own appointments
+// and administrators the appointments of the clinics they manage.
+func (s service) CancelAppointment(ctx context.Context, id string) (entity.Appointment, error) {
+	var appointment entity.Appointment
+	err := s.transactional(ctx, func(ctx context.Context) error {
+		var err error
+		if appointment, err = s.getChangeableAppointment(ctx, id); err != nil {
+			return err
+		}
+		if !appointment.CanTransition(entity.StatusCancelled) {
+			return invalidTransition(appointment, entity.StatusCancelled)
+		}
+
+		appointment.Status = entity.StatusCancelled
+		return s.repo.Update(ctx, appointment)
+	})
 	if err != nil {
-		return Doctor{}, err
+		return entity.Appointment{}, err
+	}
+	return appointment, nil
+}
+
+// RescheduleAppointment moves the appointment to another slot of the same doctor.
+// The same ownership and notice rules as for cancellation apply. A moved appointment gives up its room
+// and has to be approved again, once a room free at the new time is assigned to it.
+func (s service) RescheduleAppointment(ctx context.Context, id string, req RescheduleAppointmentRequest) (entity.Appointment, error) {
+	if err := req.Validate(); err != nil {
+		return entity.Appointment{}, err
 	}
 
-	client := httpclient.NewJsonClient(
-		"GET",
-		url,
-		func(ctx context.Context, r *http.Response) (interface{}, error) {
-			var doctor Doctor
-			err := json.NewDecoder(r.Body).Decode(&doctor)
-			if err != nil {
-				return nil, err
+	var appointment entity.Appointment
+	err := s.book(ctx, func(ctx context.Context) error {
+		var err error
+		if appointment, err = s.getChangeableAppointment(ctx, id); err != nil {
+			return err
+		}
+		if !appointment.IsUpcoming() {
+			return errors.Conflict(fmt.Sprintf("An appointment that is %s cannot be rescheduled.", appointment.Status))
+		}
+		if err = s.checkNotice(ctx, req.Time); err != nil {
+			return err
+		}
+
+		doctor, err := s.clinicClient.GetDoctor(ctx, appointment.DoctorId)
+		if err != nil {
+			return err
+		}
+		if err = s.validateSlot(ctx, doctor, req.Time); err != nil {
+			return err
+		}
+
+		appointment.Time = req.Time.UTC()
+		appointment.Status = entity.StatusRequested
+		appointment.RoomId = ""
+		appointment.Duration = doctor.AppointmentType.Duration
+		appointment.Buffer = doctor.AppointmentType.Buffer
+		if err = s.checkOverlap(ctx, appointment); err != nil {
+			return err
+		}
+
+		err = s.repo.Update(ctx, appointment)
+		if dbcontext.IsUniqueViolation(err) {
+			return errSlotTaken
+		} else if err != nil {
+			return err
+		}
+
+		appointment, err = s.repo.GetById(ctx, id)
+		return err
+	})
+	if err != nil {
+		return entity.Appointment{}, err
+	}
+	return appointment, nil
+}
+
+// ChangeStatus moves the appointment to another status, e.g. when an administrator approves it.
+// Only transitions allowed by the appointment lifecycle are accepted, and an appointment can only be marked
+// as completed or as a no-show once it has started. An appointment approved without a room is assigned
+// a free room of the clinic.
+func (s service) ChangeStatus(ctx context.Context, id string, req ChangeStatusRequest) (entity.Appointment, error) {
+	if err := req.Validate(); err != nil {
+		return entity.Appointment{}, err
+	}
+
+	var appointment entity.Appointment
+	err := s.transactional(ctx, func(ctx context.Context) error {
+		var err error
+		if appointment, err = s.repo.GetById(ctx, id); err != nil {
+			return err
+		}
+		if !auth.CanManageClinic(ctx, appointment.ClinicId) {
+			return errors.Forbidden("")
+		}
+		if !appointment.CanTransition(req.Status) {
+			return invalidTransition(appointment, req.Status)
+		}
+		if (req.Status == entity.StatusCompleted || req.Status == entity.StatusNoShow) && appointment.Time.After(time.Now()) {
+			return errors.Conflict(fmt.Sprintf("An appointment that has not started yet cannot be marked as %s.", req.Status))
+		}
+
+		if req.Status == entity.StatusApproved && appointment.RoomId == "" {
+			if appointment.RoomId, err = s.lockFreeRoom(ctx, appointment); err != nil {
+				return err
 			}
-			return doctor, nil
-		},
-		token,
-		nil,
-	)
+		}
+
+		appointment.Status = req.Status
+		err = s.repo.Update(ctx, appointment)
+		if dbcontext.IsUniqueViolation(err) {
+			return errNoFreeRoom
+		}
+		return err
+	})
+	if err != nil {
+		return entity.Appointment{}, err
+	}
+	return appointment, nil
+}
+
+// book runs a transaction booking a slot of a doctor. Locking the doctor's appointments lets two concurrent
+// bookings of a free period both take gap locks, so MySQL aborts one of them as a deadlock victim once they insert.
+// Such a transaction is run again after a delay letting the other booking commit, and then finds the slot taken
+// if the other booking overlaps it. errBookingContended is returned if the transaction keeps losing lock conflicts.
+func (s service) book(ctx context.Context, f func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := s.transactional(ctx, f)
+		if !dbcontext.IsLockConflict(err) {
+			return err
+		}
+		s.logger.With(ctx).Infof("booking transaction lost a lock conflict, attempt %d", attempt)
+		if attempt == maxBookingAttempts {
+			return errBookingContended
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * bookingRetryDelay):
+		}
+	}
+}
+
+// checkOverlap returns errSlotTaken if another appointment keeps the doctor busy during the given appointment.
+func (s service) checkOverlap(ctx context.Context, appointment entity.Appointment) error {
+	appointments, err := s.repo.LockDoctorAppointments(ctx, appointment.DoctorId, appointment.Time, appointment.OccupiedUntil())
+	if err != nil {
+		return err
+	}
+	for _, other := range appointments {
+		if other.Id != appointment.Id {
+			return errSlotTaken
+		}
+	}
+	return nil
+}
+
+// invalidTransition returns the error for a status change not allowed by the appointment lifecycle.
+func invalidTransition(appointment entity.Appointment, status string) error {
+	return errors.Conflict(fmt.Sprintf("An appointment that is %s cannot become %s.", appointment.Status, status))
+}
 
-	res, err := client.Endpoint()(context.Background(), struct{}{})
+// getChangeableAppointment returns the appointment if the current user may cancel or move it.
+func (s service) getChangeableAppointment(ctx context.Context, id string) (entity.Appointment, error) {
+	appointment, err := s.repo.GetById(ctx, id)
 	if err != nil {
-		return Doctor{}, err
+		return entity.Appointment{}, err
+	}
+
+	user := auth.CurrentUser(ctx)
+	if user == nil {
+		return entity.Appointment{}, errors.Forbidden("")
 	}
-	doctor, ok := res.(Doctor)
-	if !ok {
-		return Doctor{}, errors.New("unexpected error")
+	if user.GetRole() == entity.RolePatient {
+		if appointment.PatientId != user.GetID() {
+			return entity.Appointment{}, errors.Forbidden("")
+		}
+	} else if !user.CanManageClinic(appointment.ClinicId) {
+		return entity.Appointment{}, errors.Forbidden("")
 	}
 
-	return doctor, nil
+	if err = s.checkNotice(ctx, appointment.Time); err != nil {
+		return entity.Appointment{}, err
+	}
+	return appointment, nil
+}
+
+// checkNotice rejects changes by patients to appointments starting within the minimum notice period.
+// Administrators may change appointments at any time, e.g. when a doctor falls ill.
+func (s service) checkNotice(ctx context.Context, t time.Time) error {
+	user := auth.CurrentUser(ctx)
+	if user == nil || user.GetRole() != entity.RolePatient {
+		return nil
+	}
+	if t.Before(time.Now().Add(s.minNotice)) {
+		return errors.UnprocessableEntity(validation.Errors{
+			"time": validation.NewError("validation_min_notice", fmt.Sprintf("must be at least %v from now", s.minNotice)),
+		})
+	}
+	return nil
 }
 
 func (s service) GetDoctorAppointments(ctx context.Context, req GetDoctorAppointmentsRequest) ([]entity.Appointment, error) {
@@ -197,12 +458,16 @@ func (s service) GetDoctorAppointments(ctx context.Context, req GetDoctorAppoint
 		return nil, err
 	}
 
-	date, err := parseDate(req.Date)
+	doctor, err := s.clinicClient.GetDoctor(ctx, req.DoctorId)
+	if err != nil {
+		return nil, err
+	}
+	date, err := parseDate("date", req.Date, entity.LoadLocation(doctor.TimeZone))
 	if err != nil {
 		return nil, err
 	}
 
-	appointments, err := s.repo.GetDoctorAppointments(ctx, req.DoctorId, date, date.Add(24*time.Hour))
+	appointments, err := s.repo.GetDoctorAppointments(ctx, req.DoctorId, date, date.AddDate(0, 0, 1))
 	if err != nil {
 		return nil, err
 	}
@@ -210,24 +475,64 @@ func (s service) GetDoctorAppointments(ctx context.Context, req GetDoctorAppoint
 	return appointments, nil
 }
 
-func (s service) GetPatientAppointments(request *http.Request, req GetPatientAppointmentsRequest) ([]entity.Appointment, error) {
-	ctx := request.Context()
-	startDate, err := parseDate(req.StartDate)
+// GetBusyTimes returns the times at which the doctors are busy at some point in the requested period,
+// keyed by doctor ID. Busy times starting before or ending after the period are included whole.
+func (s service) GetBusyTimes(ctx context.Context, req GetBusyTimesRequest) (map[string][]BusyTime, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	start, err := time.Parse(time.RFC3339, req.Start)
 	if err != nil {
 		return nil, err
 	}
-	endDate, err := parseDate(req.EndDate)
+	end, err := time.Parse(time.RFC3339, req.End)
 	if err != nil {
 		return nil, err
 	}
+	appointments, err := s.repo.GetByDoctorIds(ctx, req.DoctorIds, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	busyTimes := make(map[string][]BusyTime, len(req.DoctorIds))
+	for _, id := range req.DoctorIds {
+		busyTimes[id] = []BusyTime{}
+	}
+	for _, appointment := range appointments {
+		busyTimes[appointment.DoctorId] = append(busyTimes[appointment.DoctorId], BusyTime{
+			Start: appointment.Time,
+			End:   appointment.OccupiedUntil(),
+		})
+	}
+	return busyTimes, nil
+}
+
+func (s service) GetPatientAppointments(ctx context.Context, req GetPatientAppointmentsRequest) ([]entity.Appointment, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
 
-	appointments, err := s.repo.GetByPatientIdAndDate(ctx, req.PatientId, startDate, endDate)
+	location := entity.LoadLocation(req.TimeZone)
+	startDate, err := parseDate("startDate", req.StartDate, location)
+	if err != nil {
+		return nil, err
+	}
+	endDate, err := parseDate("endDate", req.EndDate, location)
+	if err != nil {
+		return nil, err
+	}
+	if !endDate.IsZero() {
+		endDate = endDate.AddDate(0, 0, 1)
+	}
+
+	appointments, err := s.repo.GetByPatientIdAndDate(ctx, req.PatientId, startDate, endDate, req.Status)
 	if err != nil {
 		return nil, err
 	}
 
 	for idx, appointment := range appointments {
-		doctor, err := getDoctor(request.Header.Get("Authorization"), appointment.DoctorId)
+		doctor, err := s.clinicClient.GetDoctor(ctx, appointment.DoctorId)
 		if err != nil {
 			return nil, err
 		}
@@ -239,25 +544,52 @@ func (s service) GetPatientAppointments(request *http.Request, req GetPatientApp
 	return appointments, nil
 }
 
-func parseDate(date string) (time.Time, error) {
-	if date == "" {
-		return time.Time{}, nil
-	}
-	split := strings.Split(date, "-")
-	year, err := strconv.Atoi(split[0])
+// validateSlot checks that an appointment with the doctor at the given time starts at one of the doctor's slots,
+// the same slots the clinic-service offers as available hours. The slots are computed for the date of the
+// appointment in the time zone of the doctor's clinic.
+func (s service) validateSlot(ctx context.Context, doctor clinic.Doctor, t time.Time) error {
+	date := t.In(entity.LoadLocation(doctor.TimeZone))
+	// shifts starting the day before may continue into the date of the appointment
+	schedule, err := s.clinicClient.GetDoctorSchedule(ctx, doctor.Id,
+		date.AddDate(0, 0, -1).Format(entity.DateLayout), date.Format(entity.DateLayout))
 	if err != nil {
-		return time.Time{}, err
+		return err
 	}
 
-	month, err := strconv.Atoi(split[1])
-	if err != nil {
-		return time.Time{}, err
+	appointmentType := doctor.AppointmentType
+	length := time.Duration(appointmentType.Duration) * time.Minute
+	step := time.Duration(appointmentType.Duration+appointmentType.Buffer) * time.Minute
+	for _, slot := range schedule.GetSlots(date, length, step) {
+		if slot.Equal(t) {
+			return nil
+		}
 	}
+	return errors.UnprocessableEntity(validation.Errors{
+		"time": validation.NewError("validation_slot", "must be the start of a slot within the working hours of the doctor"),
+	})
+}
 
-	day, err := strconv.Atoi(split[2])
-	if err != nil {
-		return time.Time{}, err
+// validateTimeZone checks that the value is the name of a known IANA time zone.
+func validateTimeZone(value interface{}) error {
+	name, _ := value.(string)
+	if name == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil || name == "Local" {
+		return validation.NewError("validation_time_zone", "must be a valid IANA time zone")
 	}
+	return nil
+}
 
-	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC), nil
+// parseDate returns the start of the given date in the given location, or the zero time for an empty date.
+// A date that is not in the entity.DateLayout layout is reported as invalid input of the given field.
+func parseDate(field string, date string, location *time.Location) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation(entity.DateLayout, date, location)
+	if err != nil {
+		return time.Time{}, validation.Errors{field: validation.ErrDateInvalid}
+	}
+	return t, nil
 }
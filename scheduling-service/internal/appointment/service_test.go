@@ -0,0 +1,68 @@
+package appointment
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
+)
+
+const testPatientId = "00000000-0000-0000-0000-000000000100"
+
+// patientRepository filters the given appointments the way the patient appointments query does.
+type patientRepository struct {
+	Repository
+	appointments []entity.Appointment
+}
+
+func (r patientRepository) GetByPatientIdAndDate(ctx context.Context, patientId string, startDate time.Time, endDate time.Time, status string) ([]entity.Appointment, error) {
+	var appointments []entity.Appointment
+	for _, appointment := range r.appointments {
+		if appointment.PatientId == patientId &&
+			(startDate.IsZero() || !appointment.Time.Before(startDate)) &&
+			(endDate.IsZero() || appointment.Time.Before(endDate)) &&
+			(status == "" || appointment.Status == status) {
+			appointments = append(appointments, appointment)
+		}
+	}
+	return appointments, nil
+}
+
+func TestService_GetPatientAppointments_sameDay(t *testing.T) {
+	logger, _ := log.NewForTest()
+	location, _ := time.LoadLocation("Europe/Belgrade")
+	at := func(day, hour, minute int) entity.Appointment {
+		return entity.Appointment{
+			Id:        time.Date(2021, 9, day, hour, minute, 0, 0, location).Format(time.RFC3339),
+			PatientId: testPatientId,
+			DoctorId:  testDoctorId,
+			Time:      time.Date(2021, 9, day, hour, minute, 0, 0, location).UTC(),
+			Status:    entity.StatusApproved,
+		}
+	}
+	repo := patientRepository{appointments: []entity.Appointment{
+		at(5, 23, 30), at(6, 0, 0), at(6, 9, 0), at(6, 23, 30), at(7, 0, 0),
+	}}
+	s := NewService(repo, nil, fakeClinicClient{}, 0, logger)
+
+	appointments, err := s.GetPatientAppointments(context.Background(), GetPatientAppointmentsRequest{
+		PatientId: testPatientId,
+		StartDate: "2021-09-06",
+		EndDate:   "2021-09-06",
+		TimeZone:  "Europe/Belgrade",
+	})
+	if err != nil {
+		t.Fatalf("GetPatientAppointments() error = %v", err)
+	}
+	var ids []string
+	for _, appointment := range appointments {
+		ids = append(ids, appointment.Id)
+	}
+	want := []string{at(6, 0, 0).Id, at(6, 9, 0).Id, at(6, 23, 30).Id}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("appointments = %v, want the ones on 2021-09-06 %v", ids, want)
+	}
+}
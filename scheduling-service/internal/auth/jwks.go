@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
+)
+
+// minMissRefreshInterval is the minimum time between two refreshes triggered by tokens with an unknown key ID.
+const minMissRefreshInterval = time.Minute
+
+// KeySet holds the verification keys published in a JWKS document, indexed by their key ID.
+// RSA keys are stored as *rsa.PublicKey and symmetric keys as []byte.
+type KeySet struct {
+	location string
+	client   *http.Client
+	logger   log.Logger
+
+	mu          sync.RWMutex
+	keys        map[string]interface{}
+	refreshedAt time.Time
+}
+
+// NewKeySet creates a KeySet and loads the keys from the JWKS document found at the given location,
+// which is either an http(s) URL or a file path.
+func NewKeySet(location string, logger log.Logger) (*KeySet, error) {
+	s := &KeySet{
+		location: location,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		logger:   logger,
+	}
+	if err := s.Refresh(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Key returns the key with the given key ID.
+// If the key is not known, the key set is refreshed first, at most once per minMissRefreshInterval,
+// so that keys introduced by a rotation are picked up before the next scheduled refresh.
+func (s *KeySet) Key(kid string) (interface{}, bool) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	refreshedAt := s.refreshedAt
+	s.mu.RUnlock()
+
+	if ok || time.Since(refreshedAt) < minMissRefreshInterval {
+		return key, ok
+	}
+	if err := s.Refresh(); err != nil {
+		s.logger.Errorf("failed to refresh JWKS from %s: %v", s.location, err)
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok = s.keys[kid]
+	return key, ok
+}
+
+// Refresh reloads the keys from the JWKS document.
+// The previously loaded keys are kept if the document cannot be loaded.
+func (s *KeySet) Refresh() error {
+	bytes, err := s.load()
+	if err == nil {
+		var keys map[string]interface{}
+		if keys, err = parseJWKS(bytes); err == nil {
+			s.mu.Lock()
+			s.keys = keys
+			s.refreshedAt = time.Now()
+			s.mu.Unlock()
+			return nil
+		}
+	}
+
+	s.mu.Lock()
+	s.refreshedAt = time.Now()
+	s.mu.Unlock()
+	return err
+}
+
+// RefreshEvery refreshes the keys periodically. It never returns and should be run in its own goroutine.
+func (s *KeySet) RefreshEvery(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := s.Refresh(); err != nil {
+			s.logger.Errorf("failed to refresh JWKS from %s: %v", s.location, err)
+		}
+	}
+}
+
+// load reads the raw JWKS document from a URL or a file.
+func (s *KeySet) load() ([]byte, error) {
+	if !strings.HasPrefix(s.location, "http://") && !strings.HasPrefix(s.location, "https://") {
+		return ioutil.ReadFile(s.location)
+	}
+
+	res, err := s.client.Get(s.location)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected JWKS response status: %s", res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// parseJWKS parses a JWKS document into keys indexed by their key ID.
+// Keys without a key ID, encryption keys and keys of unsupported types are skipped.
+func parseJWKS(bytes []byte) (map[string]interface{}, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(bytes, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]interface{})
+	for _, jwk := range jwks.Keys {
+		if jwk.Kid == "" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			key, err := parseRSAKey(jwk)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RSA key %q: %v", jwk.Kid, err)
+			}
+			keys[jwk.Kid] = key
+		case "oct":
+			key, err := base64.RawURLEncoding.DecodeString(jwk.K)
+			if err != nil {
+				return nil, fmt.Errorf("invalid symmetric key %q: %v", jwk.Kid, err)
+			}
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func parseRSAKey(jwk jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return nil, err
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 2 || exponent.Int64() > 1<<31-1 {
+		return nil, fmt.Errorf("unsupported exponent")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(exponent.Int64()),
+	}, nil
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
+)
+
+// rsaJWK returns the JWK of the given RSA public key.
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// octJWK returns the JWK of the given symmetric key.
+func octJWK(kid string, key []byte) map[string]string {
+	return map[string]string{
+		"kty": "oct",
+		"kid": kid,
+		"k":   base64.RawURLEncoding.EncodeToString(key),
+	}
+}
+
+// jwksServer serves a JWKS document with the keys set by the returned function and counts the requests.
+func jwksServer(keys ...map[string]string) (*httptest.Server, *int32, func(keys ...map[string]string)) {
+	var mu sync.Mutex
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	return server, &calls, func(newKeys ...map[string]string) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys = newKeys
+	}
+}
+
+// expireRefresh makes the key set look as if it was refreshed long enough ago to refresh again on a miss.
+func expireRefresh(s *KeySet) {
+	s.mu.Lock()
+	s.refreshedAt = time.Now().Add(-minMissRefreshInterval)
+	s.mu.Unlock()
+}
+
+func TestParseJWKS(t *testing.T) {
+	document, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		rsaJWK("rsa", &testRSAKey.PublicKey),
+		octJWK("oct", []byte(testSigningKey)),
+		rsaJWK("", &testRSAKey.PublicKey),
+		{"kty": "RSA", "kid": "encryption", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "AQAB", "y": "AQAB"},
+	}})
+
+	keys, err := parseJWKS(document)
+	if err != nil {
+		t.Fatalf("parseJWKS() error = %v", err)
+	}
+	if len(keys) != 2 {
+		t.Errorf("parseJWKS() = %v, want the rsa and oct keys only", keys)
+	}
+	if key, ok := keys["rsa"].(*rsa.PublicKey); !ok || key.N.Cmp(testRSAKey.N) != 0 || key.E != testRSAKey.E {
+		t.Errorf("rsa key = %v, want the test RSA public key", keys["rsa"])
+	}
+	if key, ok := keys["oct"].([]byte); !ok || string(key) != testSigningKey {
+		t.Errorf("oct key = %v, want the test signing key", keys["oct"])
+	}
+}
+
+func TestParseJWKS_invalidDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+	}{
+		{"not JSON", "keys"},
+		{"invalid modulus", `{"keys":[{"kty":"RSA","kid":"rsa","n":"!","e":"AQAB"}]}`},
+		{"invalid exponent", `{"keys":[{"kty":"RSA","kid":"rsa","n":"AQAB","e":"AQ"}]}`},
+		{"invalid symmetric key", `{"keys":[{"kty":"oct","kid":"oct","k":"!"}]}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if keys, err := parseJWKS([]byte(tc.document)); err == nil {
+				t.Errorf("parseJWKS() = %v, want an error", keys)
+			}
+		})
+	}
+}
+
+func TestKeySet_Key(t *testing.T) {
+	logger, _ := log.NewForTest()
+	server, calls, _ := jwksServer(rsaJWK("rsa", &testRSAKey.PublicKey))
+	defer server.Close()
+
+	keySet, err := NewKeySet(server.URL, logger)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	if _, ok := keySet.Key("rsa"); !ok {
+		t.Error("Key(rsa) is not found")
+	}
+	if _, ok := keySet.Key("unknown"); ok {
+		t.Error("Key(unknown) is found")
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("JWKS requests = %d, want 1 as misses right after a refresh do not refresh again", got)
+	}
+}
+
+func TestKeySet_Key_refreshOnMiss(t *testing.T) {
+	logger, _ := log.NewForTest()
+	server, calls, setKeys := jwksServer(rsaJWK("old", &testRSAKey.PublicKey))
+	defer server.Close()
+
+	keySet, err := NewKeySet(server.URL, logger)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+
+	setKeys(rsaJWK("new", &testRSAKey.PublicKey))
+	expireRefresh(keySet)
+	if _, ok := keySet.Key("new"); !ok {
+		t.Error("Key(new) is not found after the key rotation")
+	}
+	if _, ok := keySet.Key("old"); ok {
+		t.Error("Key(old) is still found after the key rotation")
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("JWKS requests = %d, want 2", got)
+	}
+}
+
+func TestKeySet_Refresh_keepsKeysOnFailure(t *testing.T) {
+	logger, _ := log.NewForTest()
+	server, _, _ := jwksServer(rsaJWK("rsa", &testRSAKey.PublicKey))
+
+	keySet, err := NewKeySet(server.URL, logger)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	server.Close()
+	if err := keySet.Refresh(); err == nil {
+		t.Error("Refresh() error = nil, want an error for an unreachable JWKS")
+	}
+	if _, ok := keySet.Key("rsa"); !ok {
+		t.Error("Key(rsa) is not found after a failed refresh")
+	}
+}
+
+func TestNewKeySet_failure(t *testing.T) {
+	logger, _ := log.NewForTest()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if _, err := NewKeySet(server.URL, logger); err == nil {
+		t.Error("NewKeySet() error = nil, want an error for a failing JWKS endpoint")
+	}
+}
+
+func TestNewKeySet_file(t *testing.T) {
+	logger, _ := log.NewForTest()
+	document, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{octJWK("oct", []byte(testSigningKey))}})
+	file, err := ioutil.TempFile("", "jwks-*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	_, _ = file.Write(document)
+	file.Close()
+
+	keySet, err := NewKeySet(file.Name(), logger)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	if _, ok := keySet.Key("oct"); !ok {
+		t.Error("Key(oct) is not found")
+	}
+}
+
+func TestHandler_keySet(t *testing.T) {
+	logger, _ := log.NewForTest()
+	server, _, setKeys := jwksServer(rsaJWK("rsa", &testRSAKey.PublicKey), octJWK("oct", []byte(testSigningKey)))
+	defer server.Close()
+	keySet, err := NewKeySet(server.URL, logger)
+	if err != nil {
+		t.Fatalf("NewKeySet() error = %v", err)
+	}
+	keyFunc, err := NewKeyFunc(keySet, "", "")
+	if err != nil {
+		t.Fatalf("NewKeyFunc() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		method     jwt.SigningMethod
+		key        interface{}
+		kid        string
+		wantStatus int
+	}{
+		{"RS256 token with an RSA key ID", jwt.SigningMethodRS256, testRSAKey, "rsa", http.StatusOK},
+		{"HS256 token with a symmetric key ID", jwt.SigningMethodHS256, []byte(testSigningKey), "oct", http.StatusOK},
+		{"HS256 token with an RSA key ID", jwt.SigningMethodHS256, []byte(testSigningKey), "rsa", http.StatusUnauthorized},
+		{"RS256 token with a symmetric key ID", jwt.SigningMethodRS256, testRSAKey, "oct", http.StatusUnauthorized},
+		{"token with an unknown key ID", jwt.SigningMethodRS256, testRSAKey, "unknown", http.StatusUnauthorized},
+		{"token without a key ID", jwt.SigningMethodRS256, testRSAKey, "", http.StatusUnauthorized},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := serveAuthenticated(keyFunc, testIssuer, "Bearer "+signToken(t, tc.method, tc.key, tc.kid, validClaims()))
+			if res.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d (%s)", res.Code, tc.wantStatus, res.Body.String())
+			}
+		})
+	}
+
+	// a token signed with a rotated key is accepted once the key set is refreshed on the miss
+	setKeys(rsaJWK("rotated", &testRSAKey.PublicKey))
+	expireRefresh(keySet)
+	res := serveAuthenticated(keyFunc, testIssuer, "Bearer "+signToken(t, jwt.SigningMethodRS256, testRSAKey, "rotated", validClaims()))
+	if res.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200 for a token signed with a rotated key", res.Code)
+	}
+}
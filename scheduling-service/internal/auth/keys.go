@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// NewKeyFunc returns a jwt.Keyfunc that looks up the verification key of a token.
+//
+// Tokens carrying a key ID are verified with the matching key from keySet, if one is given.
+// Otherwise HS256-signed tokens are verified with the given signing key and RS256-signed tokens
+// with the RSA public key stored in the given PEM file. Either of the two can be empty, in which case
+// tokens signed with the corresponding method are rejected.
+func NewKeyFunc(keySet *KeySet, signingKey, publicKeyFile string) (jwt.Keyfunc, error) {
+	var publicKey *rsa.PublicKey
+	if publicKeyFile != "" {
+		bytes, err := ioutil.ReadFile(publicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if publicKey, err = jwt.ParseRSAPublicKeyFromPEM(bytes); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(token *jwt.Token) (interface{}, error) {
+		if kid, ok := token.Header["kid"].(string); ok && keySet != nil {
+			key, ok := keySet.Key(kid)
+			if !ok {
+				return nil, fmt.Errorf("unknown key ID: %v", kid)
+			}
+			return matchKey(token, key)
+		}
+
+		switch token.Method.(type) {
+		case *jwt.SigningMethodHMAC:
+			if signingKey != "" {
+				return []byte(signingKey), nil
+			}
+		case *jwt.SigningMethodRSA:
+			if publicKey != nil {
+				return publicKey, nil
+			}
+		}
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}, nil
+}
+
+// matchKey returns the key if its type matches the signing method of the token.
+func matchKey(token *jwt.Token, key interface{}) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		if k, ok := key.([]byte); ok {
+			return k, nil
+		}
+	case *jwt.SigningMethodRSA:
+		if k, ok := key.(*rsa.PublicKey); ok {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("key %v cannot be used with signing method %v", token.Header["kid"], token.Header["alg"])
+}
@@ -0,0 +1,107 @@
+// Package clinic provides a client for the clinic-service API.
+package clinic
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/httpclient"
+)
+
+// Doctor represents a doctor working in a clinic.
+type Doctor struct {
+	Id                   string `json:"id"`
+	ClinicId             string `json:"clinicId"`
+	FirstName            string `json:"firstName"`
+	LastName             string `json:"lastName"`
+	WorkStart            string `json:"workStart"`
+	WorkEnd              string `json:"workEnd"`
+	AppointmentType      `json:"specialization"`
+	AppointmentTypePrice uint     `json:"specializationPrice"`
+	AvailableHours       []string `json:"availableHours"`
+	// TimeZone is the IANA time zone of the doctor's clinic.
+	TimeZone string `json:"timeZone"`
+}
+
+// Clinic represents a clinic.
+type Clinic struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// TimeZone is the IANA time zone of the clinic.
+	TimeZone string `json:"timeZone"`
+}
+
+// AppointmentType represents a type of appointment a doctor is specialized for.
+type AppointmentType struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+	// Duration and Buffer are given in minutes.
+	Duration int `json:"duration"`
+	Buffer   int `json:"buffer"`
+}
+
+// Room represents a room of a clinic and the appointment types it supports.
+type Room struct {
+	Id                 string   `json:"id"`
+	ClinicId           string   `json:"clinicId"`
+	Name               string   `json:"name"`
+	AppointmentTypeIds []string `json:"appointmentTypeIds"`
+}
+
+// Supports returns whether appointments of the given type can take place in the room.
+func (r Room) Supports(appointmentTypeId string) bool {
+	for _, id := range r.AppointmentTypeIds {
+		if id == appointmentTypeId {
+			return true
+		}
+	}
+	return false
+}
+
+// Client sends requests to the clinic-service API.
+type Client interface {
+	// GetClinic returns the clinic with the given ID.
+	GetClinic(ctx context.Context, clinicId string) (Clinic, error)
+	// GetDoctor returns the doctor with the given ID.
+	GetDoctor(ctx context.Context, doctorId string) (Doctor, error)
+	// GetDoctorSchedule returns the weekly schedule of the doctor with the time off including any of the days
+	// from startDate to endDate.
+	GetDoctorSchedule(ctx context.Context, doctorId string, startDate string, endDate string) (entity.Schedule, error)
+	// GetRooms returns the rooms of the clinic with the given ID.
+	GetRooms(ctx context.Context, clinicId string) ([]Room, error)
+}
+
+type client struct {
+	http *httpclient.Client
+}
+
+// NewClient creates a new clinic-service client sending requests with the given HTTP client.
+func NewClient(http *httpclient.Client) Client {
+	return client{http}
+}
+
+func (c client) GetClinic(ctx context.Context, clinicId string) (Clinic, error) {
+	var clinic Clinic
+	err := c.http.Get(ctx, "/v1/clinics/"+clinicId, nil, &clinic)
+	return clinic, err
+}
+
+func (c client) GetDoctor(ctx context.Context, doctorId string) (Doctor, error) {
+	var doctor Doctor
+	err := c.http.Get(ctx, "/v1/doctors/"+doctorId, nil, &doctor)
+	return doctor, err
+}
+
+func (c client) GetDoctorSchedule(ctx context.Context, doctorId string, startDate string, endDate string) (entity.Schedule, error) {
+	var schedule entity.Schedule
+	query := url.Values{"startDate": {startDate}, "endDate": {endDate}}
+	err := c.http.Get(ctx, "/v1/doctors/"+doctorId+"/schedule", query, &schedule)
+	return schedule, err
+}
+
+func (c client) GetRooms(ctx context.Context, clinicId string) ([]Room, error) {
+	var rooms []Room
+	err := c.http.Get(ctx, "/v1/clinics/"+clinicId+"/rooms", nil, &rooms)
+	return rooms, err
+}
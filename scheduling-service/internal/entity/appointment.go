@@ -2,6 +2,27 @@ package entity
 
 import "time"
 
+// Appointment statuses. A booked appointment is requested until an administrator approves or rejects it.
+// An approved appointment ends up completed or, if the patient did not come, as a no-show.
+// Rejected, completed, cancelled and no-show appointments cannot change anymore.
+const (
+	StatusRequested = "requested"
+	StatusApproved  = "approved"
+	StatusRejected  = "rejected"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+	StatusNoShow    = "no_show"
+)
+
+// statusTransitions lists the statuses an appointment in a given status can move to.
+var statusTransitions = map[string][]string{
+	StatusRequested: {StatusApproved, StatusRejected, StatusCancelled},
+	StatusApproved:  {StatusCompleted, StatusCancelled, StatusNoShow},
+}
+
+// InactiveStatuses lists the statuses of appointments that no longer occupy their slot.
+var InactiveStatuses = []string{StatusRejected, StatusCancelled}
+
 type Appointment struct {
 	Id                string    `json:"id"`
 	ClinicId          string    `json:"clinicId"`
@@ -10,8 +31,47 @@ type Appointment struct {
 	AppointmentTypeId string    `json:"appointmentTypeId"`
 	Price             int       `json:"price"`
 	Time              time.Time `json:"time"`
+	Status            string    `json:"status"`
+	RoomId            string    `json:"roomId"`
+	// Duration is the length of the appointment and Buffer the time the doctor needs after it, both in minutes.
+	Duration int `json:"duration"`
+	Buffer   int `json:"buffer"`
 
 	DoctorFullName      string `json:"doctorFullName" db:"-"`
 	ClinicName          string `json:"clinicName" db:"-"`
 	AppointmentTypeName string `json:"name" db:"-"`
 }
+
+// IsValidStatus returns whether the given string is one of the appointment statuses.
+func IsValidStatus(status string) bool {
+	switch status {
+	case StatusRequested, StatusApproved, StatusRejected, StatusCompleted, StatusCancelled, StatusNoShow:
+		return true
+	}
+	return false
+}
+
+// CanTransition returns whether the appointment can move to the given status.
+func (a Appointment) CanTransition(status string) bool {
+	for _, s := range statusTransitions[a.Status] {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUpcoming returns whether the appointment is yet to take place, i.e. it is requested or approved.
+func (a Appointment) IsUpcoming() bool {
+	return a.Status == StatusRequested || a.Status == StatusApproved
+}
+
+// OccupiedUntil returns the time at which the doctor becomes free after the appointment, including the buffer.
+func (a Appointment) OccupiedUntil() time.Time {
+	return a.Time.Add(time.Duration(a.Duration+a.Buffer) * time.Minute)
+}
+
+// Overlaps returns whether the appointment keeps the doctor or its room busy at some point between start and end.
+func (a Appointment) Overlaps(start time.Time, end time.Time) bool {
+	return a.Time.Before(end) && start.Before(a.OccupiedUntil())
+}
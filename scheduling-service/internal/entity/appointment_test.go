@@ -0,0 +1,33 @@
+package entity
+
+import "testing"
+
+func TestAppointment_CanTransition(t *testing.T) {
+	statuses := []string{StatusRequested, StatusApproved, StatusRejected, StatusCompleted, StatusCancelled, StatusNoShow}
+	allowed := map[string]map[string]bool{
+		StatusRequested: {StatusApproved: true, StatusRejected: true, StatusCancelled: true},
+		StatusApproved:  {StatusCompleted: true, StatusCancelled: true, StatusNoShow: true},
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			t.Run(from+" to "+to, func(t *testing.T) {
+				if got := (Appointment{Status: from}).CanTransition(to); got != allowed[from][to] {
+					t.Errorf("CanTransition() = %v, want %v", got, allowed[from][to])
+				}
+			})
+		}
+	}
+}
+
+func TestIsValidStatus(t *testing.T) {
+	for _, status := range []string{StatusRequested, StatusApproved, StatusRejected, StatusCompleted, StatusCancelled, StatusNoShow} {
+		if !IsValidStatus(status) {
+			t.Errorf("IsValidStatus(%q) = false, want true", status)
+		}
+	}
+	for _, status := range []string{"", "done", "Approved"} {
+		if IsValidStatus(status) {
+			t.Errorf("IsValidStatus(%q) = true, want false", status)
+		}
+	}
+}
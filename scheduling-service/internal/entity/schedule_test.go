@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"testing"
+	"time"
+)
+
+// belgrade returns the location of Europe/Belgrade, where daylight saving time started on 2021-03-28
+// at 02:00, skipping to 03:00, and ended on 2021-10-31 at 03:00, going back to 02:00.
+func belgrade(t *testing.T) *time.Location {
+	location, err := time.LoadLocation("Europe/Belgrade")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	return location
+}
+
+func TestTime_on(t *testing.T) {
+	location := belgrade(t)
+	tests := []struct {
+		name string
+		date string
+		time Time
+		want string
+	}{
+		{"ordinary day", "2021-06-01", Time{Hour: 8, Minute: 30}, "2021-06-01T08:30:00+02:00"},
+		{"midnight of spring forward day", "2021-03-28", Time{Hour: 0, Minute: 0}, "2021-03-28T00:00:00+01:00"},
+		{"before spring forward", "2021-03-28", Time{Hour: 1, Minute: 30}, "2021-03-28T01:30:00+01:00"},
+		{"skipped by spring forward", "2021-03-28", Time{Hour: 2, Minute: 30}, "2021-03-28T03:30:00+02:00"},
+		{"after spring forward", "2021-03-28", Time{Hour: 3, Minute: 0}, "2021-03-28T03:00:00+02:00"},
+		{"midnight of fall back day", "2021-10-31", Time{Hour: 0, Minute: 0}, "2021-10-31T00:00:00+02:00"},
+		{"before fall back", "2021-10-31", Time{Hour: 1, Minute: 0}, "2021-10-31T01:00:00+02:00"},
+		{"after fall back", "2021-10-31", Time{Hour: 3, Minute: 0}, "2021-10-31T03:00:00+01:00"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			day, _ := time.ParseInLocation(DateLayout, tc.date, location)
+			want, _ := time.Parse(time.RFC3339, tc.want)
+			if got := tc.time.on(day); !got.Equal(want) {
+				t.Errorf("on() = %v, want %v", got, want)
+			}
+		})
+	}
+
+	// a time of day repeated by fall back is on the date at that wall clock time, with either offset
+	day, _ := time.ParseInLocation(DateLayout, "2021-10-31", location)
+	if got := (Time{Hour: 2, Minute: 30}).on(day); got.Day() != 31 || got.Hour() != 2 || got.Minute() != 30 {
+		t.Errorf("on() = %v, want 02:30 on 2021-10-31", got)
+	}
+}
+
+func TestSchedule_GetSlots(t *testing.T) {
+	location := belgrade(t)
+	tests := []struct {
+		name       string
+		date       string
+		start, end string
+		want       []string
+	}{
+		{"ordinary day", "2021-03-27", "01:00", "04:00", []string{
+			"2021-03-27T01:00:00+01:00", "2021-03-27T02:00:00+01:00", "2021-03-27T03:00:00+01:00",
+		}},
+		{"spring forward shortens the shift", "2021-03-28", "01:00", "04:00", []string{
+			"2021-03-28T01:00:00+01:00", "2021-03-28T03:00:00+02:00",
+		}},
+		{"fall back lengthens the shift", "2021-10-31", "01:00", "04:00", []string{
+			"2021-10-31T01:00:00+02:00", "2021-10-31T02:00:00+02:00", "2021-10-31T02:00:00+01:00", "2021-10-31T03:00:00+01:00",
+		}},
+		{"night shifts on spring forward", "2021-03-28", "22:00", "06:00", []string{
+			"2021-03-28T00:00:00+01:00", "2021-03-28T01:00:00+01:00", "2021-03-28T03:00:00+02:00",
+			"2021-03-28T04:00:00+02:00", "2021-03-28T05:00:00+02:00",
+			"2021-03-28T22:00:00+02:00", "2021-03-28T23:00:00+02:00",
+		}},
+		{"night shifts on fall back", "2021-10-31", "22:00", "06:00", []string{
+			"2021-10-31T00:00:00+02:00", "2021-10-31T01:00:00+02:00", "2021-10-31T02:00:00+02:00",
+			"2021-10-31T02:00:00+01:00", "2021-10-31T03:00:00+01:00", "2021-10-31T04:00:00+01:00",
+			"2021-10-31T05:00:00+01:00", "2021-10-31T22:00:00+01:00", "2021-10-31T23:00:00+01:00",
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule := Schedule{Shifts: DefaultShifts("doctor", tc.start, tc.end), TimeZone: "Europe/Belgrade"}
+			date, _ := time.ParseInLocation(DateLayout, tc.date, location)
+			got := schedule.GetSlots(date, time.Hour, time.Hour)
+			if len(got) != len(tc.want) {
+				t.Fatalf("GetSlots() = %v, want %v", got, tc.want)
+			}
+			for i, slot := range got {
+				want, _ := time.Parse(time.RFC3339, tc.want[i])
+				if !slot.Equal(want) || slot.Location().String() != location.String() {
+					t.Errorf("GetSlots()[%d] = %v, want %v in Europe/Belgrade", i, slot, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Time is a time of day, such as the start of a shift, without a date or a time zone.
+type Time struct {
+	Hour   uint `json:"hour"`
+	Minute uint `json:"minute"`
+}
+
+// ParseTime parses a time of day written as "hour:minute", e.g. "08:30".
+// An error is returned unless the hour is from 0 to 23 and the minute from 0 to 59.
+func ParseTime(s string) (Time, error) {
+	split := strings.Split(s, ":")
+	if len(split) != 2 {
+		return Time{}, fmt.Errorf("invalid time of day %q: want hour:minute", s)
+	}
+	hour, err := strconv.ParseUint(split[0], 10, 8)
+	if err != nil || hour > 23 {
+		return Time{}, fmt.Errorf("invalid hour in time of day %q", s)
+	}
+	minute, err := strconv.ParseUint(split[1], 10, 8)
+	if err != nil || minute > 59 {
+		return Time{}, fmt.Errorf("invalid minute in time of day %q", s)
+	}
+	return Time{Hour: uint(hour), Minute: uint(minute)}, nil
+}
+
+// ToString formats the time of day as "hh:mm".
+func (t Time) ToString() string {
+	return fmt.Sprintf("%02d:%02d", t.Hour, t.Minute)
+}
+
+// Before returns whether the time of day is earlier than o.
+func (t Time) Before(o Time) bool {
+	if t.Hour != o.Hour {
+		return t.Hour < o.Hour
+	}
+	return t.Minute < o.Minute
+}
@@ -1,10 +1,20 @@
 package entity
 
+const (
+	// RoleAdmin is the role of the administrators managing clinics, doctors and appointment types.
+	RoleAdmin = "admin"
+	// RoleClinicAdmin is the role of the administrators managing only the clinics listed in their token.
+	RoleClinicAdmin = "clinic_admin"
+	// RolePatient is the role of the registered users booking and rating appointments.
+	RolePatient = "patient"
+)
+
 // User represents a user.
 type User struct {
-	ID   string
-	Name string
-	Role string
+	ID        string
+	Name      string
+	Role      string
+	ClinicIds []string
 }
 
 // GetID returns the user ID.
@@ -17,6 +27,27 @@ func (u User) GetName() string {
 	return u.Name
 }
 
+// GetRole returns the user role.
 func (u User) GetRole() string {
 	return u.Role
 }
+
+// GetClinicIds returns the IDs of the clinics managed by a clinic administrator.
+func (u User) GetClinicIds() []string {
+	return u.ClinicIds
+}
+
+// CanManageClinic returns whether the user is allowed to administer the clinic with the given ID.
+func (u User) CanManageClinic(clinicId string) bool {
+	switch u.Role {
+	case RoleAdmin:
+		return true
+	case RoleClinicAdmin:
+		for _, id := range u.ClinicIds {
+			if id == clinicId {
+				return true
+			}
+		}
+	}
+	return false
+}
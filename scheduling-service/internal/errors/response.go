@@ -34,6 +34,28 @@ func InternalServerError(msg string) ErrorResponse {
 	}
 }
 
+// ServiceUnavailable creates a new error response representing a service that cannot process requests for now (HTTP 503)
+func ServiceUnavailable(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "The service is temporarily unavailable."
+	}
+	return ErrorResponse{
+		Status:  http.StatusServiceUnavailable,
+		Message: msg,
+	}
+}
+
+// GatewayTimeout creates a new error response representing a request to another service that timed out (HTTP 504)
+func GatewayTimeout(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "A service needed to process your request did not respond in time."
+	}
+	return ErrorResponse{
+		Status:  http.StatusGatewayTimeout,
+		Message: msg,
+	}
+}
+
 // NotFound creates a new error response representing a resource-not-found error (HTTP 404)
 func NotFound(msg string) ErrorResponse {
 	if msg == "" {
@@ -83,8 +105,38 @@ type invalidField struct {
 	Error string `json:"error"`
 }
 
+// Conflict creates a new error response representing a request conflicting with the current state of a resource (HTTP 409)
+func Conflict(msg string) ErrorResponse {
+	if msg == "" {
+		msg = "The request conflicts with the current state of the resource."
+	}
+	return ErrorResponse{
+		Status:  http.StatusConflict,
+		Message: msg,
+	}
+}
+
 // InvalidInput creates a new error response representing a data validation error (HTTP 400).
 func InvalidInput(errs validation.Errors) ErrorResponse {
+	return ErrorResponse{
+		Status:  http.StatusBadRequest,
+		Message: "There is some problem with the data you submitted.",
+		Details: invalidFields(errs),
+	}
+}
+
+// UnprocessableEntity creates a new error response representing well-formed data that is rejected
+// by the business rules (HTTP 422).
+func UnprocessableEntity(errs validation.Errors) ErrorResponse {
+	return ErrorResponse{
+		Status:  http.StatusUnprocessableEntity,
+		Message: "The data you submitted cannot be processed.",
+		Details: invalidFields(errs),
+	}
+}
+
+// invalidFields lists the given validation errors sorted by field name.
+func invalidFields(errs validation.Errors) []invalidField {
 	var details []invalidField
 	var fields []string
 	for field := range errs {
@@ -97,10 +149,5 @@ func InvalidInput(errs validation.Errors) ErrorResponse {
 			Error: errs[field].Error(),
 		})
 	}
-
-	return ErrorResponse{
-		Status:  http.StatusBadRequest,
-		Message: "There is some problem with the data you submitted.",
-		Details: details,
-	}
+	return details
 }
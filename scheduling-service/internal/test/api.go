@@ -0,0 +1,125 @@
+// Package test provides helpers for testing the API handlers.
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-ozzo/ozzo-routing/v2/content"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/auth"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/entity"
+	"github.com/matijapetrovic/clinichub/scheduling-service/internal/errors"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
+)
+
+// ClinicId is the ID of the clinic managed by the clinic administrators of MockAuthHeader.
+const ClinicId = "00000000-0000-0000-0000-000000000001"
+
+// signingKey signs the tokens of MockAuthHeader.
+var signingKey = []byte("test-signing-key")
+
+// APITestCase represents the data needed to describe an API test case.
+type APITestCase struct {
+	Name       string
+	Method     string
+	URL        string
+	Body       string
+	Header     http.Header
+	WantStatus int
+}
+
+// RouteTestCase represents the expected statuses of a route keyed by the role of the user sending the request.
+// The empty role stands for requests without a token. Every request is sent with the same body.
+type RouteTestCase struct {
+	Method     string
+	URL        string
+	Body       string
+	WantStatus map[string]int
+}
+
+// Roles are the roles every RouteTestCase has to give an expected status for.
+var Roles = []string{"", entity.RoleAdmin, entity.RoleClinicAdmin, entity.RolePatient}
+
+// MockRouter creates a routing.Router for testing APIs.
+func MockRouter(logger log.Logger) *routing.Router {
+	router := routing.New()
+	router.Use(
+		errors.Handler(logger),
+		content.TypeNegotiator(content.JSON),
+	)
+	return router
+}
+
+// MockAuthHandler returns the authentication middleware accepting the tokens of MockAuthHeader.
+func MockAuthHandler() routing.Handler {
+	return auth.Handler(func(*jwt.Token) (interface{}, error) {
+		return signingKey, nil
+	}, "")
+}
+
+// MockAuthHeader returns an Authorization header with a valid token of a user having the given role.
+// Clinic administrators manage the clinic with ClinicId. An empty role returns an empty header.
+func MockAuthHeader(role string) http.Header {
+	header := http.Header{}
+	if role == "" {
+		return header
+	}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":       "00000000-0000-0000-0000-000000000100",
+		"username": role,
+		"role":     role,
+		"clinics":  []string{ClinicId},
+		"exp":      time.Now().Add(time.Hour).Unix(),
+	}).SignedString(signingKey)
+	header.Set("Authorization", "Bearer "+token)
+	return header
+}
+
+// Endpoint tests an HTTP endpoint using the given APITestCase spec.
+func Endpoint(t *testing.T, router *routing.Router, tc APITestCase) {
+	t.Run(tc.Name, func(t *testing.T) {
+		req, _ := http.NewRequest(tc.Method, tc.URL, strings.NewReader(tc.Body))
+		for name, values := range tc.Header {
+			req.Header[name] = values
+		}
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		if res.Code != tc.WantStatus {
+			t.Errorf("status = %d, want %d", res.Code, tc.WantStatus)
+		}
+	})
+}
+
+// Routes tests the status of every route for a request without a token and for requests of users having each role.
+func Routes(t *testing.T, router *routing.Router, routes []RouteTestCase) {
+	for _, route := range routes {
+		for _, role := range Roles {
+			status, ok := route.WantStatus[role]
+			if !ok {
+				t.Errorf("%s %s: no expected status for role %q", route.Method, route.URL, role)
+				continue
+			}
+			name := role
+			if name == "" {
+				name = "anonymous"
+			}
+			Endpoint(t, router, APITestCase{
+				Name:       route.Method + " " + route.URL + " as " + name,
+				Method:     route.Method,
+				URL:        route.URL,
+				Body:       route.Body,
+				Header:     MockAuthHeader(role),
+				WantStatus: status,
+			})
+		}
+	}
+}
@@ -0,0 +1 @@
+DROP INDEX appointment_doctor_id_time ON appointment;
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX appointment_doctor_id_time ON appointment (doctor_id, time);
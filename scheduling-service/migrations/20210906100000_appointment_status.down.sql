@@ -0,0 +1,25 @@
+-- Rejected and cancelled appointments may share their slot with another appointment, which the unique index
+-- on the doctor and time does not allow. They are moved to appointment_inactive instead of being dropped.
+CREATE TABLE appointment_inactive
+(
+    id                  VARCHAR(255) NOT NULL PRIMARY KEY,
+    clinic_id           VARCHAR(255) NOT NULL,
+    doctor_id           VARCHAR(255) NOT NULL,
+    patient_id          VARCHAR(255) NOT NULL,
+    appointment_type_id VARCHAR(255) NOT NULL,
+    price               INT          NOT NULL,
+    time                DATETIME     NOT NULL,
+    status              VARCHAR(32)  NOT NULL,
+    archived_at         DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO appointment_inactive (id, clinic_id, doctor_id, patient_id, appointment_type_id, price, time, status)
+SELECT id, clinic_id, doctor_id, patient_id, appointment_type_id, price, time, status
+FROM appointment
+WHERE status IN ('rejected', 'cancelled');
+DELETE a FROM appointment a JOIN appointment_inactive i ON i.id = a.id;
+
+DROP INDEX appointment_doctor_id_time_active ON appointment;
+CREATE UNIQUE INDEX appointment_doctor_id_time ON appointment (doctor_id, time);
+ALTER TABLE appointment DROP COLUMN active_slot;
+ALTER TABLE appointment DROP COLUMN status;
@@ -0,0 +1,24 @@
+-- Past appointments are approved rather than completed, as it is not known whether the patients came.
+-- Administrators mark them as completed or as no-shows.
+ALTER TABLE appointment ADD COLUMN status VARCHAR(32) NOT NULL DEFAULT 'requested';
+UPDATE appointment SET status = 'approved' WHERE time < NOW();
+ALTER TABLE appointment ADD COLUMN active_slot TINYINT AS (IF(status IN ('rejected', 'cancelled'), NULL, 1)) STORED;
+DROP INDEX appointment_doctor_id_time ON appointment;
+CREATE UNIQUE INDEX appointment_doctor_id_time_active ON appointment (doctor_id, time, active_slot);
+
+-- restore the rejected and cancelled appointments archived by the down migration
+CREATE TABLE IF NOT EXISTS appointment_inactive
+(
+    id                  VARCHAR(255) NOT NULL PRIMARY KEY,
+    clinic_id           VARCHAR(255) NOT NULL,
+    doctor_id           VARCHAR(255) NOT NULL,
+    patient_id          VARCHAR(255) NOT NULL,
+    appointment_type_id VARCHAR(255) NOT NULL,
+    price               INT          NOT NULL,
+    time                DATETIME     NOT NULL,
+    status              VARCHAR(32)  NOT NULL,
+    archived_at         DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO appointment (id, clinic_id, doctor_id, patient_id, appointment_type_id, price, time, status)
+SELECT id, clinic_id, doctor_id, patient_id, appointment_type_id, price, time, status FROM appointment_inactive;
+DROP TABLE appointment_inactive;
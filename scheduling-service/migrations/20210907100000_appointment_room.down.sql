@@ -0,0 +1,3 @@
+DROP INDEX appointment_room_id_time_active ON appointment;
+ALTER TABLE appointment DROP COLUMN room_slot;
+ALTER TABLE appointment DROP COLUMN room_id;
@@ -0,0 +1,3 @@
+ALTER TABLE appointment ADD COLUMN room_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE appointment ADD COLUMN room_slot TINYINT AS (IF(room_id = '' OR status IN ('rejected', 'cancelled'), NULL, 1)) STORED;
+CREATE UNIQUE INDEX appointment_room_id_time_active ON appointment (room_id, time, room_slot);
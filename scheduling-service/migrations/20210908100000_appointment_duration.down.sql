@@ -0,0 +1,2 @@
+ALTER TABLE appointment DROP COLUMN buffer;
+ALTER TABLE appointment DROP COLUMN duration;
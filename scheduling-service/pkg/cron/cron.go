@@ -0,0 +1,172 @@
+// Package cron runs jobs on schedules given in the five-field cron format.
+package cron
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
+)
+
+// Schedule is a parsed cron schedule.
+type Schedule struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+	// anyDay and anyWeekday tell whether the day-of-month and day-of-week fields are "*".
+	// As in cron, when both fields are restricted a time matches if either of them matches.
+	anyDay     bool
+	anyWeekday bool
+}
+
+// Parse parses a schedule in the "minute hour day-of-month month day-of-week" format.
+// Every field is either "*" or a comma-separated list of values and ranges ("1-5"),
+// each optionally followed by a step ("*/15", "8-18/2"). Sunday is 0 or 7.
+func Parse(spec string) (Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("cron schedule %q must have 5 fields", spec)
+	}
+
+	var s Schedule
+	var err error
+	if s.minutes, err = parseField(fields[0], 0, 59); err != nil {
+		return Schedule{}, err
+	}
+	if s.hours, err = parseField(fields[1], 0, 23); err != nil {
+		return Schedule{}, err
+	}
+	if s.days, err = parseField(fields[2], 1, 31); err != nil {
+		return Schedule{}, err
+	}
+	if s.months, err = parseField(fields[3], 1, 12); err != nil {
+		return Schedule{}, err
+	}
+	if s.weekdays, err = parseField(fields[4], 0, 7); err != nil {
+		return Schedule{}, err
+	}
+	if s.weekdays[7] {
+		s.weekdays[0] = true
+	}
+	s.anyDay = fields[2] == "*"
+	s.anyWeekday = fields[4] == "*"
+	return s, nil
+}
+
+// parseField returns the set of values matched by a schedule field.
+func parseField(field string, min, max int) (map[int]bool, error) {
+	values := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in cron field %q", field)
+			}
+			part = part[:i]
+		}
+
+		from, to := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value in cron field %q", field)
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid range in cron field %q", field)
+				}
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("cron field %q is out of range %d-%d", field, min, max)
+		}
+
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// Next returns the first time after t matched by the schedule, in the location of t.
+// Wall clock times skipped when clocks are turned forward are not matched, and times repeated when clocks
+// are turned back are only matched the first time. The zero time is returned if the schedule matches
+// no time within the next five years.
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.minutes[t.Minute()] || repeated(t) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// repeated returns whether the wall clock time of t already occurred an hour earlier,
+// which happens when clocks are turned back.
+func repeated(t time.Time) bool {
+	earlier := t.Add(-time.Hour)
+	return earlier.Hour() == t.Hour() && earlier.Minute() == t.Minute()
+}
+
+func (s Schedule) matchesDay(t time.Time) bool {
+	day := s.days[t.Day()]
+	weekday := s.weekdays[int(t.Weekday())]
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	}
+	return day || weekday
+}
+
+// Run calls the job every time the schedule matches until the context is cancelled.
+// Errors returned by the job are logged and do not stop later runs.
+func Run(ctx context.Context, schedule Schedule, name string, job func(ctx context.Context) error, logger log.Logger) {
+	for {
+		next := schedule.Next(time.Now())
+		if next.IsZero() {
+			logger.Errorf("job %s is never scheduled to run", name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		logger.Infof("running job %s", name)
+		if err := job(ctx); err != nil {
+			logger.Errorf("job %s failed: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package cron
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
+)
+
+func TestParse_invalidSchedule(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+		"*/a * * * *",
+	}
+	for _, spec := range tests {
+		t.Run(spec, func(t *testing.T) {
+			if _, err := Parse(spec); err == nil {
+				t.Errorf("Parse(%q) error = nil, want an error", spec)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	belgrade, err := time.LoadLocation("Europe/Belgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"every minute", "* * * * *", at(2021, 9, 6, 10, 15).Add(30 * time.Second), at(2021, 9, 6, 10, 16)},
+		{"strictly after the given time", "15 10 * * *", at(2021, 9, 6, 10, 15), at(2021, 9, 7, 10, 15)},
+		{"step", "*/15 * * * *", at(2021, 9, 6, 10, 16), at(2021, 9, 6, 10, 30)},
+		{"step over the hour", "*/15 * * * *", at(2021, 9, 6, 10, 50), at(2021, 9, 6, 11, 0)},
+		{"list", "5,35 * * * *", at(2021, 9, 6, 10, 6), at(2021, 9, 6, 10, 35)},
+		{"range with step", "0 8-18/2 * * *", at(2021, 9, 6, 13, 0), at(2021, 9, 6, 14, 0)},
+		{"after the last hour of the range", "0 8-18/2 * * *", at(2021, 9, 6, 18, 30), at(2021, 9, 7, 8, 0)},
+		{"weekdays", "0 9 * * 1-5", at(2021, 9, 10, 10, 0), at(2021, 9, 13, 9, 0)},
+		{"Sunday as 7", "0 9 * * 7", at(2021, 9, 6, 10, 0), at(2021, 9, 12, 9, 0)},
+		{"Sunday as 0", "0 9 * * 0", at(2021, 9, 6, 10, 0), at(2021, 9, 12, 9, 0)},
+		{"day of month", "0 0 15 * *", at(2021, 9, 16, 0, 0), at(2021, 10, 15, 0, 0)},
+		{"day of month or weekday", "0 0 15 * 1", at(2021, 9, 6, 0, 0), at(2021, 9, 13, 0, 0)},
+		{"day of month or weekday, day of month first", "0 0 8 * 1", at(2021, 9, 6, 0, 0), at(2021, 9, 8, 0, 0)},
+		{"month", "0 0 1 3 *", at(2021, 9, 6, 0, 0), at(2022, 3, 1, 0, 0)},
+		{"day missing in some months", "0 0 31 * *", at(2021, 9, 6, 0, 0), at(2021, 10, 31, 0, 0)},
+		{"leap day", "0 0 29 2 *", at(2021, 3, 1, 0, 0), at(2024, 2, 29, 0, 0)},
+		{"never", "0 0 30 2 *", at(2021, 9, 6, 0, 0), time.Time{}},
+		{"location of the given time", "0 2 * * *", time.Date(2021, 9, 6, 3, 0, 0, 0, belgrade), time.Date(2021, 9, 7, 2, 0, 0, 0, belgrade)},
+		{"time skipped when clocks go forward", "30 2 * * *", time.Date(2021, 3, 28, 1, 0, 0, 0, belgrade), time.Date(2021, 3, 29, 2, 30, 0, 0, belgrade)},
+		{"hour after clocks go forward", "0 3 * * *", time.Date(2021, 3, 28, 1, 0, 0, 0, belgrade), time.Date(2021, 3, 28, 3, 0, 0, 0, belgrade)},
+		{"time before clocks go back", "30 2 * * *", at(2021, 10, 31, 0, 0).In(belgrade), at(2021, 10, 31, 0, 30).In(belgrade)},
+		{"time repeated when clocks go back", "30 2 * * *", at(2021, 10, 31, 0, 30).In(belgrade), time.Date(2021, 11, 1, 2, 30, 0, 0, belgrade)},
+		{"repeated hour after clocks go back", "*/20 * * * *", at(2021, 10, 31, 0, 45).In(belgrade), at(2021, 10, 31, 2, 0).In(belgrade)},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := Parse(tc.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tc.spec, err)
+			}
+			got := schedule.Next(tc.from)
+			if !got.Equal(tc.want) || !got.IsZero() && got.Location().String() != tc.from.Location().String() {
+				t.Errorf("Next(%v) = %v, want %v", tc.from, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRun_neverScheduled(t *testing.T) {
+	logger, logs := log.NewForTest()
+	schedule, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		Run(context.Background(), schedule, "test", func(ctx context.Context) error {
+			t.Error("the job ran")
+			return nil
+		}, logger)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return for a schedule that never matches")
+	}
+	if logs.FilterMessage("job test is never scheduled to run").Len() != 1 {
+		t.Errorf("logs = %v, want the job reported as never scheduled", logs.All())
+	}
+}
+
+func TestRun_cancelled(t *testing.T) {
+	logger, _ := log.NewForTest()
+	schedule, err := Parse("* * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, schedule, "test", func(ctx context.Context) error { return nil }, logger)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after the context was cancelled")
+	}
+}
@@ -4,9 +4,11 @@ package dbcontext
 
 import (
 	"context"
+	"errors"
 
 	dbx "github.com/go-ozzo/ozzo-dbx"
 	routing "github.com/go-ozzo/ozzo-routing/v2"
+	"github.com/go-sql-driver/mysql"
 )
 
 // DB represents a DB connection that can be used to run SQL queries.
@@ -23,6 +25,16 @@ const (
 	txKey contextKey = iota
 )
 
+// MySQL error numbers.
+const (
+	// mysqlDuplicateEntry is reported when a unique index is violated.
+	mysqlDuplicateEntry = 1062
+	// mysqlLockWaitTimeout is reported when a lock held by another transaction was not released in time.
+	mysqlLockWaitTimeout = 1205
+	// mysqlDeadlock is reported when the transaction was rolled back to break a deadlock.
+	mysqlDeadlock = 1213
+)
+
 // New returns a new DB connection that wraps the given dbx.DB instance.
 func New(db *dbx.DB) *DB {
 	return &DB{db}
@@ -62,3 +74,16 @@ func (db *DB) TransactionHandler() routing.Handler {
 		})
 	}
 }
+
+// IsUniqueViolation returns whether the error was caused by a statement violating a unique index.
+func IsUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// IsLockConflict returns whether the error was caused by a lock held by a concurrent transaction,
+// i.e. the transaction was chosen as a deadlock victim or timed out waiting for the lock.
+func IsLockConflict(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == mysqlDeadlock || mysqlErr.Number == mysqlLockWaitTimeout)
+}
@@ -0,0 +1,61 @@
+package httpclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(3, time.Minute)
+	b.now = func() time.Time { return now }
+
+	// failures below the threshold keep the circuit closed, a success resets the count
+	b.failure()
+	b.failure()
+	b.success()
+	b.failure()
+	b.failure()
+	if !b.allow() {
+		t.Fatal("allow() = false, want the circuit to be closed below the threshold")
+	}
+	b.failure()
+	if b.allow() {
+		t.Fatal("allow() = true, want the circuit to open at the threshold")
+	}
+
+	// after the cooldown a single trial request is let through
+	now = now.Add(59 * time.Second)
+	if b.allow() {
+		t.Fatal("allow() = true, want the circuit to stay open during the cooldown")
+	}
+	now = now.Add(time.Second)
+	if !b.allow() {
+		t.Fatal("allow() = false, want a trial request after the cooldown")
+	}
+	if b.allow() {
+		t.Fatal("allow() = true, want a single trial request")
+	}
+
+	// a failed trial opens the circuit for another cooldown
+	b.failure()
+	if b.allow() {
+		t.Fatal("allow() = true, want the circuit to open again after a failed trial")
+	}
+
+	// an abandoned trial lets the next request through as a new trial
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Fatal("allow() = false, want a trial request after the cooldown")
+	}
+	b.cancel()
+	if !b.allow() {
+		t.Fatal("allow() = false, want a new trial after an abandoned one")
+	}
+
+	// a successful trial closes the circuit
+	b.success()
+	if !b.allow() || !b.allow() {
+		t.Fatal("allow() = false, want the circuit to close after a successful trial")
+	}
+}
@@ -1,45 +1,237 @@
+// Package httpclient provides a client for the JSON APIs of other services.
 package httpclient
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
 
-	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/matijapetrovic/clinichub/scheduling-service/pkg/log"
 )
 
-func NewJsonClient(method string, target *url.URL, decode httptransport.DecodeResponseFunc, authorization string, requestFunc httptransport.RequestFunc) *httptransport.Client {
-	return httptransport.NewClient(
-		method,
-		target,
-		httptransport.EncodeJSONRequest,
-		decode,
-		httptransport.ClientBefore(httptransport.SetRequestHeader("Authorization", authorization)),
-	)
+// RequestFunc may modify an outgoing request before it is sent, e.g. to add headers taken from the context.
+type RequestFunc func(ctx context.Context, r *http.Request)
+
+// Client sends JSON requests to the API of another service.
+type Client struct {
+	baseURL      string
+	httpClient   *http.Client
+	timeout      time.Duration
+	retries      int
+	backoff      time.Duration
+	breaker      *circuitBreaker
+	requestFuncs []RequestFunc
 }
 
-func DefaultHttpRequestEncoder(_ context.Context, r *http.Request, request interface{}) error {
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(request); err != nil {
-		return err
+// Option configures a Client.
+type Option func(c *Client)
+
+// WithRequestFunc returns an option that applies the given function to every outgoing request.
+func WithRequestFunc(f RequestFunc) Option {
+	return func(c *Client) {
+		c.requestFuncs = append(c.requestFuncs, f)
+	}
+}
+
+// WithTimeout returns an option that limits the time a single request attempt may take, including reading the response body.
+// The deadline of the context passed with the request still applies if it is earlier.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetries returns an option that retries failed GET requests up to the given number of times.
+// The delay before a retry starts at backoff and doubles with every attempt.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithCircuitBreaker returns an option that stops sending requests to the service after the given number
+// of consecutive failed requests. A trial request is let through once the cooldown has passed.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Option {
+	return func(c *Client) {
+		c.breaker = newCircuitBreaker(threshold, cooldown)
+	}
+}
+
+// New creates a new Client sending requests to the service with the given base URL.
+// Request and correlation IDs found in the request context are forwarded to the service.
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{},
+	}
+	for _, option := range options {
+		option(c)
 	}
-	r.Body = ioutil.NopCloser(&buf)
-	r.Close = true
-	return nil
+	return c
 }
 
-func QueryParamBeforeFunc(queryParamMap map[string]string) httptransport.RequestFunc {
-	f := func(ctx context.Context, r *http.Request) context.Context {
-		q := r.URL.Query()
-		for key, value := range queryParamMap {
-			q.Add(key, value)
+// ResponseError is returned when a service responds with a non-2xx status code.
+type ResponseError struct {
+	Method     string
+	URL        string
+	StatusCode int
+	// Message is the error message found in the response body, if any.
+	Message string
+}
+
+// Error is required by the error interface.
+func (e *ResponseError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.StatusCode, e.Message)
+}
+
+// Get sends a GET request to the given path and decodes the JSON response body into result.
+func (c *Client) Get(ctx context.Context, path string, query url.Values, result interface{}) error {
+	return c.Do(ctx, http.MethodGet, path, query, nil, result)
+}
+
+// Do sends a request with the JSON-encoded body to the given path and decodes the JSON response body into result.
+// The body and the result can be nil. A *ResponseError is returned if the response status code is not 2xx.
+// GET requests failing because the service is unreachable or responds with a 5xx status code are retried
+// if the client is configured with retries. ErrCircuitOpen is returned while the circuit breaker is open.
+func (c *Client) Do(ctx context.Context, method string, path string, query url.Values, body interface{}, result interface{}) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
 		}
-		r.URL.RawQuery = q.Encode()
-		return ctx
 	}
 
-	return f
+	if c.breaker != nil && !c.breaker.allow() {
+		return ErrCircuitOpen
+	}
+
+	attempts := 1
+	if method == http.MethodGet {
+		attempts += c.retries
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err = sleep(ctx, c.backoff<<uint(attempt-1)); err != nil {
+				break
+			}
+		}
+		err = c.send(ctx, method, u, payload, result)
+		if ctx.Err() != nil || !isServerFailure(err) {
+			break
+		}
+	}
+
+	if c.breaker != nil {
+		switch {
+		case ctx.Err() != nil:
+			c.breaker.cancel()
+		case isServerFailure(err):
+			c.breaker.failure()
+		default:
+			c.breaker.success()
+		}
+	}
+	return err
+}
+
+// send sends a single request and decodes the JSON response body into result.
+func (c *Client) send(ctx context.Context, method string, u string, payload []byte, result interface{}) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	log.ForwardRequestIDs(ctx, req)
+	for _, f := range c.requestFuncs {
+		f(ctx, req)
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return newResponseError(req, res)
+	}
+	if result == nil {
+		_, err = io.Copy(ioutil.Discard, res.Body)
+		return err
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// isServerFailure returns whether the error shows that the service is unreachable, too slow or failing,
+// as opposed to rejecting the request. Only such errors are retried and counted by the circuit breaker.
+func isServerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var responseErr *ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// sleep waits for the given duration or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newResponseError builds a ResponseError, taking the message from the JSON error response body if there is one.
+func newResponseError(req *http.Request, res *http.Response) *ResponseError {
+	var body struct {
+		Message string `json:"message"`
+	}
+	_ = json.NewDecoder(res.Body).Decode(&body)
+	return &ResponseError{
+		Method:     req.Method,
+		URL:        req.URL.Path,
+		StatusCode: res.StatusCode,
+		Message:    body.Message,
+	}
 }
@@ -93,6 +93,35 @@ func WithRequest(ctx context.Context, req *http.Request) context.Context {
 	return ctx
 }
 
+// RequestID returns the request ID recorded in the given context.
+// An empty string is returned if the context has no request ID.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// CorrelationID returns the correlation ID recorded in the given context.
+// An empty string is returned if the context has no correlation ID.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey).(string)
+	return id
+}
+
+// ForwardRequestIDs sets the request ID and correlation ID headers of an outgoing request from the given context.
+// When the context has no correlation ID, the request ID is used instead so that all calls made
+// while handling a request can be correlated with it.
+func ForwardRequestIDs(ctx context.Context, req *http.Request) {
+	requestID := RequestID(ctx)
+	if requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
+	if id := CorrelationID(ctx); id != "" {
+		req.Header.Set("X-Correlation-ID", id)
+	} else if requestID != "" {
+		req.Header.Set("X-Correlation-ID", requestID)
+	}
+}
+
 // getCorrelationID extracts the correlation ID from the HTTP request
 func getCorrelationID(req *http.Request) string {
 	return req.Header.Get("X-Correlation-ID")